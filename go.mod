@@ -1,6 +1,6 @@
 module github.com/abelanger5/nginx-ingress-parser
 
-go 1.16
+go 1.21
 
 require (
 	github.com/gen2brain/beeep v0.0.0-20210529141713-5586760f0cc1 // indirect
@@ -9,6 +9,8 @@ require (
 	github.com/godbus/dbus/v5 v5.0.4 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20210722203344-69c5ea87048d // indirect
 	github.com/honeycombio/gonx v1.3.1-0.20171118020637-f9b2468e9ef8
+	github.com/klauspost/compress v1.13.6
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 )
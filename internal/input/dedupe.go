@@ -0,0 +1,46 @@
+package input
+
+import "hash/fnv"
+
+// Deduper drops lines identical to one of the last lines seen, by hash
+type Deduper struct {
+	recent []uint64
+	next   int
+	counts map[uint64]int
+}
+
+// NewDeduper returns a deduper remembering the last window lines
+func NewDeduper(window int) *Deduper {
+	return &Deduper{
+		recent: make([]uint64, 0, window),
+		counts: make(map[uint64]int, window),
+	}
+}
+
+// Duplicate returns whether the line is in the window, and adds it otherwise
+func (d *Deduper) Duplicate(line string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(line))
+	sum := h.Sum64()
+
+	if d.counts[sum] > 0 {
+		return true
+	}
+
+	if len(d.recent) < cap(d.recent) {
+		d.recent = append(d.recent, sum)
+	} else {
+		evicted := d.recent[d.next]
+
+		if d.counts[evicted]--; d.counts[evicted] <= 0 {
+			delete(d.counts, evicted)
+		}
+
+		d.recent[d.next] = sum
+		d.next = (d.next + 1) % len(d.recent)
+	}
+
+	d.counts[sum]++
+
+	return false
+}
@@ -0,0 +1,61 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeduper(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int
+		lines  []string
+		want   []bool
+	}{
+		{
+			name:   "unique",
+			window: 2,
+			lines:  []string{"a", "b", "c"},
+			want:   []bool{false, false, false},
+		},
+		{
+			name:   "consecutive",
+			window: 2,
+			lines:  []string{"a", "a", "a", "b"},
+			want:   []bool{false, true, true, false},
+		},
+		{
+			name:   "within the window",
+			window: 2,
+			lines:  []string{"a", "b", "a", "b"},
+			want:   []bool{false, false, true, true},
+		},
+		{
+			name:   "evicted from the window",
+			window: 2,
+			lines:  []string{"a", "b", "c", "d", "a", "d"},
+			want:   []bool{false, false, false, false, false, true},
+		},
+		{
+			name:   "window of one",
+			window: 1,
+			lines:  []string{"a", "a", "b", "a"},
+			want:   []bool{false, true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeduper(tt.window)
+			got := make([]bool, 0, len(tt.lines))
+
+			for _, line := range tt.lines {
+				got = append(got, d.Duplicate(line))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("duplicates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
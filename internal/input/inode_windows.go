@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package input
+
+import "os"
+
+// fileInode returns 0 on windows, where os.FileInfo doesn't expose a file ID
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,91 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type Options struct {
+	// Zstd forces zstd decompression, even if the stream doesn't start with the zstd magic bytes
+	Zstd bool
+	// Gzip forces gzip decompression, e.g. for files with a .gz suffix
+	Gzip bool
+}
+
+// NewReader wraps r in a decompressing reader if the stream is compressed
+func NewReader(r io.Reader, opts Options) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	switch {
+	case opts.Zstd:
+		return newZstdReader(br)
+	case opts.Gzip:
+		return newGzipReader(br)
+	case hasPrefix(br, gzipMagic):
+		return newGzipReader(br)
+	case hasPrefix(br, zstdMagic):
+		return newZstdReader(br)
+	}
+
+	return ioutil.NopCloser(br), nil
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.IOReadCloser(), nil
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := gzip.NewReader(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip input: %w", err)
+	}
+
+	return &gzipReader{dec}, nil
+}
+
+// gzipReader labels the errors of a corrupt or truncated gzip stream
+type gzipReader struct {
+	*gzip.Reader
+}
+
+func (r *gzipReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("corrupt gzip input: %w", err)
+	}
+
+	return n, err
+}
+
+// IsCompressed reports whether the header starts with the gzip or zstd magic bytes
+func IsCompressed(header []byte) bool {
+	return bytes.HasPrefix(header, gzipMagic) || bytes.HasPrefix(header, zstdMagic)
+}
+
+func hasPrefix(br *bufio.Reader, magic []byte) bool {
+	header, err := br.Peek(len(magic))
+
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(header, magic)
+}
@@ -0,0 +1,183 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/metric"
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const testLog = `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] 10.1.0.5:8080 512 0.250 200 req1
+10.0.0.2 - - [14/Oct/2026:10:00:01 +0000] "GET /api HTTP/1.1" 502 0 "-" "curl/7.68.0" 120 1.200 [default-api-80] [] 10.1.0.5:8080 0 1.200 502 req2
+10.0.0.3 - - [14/Oct/2026:10:00:02 +0000] "POST /orders HTTP/1.1" 201 64 "-" "curl/7.68.0" 300 0.050 [default-orders-80] [] 10.1.0.6:8080 64 0.040 201 req3
+`
+
+func zstdCompress(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	enc, err := zstd.NewWriter(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.WriteString(enc, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func gzipCompress(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	enc := gzip.NewWriter(&buf)
+
+	if _, err := io.WriteString(enc, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// collect parses the lines of r into a collector, like the root command does
+func collect(t *testing.T, r io.Reader, opts Options) *metric.MetricCollector {
+	t.Helper()
+
+	reader, err := NewReader(r, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer reader.Close()
+
+	factory := &parser.NginxParserFactory{}
+	factory.Init(map[string]interface{}{})
+	p := factory.New()
+	collector := metric.NewMetricCollector(metric.GroupKindPath, metric.MetricKindLatency)
+
+	scanner := bufio.NewScanner(reader)
+	lines := 0
+
+	for scanner.Scan() {
+		res, err := p.Parse(scanner.Text())
+
+		if err != nil {
+			t.Fatalf("line %q dropped: %v", scanner.Text(), err)
+		}
+
+		collector.AddLine(res, scanner.Text())
+		lines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines != 3 {
+		t.Fatalf("read %d lines, want 3", lines)
+	}
+
+	return collector
+}
+
+func TestNewReaderZstd(t *testing.T) {
+	want := collect(t, strings.NewReader(testLog), Options{})
+	compressed := zstdCompress(t, testLog)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"detected", Options{}},
+		{"forced", Options{Zstd: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, bytes.NewReader(compressed), tt.opts)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("zstd input aggregated differently from the plaintext input")
+			}
+		})
+	}
+}
+
+func TestNewReaderGzip(t *testing.T) {
+	want := collect(t, strings.NewReader(testLog), Options{})
+	compressed := gzipCompress(t, testLog)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"detected", Options{}},
+		{"forced", Options{Gzip: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, bytes.NewReader(compressed), tt.opts)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("gzip input aggregated differently from the plaintext input")
+			}
+		})
+	}
+}
+
+func TestNewReaderForcedZstdFirst(t *testing.T) {
+	// a .gz file read with --zstd, which wins over the suffix and the gzip magic bytes
+	reader, err := NewReader(bytes.NewReader(gzipCompress(t, testLog)), Options{Zstd: true, Gzip: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer reader.Close()
+
+	if _, err := ioutil.ReadAll(reader); err == nil {
+		t.Error("expected gzip input to fail zstd decompression")
+	}
+}
+
+func TestNewReaderGzipCorrupt(t *testing.T) {
+	if _, err := NewReader(strings.NewReader(testLog), Options{Gzip: true}); err == nil || !strings.Contains(err.Error(), "invalid gzip input") {
+		t.Errorf("NewReader() error = %v, want an invalid gzip input error", err)
+	}
+
+	compressed := gzipCompress(t, testLog)
+	reader, err := NewReader(bytes.NewReader(compressed[:len(compressed)-4]), Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer reader.Close()
+
+	if _, err := ioutil.ReadAll(reader); err == nil || !strings.Contains(err.Error(), "corrupt gzip input") {
+		t.Errorf("ReadAll() error = %v, want a corrupt gzip input error", err)
+	}
+}
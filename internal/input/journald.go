@@ -0,0 +1,103 @@
+package input
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+)
+
+// journalEntry is a single entry of journalctl's json export
+type journalEntry struct {
+	Message json.RawMessage `json:"MESSAGE"`
+}
+
+func (e *journalEntry) message() (string, bool) {
+	var str string
+
+	if err := json.Unmarshal(e.Message, &str); err == nil {
+		return str, true
+	}
+
+	var rawInts []int
+
+	if err := json.Unmarshal(e.Message, &rawInts); err == nil {
+		raw := make([]byte, len(rawInts))
+
+		for i, b := range rawInts {
+			raw[i] = byte(b)
+		}
+
+		return string(raw), true
+	}
+
+	return "", false
+}
+
+// NewJournalMessageReader returns a reader over the MESSAGE fields of journal entries
+func NewJournalMessageReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		dec := json.NewDecoder(r)
+
+		for {
+			entry := &journalEntry{}
+
+			if err := dec.Decode(entry); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+
+				pw.CloseWithError(err)
+				return
+			}
+
+			msg, ok := entry.message()
+
+			if !ok {
+				continue
+			}
+
+			if _, err := io.WriteString(pw, msg+"\n"); err != nil {
+				return
+			}
+		}
+	}()
+
+	return pr
+}
+
+type journalReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (j *journalReader) Close() error {
+	j.ReadCloser.Close()
+
+	// journalctl exits on its own at the end of the journal, so this only stops it early
+	j.cmd.Process.Kill()
+	j.cmd.Wait()
+
+	return nil
+}
+
+// OpenJournal reads the messages logged by a systemd unit, via journalctl
+func OpenJournal(unit string) (io.ReadCloser, error) {
+	cmd := exec.Command("journalctl", "--unit", unit, "--output", "json", "--no-pager")
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &journalReader{
+		ReadCloser: NewJournalMessageReader(stdout),
+		cmd:        cmd,
+	}, nil
+}
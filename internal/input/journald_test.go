@@ -0,0 +1,47 @@
+package input
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestNewJournalMessageReader(t *testing.T) {
+	// a fake journalctl export: a plain message, an entry without a MESSAGE, and a
+	// message that isn't valid UTF-8 and is exported as an array of bytes
+	journal := strings.Join([]string{
+		`{"_SYSTEMD_UNIT":"nginx.service","MESSAGE":"10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] \"GET /api HTTP/1.1\" 200"}`,
+		`{"_SYSTEMD_UNIT":"nginx.service","PRIORITY":"6"}`,
+		`{"_SYSTEMD_UNIT":"nginx.service","MESSAGE":[71,69,84,32,47,255]}`,
+	}, "\n")
+
+	r := NewJournalMessageReader(strings.NewReader(journal))
+	defer r.Close()
+
+	got, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] \"GET /api HTTP/1.1\" 200\n" + "GET /\xff\n"
+
+	if string(got) != want {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestNewJournalMessageReaderInvalid(t *testing.T) {
+	r := NewJournalMessageReader(strings.NewReader(`{"MESSAGE":"first"}` + "\n" + `{"MESSAGE":`))
+	defer r.Close()
+
+	got, err := ioutil.ReadAll(r)
+
+	if err == nil {
+		t.Error("expected an error for a truncated journal entry")
+	}
+
+	if string(got) != "first\n" {
+		t.Errorf("messages = %q, want the entry before the error", got)
+	}
+}
@@ -0,0 +1,123 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// State records how far into a log file a previous run got
+type State struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// LoadState reads the state file at path. A missing state file is an empty state.
+func LoadState(path string) (*State, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+
+	return state, nil
+}
+
+// Save writes the state file, replacing it atomically
+func (s *State) Save(path string) error {
+	data, err := json.Marshal(s)
+
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// Resume seeks f past the content processed in the previous run, unless it was rotated
+func (s *State) Resume(f *os.File) (*OffsetTracker, error) {
+	info, err := f.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("incremental runs require a regular file as input")
+	}
+
+	inode := fileInode(info)
+
+	if inode != s.Inode || info.Size() < s.Offset {
+		s.Offset = 0
+	}
+
+	s.Inode = inode
+
+	if _, err := f.Seek(s.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &OffsetTracker{r: f, lineEnd: s.Offset}, nil
+}
+
+// OffsetTracker reads complete lines and tracks the offset past the last one
+type OffsetTracker struct {
+	r       io.Reader
+	lineEnd int64
+
+	buf     []byte
+	pending []byte
+	ready   []byte
+	err     error
+}
+
+func (t *OffsetTracker) Read(p []byte) (int, error) {
+	for len(t.ready) == 0 {
+		if t.err != nil {
+			// a partial last line is dropped at EOF
+			return 0, t.err
+		}
+
+		if t.buf == nil {
+			t.buf = make([]byte, 32*1024)
+		}
+
+		n, err := t.r.Read(t.buf)
+		t.pending = append(t.pending, t.buf[:n]...)
+		t.err = err
+
+		if i := bytes.LastIndexByte(t.pending, '\n'); i >= 0 {
+			t.ready = append([]byte(nil), t.pending[:i+1]...)
+			t.pending = append([]byte(nil), t.pending[i+1:]...)
+		}
+	}
+
+	n := copy(p, t.ready)
+	t.ready = t.ready[n:]
+	t.lineEnd += int64(n)
+
+	return n, nil
+}
+
+// LineEnd returns the offset just past the last line read
+func (t *OffsetTracker) LineEnd() int64 {
+	return t.lineEnd
+}
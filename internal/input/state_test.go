@@ -0,0 +1,166 @@
+package input
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// runSinceLastRun reads the lines appended to the log since the last run, like
+// --since-last-run, and records the offset in the state file
+func runSinceLastRun(t *testing.T, logPath, statePath string) []string {
+	t.Helper()
+
+	state, err := LoadState(statePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(logPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	tracker, err := state.Resume(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(tracker)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	state.Offset = tracker.LineEnd()
+
+	if err := state.Save(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	return lines
+}
+
+func appendLog(t *testing.T, path, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSinceLastRun(t *testing.T) {
+	type run struct {
+		// before changes the log before the run
+		before func(t *testing.T, path string)
+		want   []string
+	}
+
+	appendContent := func(content string) func(t *testing.T, path string) {
+		return func(t *testing.T, path string) {
+			appendLog(t, path, content)
+		}
+	}
+
+	rotate := func(content string) func(t *testing.T, path string) {
+		return func(t *testing.T, path string) {
+			// keep the old file around, so the new one can't reuse its inode
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+
+			appendLog(t, path, content)
+		}
+	}
+
+	truncate := func(content string) func(t *testing.T, path string) {
+		return func(t *testing.T, path string) {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		runs []run
+	}{
+		{
+			name: "growing file",
+			runs: []run{
+				{appendContent("a\nb\n"), []string{"a", "b"}},
+				{appendContent("c\n"), []string{"c"}},
+				{appendContent(""), []string{}},
+			},
+		},
+		{
+			name: "partial last line",
+			runs: []run{
+				{appendContent("a\nb"), []string{"a"}},
+				{appendContent("c\nd\n"), []string{"bc", "d"}},
+			},
+		},
+		{
+			name: "rotated",
+			runs: []run{
+				{appendContent("a\nb\n"), []string{"a", "b"}},
+				{rotate("c\n"), []string{"c"}},
+			},
+		},
+		{
+			name: "truncated",
+			runs: []run{
+				{appendContent("a\nb\nc\n"), []string{"a", "b", "c"}},
+				{truncate("d\n"), []string{"d"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			logPath := filepath.Join(dir, "access.log")
+			statePath := filepath.Join(dir, "state.json")
+
+			for i, r := range tt.runs {
+				r.before(t, logPath)
+
+				if got := runSinceLastRun(t, logPath, statePath); !reflect.DeepEqual(got, r.want) {
+					t.Errorf("run %d read %q, want %q", i+1, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadStateMissing(t *testing.T) {
+	state, err := LoadState(filepath.Join(t.TempDir(), "missing.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *state != (State{}) {
+		t.Errorf("state = %+v, want an empty state", *state)
+	}
+}
@@ -0,0 +1,114 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// arrivalGapBounds are the upper bounds of the inter-arrival histogram buckets
+var arrivalGapBounds = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// ArrivalGapBucket counts the gaps between consecutive requests in a range of durations
+type ArrivalGapBucket struct {
+	Label string
+	Count int
+}
+
+// arrivalCounter counts the gaps between consecutive requests as they're added
+type arrivalCounter struct {
+	first time.Time
+	last  time.Time
+	// gaps counts the gaps in each bucket of arrivalGapBounds
+	gaps []int
+}
+
+func (a *arrivalCounter) add(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	if a.last.IsZero() {
+		a.first, a.last = t, t
+		return
+	}
+
+	a.addGap(t.Sub(a.last))
+
+	if t.After(a.last) {
+		a.last = t
+	}
+
+	if t.Before(a.first) {
+		a.first = t
+	}
+}
+
+func (a *arrivalCounter) addGap(gap time.Duration) {
+	if a.gaps == nil {
+		a.gaps = make([]int, len(arrivalGapBounds)+1)
+	}
+
+	a.gaps[sort.Search(len(arrivalGapBounds), func(i int) bool {
+		return gap < arrivalGapBounds[i]
+	})]++
+}
+
+// merge adds the gaps of other, which follows a in the input
+func (a *arrivalCounter) merge(other *arrivalCounter) {
+	if other.last.IsZero() {
+		return
+	}
+
+	if a.last.IsZero() {
+		*a = arrivalCounter{other.first, other.last, append([]int(nil), other.gaps...)}
+		return
+	}
+
+	a.addGap(other.first.Sub(a.last))
+
+	for i, count := range other.gaps {
+		a.gaps[i] += count
+	}
+
+	if other.last.After(a.last) {
+		a.last = other.last
+	}
+
+	if other.first.Before(a.first) {
+		a.first = other.first
+	}
+}
+
+// buckets returns the histogram of the gaps, or nil if there are none
+func (a *arrivalCounter) buckets() []*ArrivalGapBucket {
+	if a.gaps == nil {
+		return nil
+	}
+
+	buckets := make([]*ArrivalGapBucket, len(arrivalGapBounds)+1)
+
+	for i, bound := range arrivalGapBounds {
+		buckets[i] = &ArrivalGapBucket{Label: "<" + formatGapBound(bound), Count: a.gaps[i]}
+	}
+
+	last := len(arrivalGapBounds)
+	buckets[last] = &ArrivalGapBucket{Label: ">=" + formatGapBound(arrivalGapBounds[last-1]), Count: a.gaps[last]}
+
+	return buckets
+}
+
+// formatGapBound formats a bound like 5m instead of 5m0s
+func formatGapBound(d time.Duration) string {
+	if d >= time.Minute && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+
+	return d.String()
+}
@@ -0,0 +1,157 @@
+package metric
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestArrivalGaps(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		offsets []time.Duration
+		// want are the counts of the <1s, <5s, <30s, <1m, <5m and >=5m buckets
+		want []int
+	}{
+		{
+			name:    "single request",
+			offsets: []time.Duration{0},
+			want:    nil,
+		},
+		{
+			name:    "evenly spaced",
+			offsets: []time.Duration{0, 10 * time.Second, 20 * time.Second, 30 * time.Second},
+			want:    []int{0, 0, 3, 0, 0, 0},
+		},
+		{
+			name:    "unevenly spaced",
+			offsets: []time.Duration{0, 500 * time.Millisecond, 3 * time.Second, 2 * time.Minute, 10 * time.Minute, 10*time.Minute + 40*time.Second},
+			want:    []int{1, 1, 0, 1, 1, 1},
+		},
+		{
+			// requests logged before the latest one count as zero gaps
+			name:    "out of order",
+			offsets: []time.Duration{0, 20 * time.Second, 10 * time.Second, 30 * time.Second},
+			want:    []int{1, 0, 2, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.ArrivalHistogram = true
+
+			for _, offset := range tt.offsets {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      start.Add(offset),
+					Request:        &parser.Request{Method: "GET", Path: "/a"},
+					RequestTime:    0.1,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			gaps := m.Analyze().Groups[0].ArrivalGaps
+
+			if tt.want == nil {
+				if gaps != nil {
+					t.Errorf("got gaps %v for a single request", gaps)
+				}
+
+				return
+			}
+
+			got := make([]int, 0, len(gaps))
+
+			for _, bucket := range gaps {
+				got = append(got, bucket.Count)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gap counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArrivalGapsSampled(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		shards int
+	}{
+		{"reservoir", 1},
+		{"merged shards", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.ArrivalHistogram = true
+			m.Reservoir = 10
+
+			// shards are merged in input order, like with --dir
+			for shard := 0; shard < tt.shards; shard++ {
+				s := m.Shard()
+
+				for i := 0; i < 1000/tt.shards; i++ {
+					s.AddLine(&parser.NginxResult{
+						TimeLocal:      start.Add(time.Duration(shard*1000/tt.shards+i) * time.Second),
+						Request:        &parser.Request{Method: "GET", Path: "/a"},
+						RequestTime:    0.1,
+						UpstreamStatus: 200,
+						UpstreamAddr:   "10.0.0.1:80",
+					}, "")
+				}
+
+				m.Merge(s)
+			}
+
+			got := make([]int, 0)
+
+			for _, bucket := range m.Analyze().Groups[0].ArrivalGaps {
+				got = append(got, bucket.Count)
+			}
+
+			if want := []int{0, 999, 0, 0, 0, 0}; !reflect.DeepEqual(got, want) {
+				t.Errorf("gap counts = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestArrivalGapsReport(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.ArrivalHistogram = true
+
+	for i, path := range []string{"/a", "/a", "/b"} {
+		m.AddLine(&parser.NginxResult{
+			TimeLocal:      start.Add(time.Duration(i) * 2 * time.Second),
+			Request:        &parser.Request{Method: "GET", Path: path},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+			UpstreamAddr:   "10.0.0.1:80",
+		}, "")
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"/a: <1s 0 <5s 1 <30s 0 <1m 0 <5m 0 >=5m 0", "/b: no gaps"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
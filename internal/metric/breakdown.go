@@ -0,0 +1,74 @@
+package metric
+
+import "github.com/abelanger5/nginx-ingress-parser/internal/parser"
+
+type timingSum struct {
+	sum   float64
+	count int
+}
+
+func (t *timingSum) add(values []float64) {
+	if values == nil {
+		return
+	}
+
+	for _, value := range values {
+		t.sum += value
+	}
+
+	t.count++
+}
+
+func (t *timingSum) mean() *float64 {
+	if t.count == 0 {
+		return nil
+	}
+
+	mean := t.sum / float64(t.count)
+
+	return &mean
+}
+
+// TimingMetric accumulates where the time of a group's requests is spent
+type TimingMetric struct {
+	connect  timingSum
+	header   timingSum
+	response timingSum
+	total    timingSum
+}
+
+func (t *TimingMetric) add(result *parser.NginxResult) {
+	t.connect.add(result.UpstreamConnectTimes)
+	t.header.add(result.UpstreamHeaderTimes)
+	t.response.add(result.UpstreamResponseTimes)
+	t.total.add([]float64{result.RequestTime})
+}
+
+func (t *TimingMetric) merge(other *TimingMetric) {
+	for _, sums := range [][2]*timingSum{
+		{&t.connect, &other.connect},
+		{&t.header, &other.header},
+		{&t.response, &other.response},
+		{&t.total, &other.total},
+	} {
+		sums[0].sum += sums[1].sum
+		sums[0].count += sums[1].count
+	}
+}
+
+// LatencyBreakdown holds the mean latencies of a group, in seconds
+type LatencyBreakdown struct {
+	Connect  *float64
+	Header   *float64
+	Response *float64
+	Total    *float64
+}
+
+func (t *TimingMetric) breakdown() *LatencyBreakdown {
+	return &LatencyBreakdown{
+		Connect:  t.connect.mean(),
+		Header:   t.header.mean(),
+		Response: t.response.mean(),
+		Total:    t.total.mean(),
+	}
+}
@@ -0,0 +1,107 @@
+package metric
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestLatencyBreakdown(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Breakdown = true
+
+	results := []*parser.NginxResult{
+		{
+			Request:               &parser.Request{Method: "GET", Path: "/api"},
+			RequestTime:           0.3,
+			UpstreamStatus:        200,
+			UpstreamConnectTimes:  []float64{0.01},
+			UpstreamHeaderTimes:   []float64{0.2},
+			UpstreamResponseTimes: []float64{0.25},
+		},
+		{
+			// retried, so the timings are summed across attempts
+			Request:               &parser.Request{Method: "GET", Path: "/api"},
+			RequestTime:           0.5,
+			UpstreamStatus:        200,
+			UpstreamConnectTimes:  []float64{0.01, 0.02},
+			UpstreamHeaderTimes:   []float64{0.1, 0.2},
+			UpstreamResponseTimes: []float64{0.15, 0.3},
+		},
+		{
+			// no upstream timings logged
+			Request:        &parser.Request{Method: "GET", Path: "/static"},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		},
+	}
+
+	for _, result := range results {
+		m.AddLine(result, "")
+	}
+
+	report := m.Analyze()
+
+	api := report.Groups[0].Breakdown
+
+	timings := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"connect", api.Connect, 0.02},
+		{"header", api.Header, 0.25},
+		{"response", api.Response, 0.35},
+		{"total", api.Total, 0.4},
+	}
+
+	for _, timing := range timings {
+		if timing.got == nil || math.Abs(*timing.got-timing.want) > 1e-9 {
+			t.Errorf("/api %s = %v, want %g", timing.name, timing.got, timing.want)
+		}
+	}
+
+	static := report.Groups[1].Breakdown
+
+	if static.Connect != nil || static.Header != nil || static.Response != nil {
+		t.Errorf("/static has upstream timings without any logged")
+	}
+
+	if static.Total == nil || *static.Total != 0.1 {
+		t.Errorf("/static total = %v, want 0.1", static.Total)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "/static: connect - header - response - total 0.100000") {
+		t.Errorf("report doesn't show the missing /static timings:\n%s", buf.String())
+	}
+}
+
+func TestLatencyBreakdownHidden(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:               &parser.Request{Method: "GET", Path: "/api"},
+		RequestTime:           0.3,
+		UpstreamStatus:        200,
+		UpstreamResponseTimes: []float64{0.25},
+	}, "")
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "LATENCY BREAKDOWN") {
+		t.Errorf("report shows the latency breakdown without Breakdown set:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,105 @@
+package metric
+
+import (
+	"sort"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// DefaultBurstWindow is the default time a burst's failures have to be logged within
+const DefaultBurstWindow = 10 * time.Second
+
+// Burst is a run of consecutive failed requests of a group
+type Burst struct {
+	Group    string
+	Start    time.Time
+	End      time.Time
+	Failures int
+}
+
+// burstState is the current run of consecutive failures of a group
+type burstState struct {
+	run   int
+	start time.Time
+	last  time.Time
+	// burst is the burst the run became, or nil
+	burst *Burst
+}
+
+// addBurst tracks the consecutive failures of the group
+func (m *MetricCollector) addBurst(group string, result *parser.NginxResult) {
+	if m.BurstSize <= 0 {
+		return
+	}
+
+	if m.burstData == nil {
+		m.burstData = make(map[string]*burstState)
+	}
+
+	state, exists := m.burstData[group]
+
+	if !exists {
+		state = &burstState{}
+		m.burstData[group] = state
+	}
+
+	if !result.IsError() {
+		*state = burstState{}
+		return
+	}
+
+	// error log lines aren't timestamped, so take the previous failure's time
+	t := result.TimeLocal
+
+	if t.IsZero() {
+		t = state.last
+	}
+
+	// failures too far apart are scattered, not a burst
+	if state.run == 0 || (!state.last.IsZero() && t.Sub(state.last) > m.BurstWindow) {
+		*state = burstState{start: t}
+	}
+
+	if state.start.IsZero() {
+		state.start = t
+	}
+
+	state.run++
+	state.last = t
+
+	if state.burst != nil {
+		state.burst.End = t
+		state.burst.Failures = state.run
+		return
+	}
+
+	if state.run >= m.BurstSize && state.last.Sub(state.start) <= m.BurstWindow {
+		state.burst = &Burst{group, state.start, state.last, state.run}
+		m.bursts = append(m.bursts, state.burst)
+	}
+}
+
+// burstsReport returns the bursts sorted by start time, or nil if there are none
+func (m *MetricCollector) burstsReport() []*Burst {
+	if len(m.bursts) == 0 {
+		return nil
+	}
+
+	res := make([]*Burst, len(m.bursts))
+
+	for i, burst := range m.bursts {
+		res[i] = &Burst{burst.Group, m.displayTime(burst.Start), m.displayTime(burst.End), burst.Failures}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Start.Before(res[j].Start)
+	})
+
+	return res
+}
+
+// mergeBursts adds the bursts of other, without joining runs across collectors
+func (m *MetricCollector) mergeBursts(other *MetricCollector) {
+	m.bursts = append(m.bursts, other.bursts...)
+}
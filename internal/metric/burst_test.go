@@ -0,0 +1,104 @@
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// addStatuses adds a request to the group for each status, a second apart
+func addStatuses(m *MetricCollector, path string, start time.Time, statuses ...int64) {
+	for i, status := range statuses {
+		m.AddLine(&parser.NginxResult{
+			TimeLocal:      start.Add(time.Duration(i) * time.Second),
+			Request:        &parser.Request{Method: "GET", Path: path},
+			RequestTime:    0.1,
+			UpstreamStatus: status,
+			TimedOut:       status == parser.StatusNoResponse,
+		}, "")
+	}
+}
+
+func TestBursts(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		statuses     []int64
+		window       time.Duration
+		wantFailures []int
+	}{
+		{"clustered", []int64{200, 502, 503, 502, 504, 200}, DefaultBurstWindow, []int{4}},
+		{"scattered", []int64{502, 200, 503, 200, 502, 200, 504}, DefaultBurstWindow, nil},
+		{"below size", []int64{200, 502, 503, 200}, DefaultBurstWindow, nil},
+		{"two bursts", []int64{502, 503, 502, 200, 500, 500, 500, 500}, DefaultBurstWindow, []int{3, 4}},
+		{"timed out", []int64{200, parser.StatusNoResponse, parser.StatusNoResponse, 502}, DefaultBurstWindow, []int{3}},
+		{"client errors", []int64{404, 404, 429, 404}, DefaultBurstWindow, nil},
+		{"outside window", []int64{502, 503, 502}, time.Second, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.BurstSize = 3
+			m.BurstWindow = tt.window
+
+			addStatuses(m, "/a", start, tt.statuses...)
+			// failures of other groups don't extend the run
+			addStatuses(m, "/b", start, 502)
+
+			bursts := m.Analyze().Bursts
+
+			if len(bursts) != len(tt.wantFailures) {
+				t.Fatalf("got %d bursts, want %d", len(bursts), len(tt.wantFailures))
+			}
+
+			for i, burst := range bursts {
+				if burst.Group != "/a" || burst.Failures != tt.wantFailures[i] {
+					t.Errorf("burst %d = %s with %d failures, want /a with %d", i, burst.Group, burst.Failures, tt.wantFailures[i])
+				}
+
+				if got := burst.End.Sub(burst.Start); got != time.Duration(burst.Failures-1)*time.Second {
+					t.Errorf("burst %d lasts %s for %d failures a second apart", i, got, burst.Failures)
+				}
+			}
+		})
+	}
+}
+
+func TestBurstsReport(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		burstSize int
+		want      bool
+	}{
+		{"enabled", 2, true},
+		{"disabled", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.BurstSize = tt.burstSize
+
+			addStatuses(m, "/a", start, 200, 502, 502)
+
+			var buf bytes.Buffer
+
+			if err := m.WriteReport(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			line := "/a: 2 consecutive failures from 2026-10-14T10:00:01Z to 2026-10-14T10:00:02Z"
+
+			if got := strings.Contains(buf.String(), line); got != tt.want {
+				t.Errorf("report contains the burst = %v, want %v:\n%s", got, tt.want, buf.String())
+			}
+		})
+	}
+}
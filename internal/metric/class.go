@@ -0,0 +1,65 @@
+package metric
+
+import (
+	"fmt"
+	"io"
+)
+
+// ClassSummary counts the responses of every group by status class
+type ClassSummary struct {
+	Informational uint
+	Success       uint
+	Redirection   uint
+	ClientError   uint
+	ServerError   uint
+	Other         uint
+}
+
+func (m *MetricCollector) classSummary() *ClassSummary {
+	summary := &ClassSummary{}
+
+	for _, respBucket := range m.responseData {
+		for code, num := range respBucket {
+			switch code / 100 {
+			case 1:
+				summary.Informational += num
+			case 2:
+				summary.Success += num
+			case 3:
+				summary.Redirection += num
+			case 4:
+				summary.ClientError += num
+			case 5:
+				summary.ServerError += num
+			default:
+				summary.Other += num
+			}
+		}
+	}
+
+	return summary
+}
+
+// String formats the summary on a single line
+func (s *ClassSummary) String() string {
+	res := ""
+
+	if s.Informational > 0 {
+		res += fmt.Sprintf("1xx=%d ", s.Informational)
+	}
+
+	res += fmt.Sprintf("2xx=%d 3xx=%d 4xx=%d 5xx=%d", s.Success, s.Redirection, s.ClientError, s.ServerError)
+
+	if s.Other > 0 {
+		res += fmt.Sprintf(" other=%d", s.Other)
+	}
+
+	return res
+}
+
+// WriteClassSummary writes the status class summary line on its own
+func (m *MetricCollector) WriteClassSummary(w io.Writer) error {
+	_, err := fmt.Fprintln(w, m.classSummary())
+
+	return err
+}
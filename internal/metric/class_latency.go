@@ -0,0 +1,89 @@
+package metric
+
+import (
+	"fmt"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// ClassLatencySample is the number of latencies sampled per status class
+const ClassLatencySample = 10000
+
+// ClassLatency holds the latencies of the responses of a status class, across every group
+type ClassLatency struct {
+	Class string
+	Count int
+	Mean  float64
+	P95   float64
+}
+
+func (m *MetricCollector) addClassLatency(result *parser.NginxResult, latency *LatencyMetric) {
+	if !m.LatencyByClass {
+		return
+	}
+
+	class := result.UpstreamStatus / 100
+
+	if class < 1 || class > 5 {
+		return
+	}
+
+	if m.classLatencyData == nil {
+		m.classLatencyData = make(map[int64]*LatencyMetricList)
+	}
+
+	bucket, exists := m.classLatencyData[class]
+
+	if !exists {
+		bucket = &LatencyMetricList{capacity: ClassLatencySample}
+		m.classLatencyData[class] = bucket
+	}
+
+	bucket.add(latency, m.rand)
+}
+
+// classLatencies returns the latencies of every status class with responses
+func (m *MetricCollector) classLatencies() []*ClassLatency {
+	if !m.LatencyByClass {
+		return nil
+	}
+
+	res := make([]*ClassLatency, 0, len(m.classLatencyData))
+
+	for class := int64(1); class <= 5; class++ {
+		bucket, exists := m.classLatencyData[class]
+
+		if !exists || bucket.count == 0 {
+			continue
+		}
+
+		res = append(res, &ClassLatency{
+			Class: fmt.Sprintf("%dxx", class),
+			Count: bucket.count,
+			Mean:  bucket.sum / float64(bucket.count),
+			P95:   m.percentile(sortedLatencies(bucket.Latencies), 95),
+		})
+	}
+
+	return res
+}
+
+func (m *MetricCollector) mergeClassLatencies(other *MetricCollector) {
+	for class := int64(1); class <= 5; class++ {
+		bucket, exists := other.classLatencyData[class]
+
+		if !exists {
+			continue
+		}
+
+		if m.classLatencyData == nil {
+			m.classLatencyData = make(map[int64]*LatencyMetricList)
+		}
+
+		if toBucket, exists := m.classLatencyData[class]; exists {
+			toBucket.merge(bucket, m.rand)
+		} else {
+			m.classLatencyData[class] = bucket
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package metric
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func addClassLatencyFixture(m *MetricCollector) {
+	responses := []struct {
+		path    string
+		status  int64
+		latency float64
+	}{
+		{"/a", 200, 0.1},
+		{"/b", 200, 0.2},
+		{"/a", 201, 0.3},
+		{"/a", 404, 0.05},
+		{"/b", 502, 2},
+		{"/a", 504, 4},
+		{"/b", parser.StatusNoResponse, 10},
+	}
+
+	for _, r := range responses {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: r.path},
+			RequestTime:    r.latency,
+			UpstreamStatus: r.status,
+		}, "")
+	}
+}
+
+func TestLatencyByClass(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.LatencyByClass = true
+
+	addClassLatencyFixture(m)
+
+	want := []ClassLatency{
+		{Class: "2xx", Count: 3, Mean: 0.2},
+		{Class: "4xx", Count: 1, Mean: 0.05},
+		{Class: "5xx", Count: 2, Mean: 3},
+	}
+
+	got := m.Analyze().ClassLatencies
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d status classes, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		if got[i].Class != w.Class || got[i].Count != w.Count || math.Abs(got[i].Mean-w.Mean) > 1e-9 {
+			t.Errorf("class %d = %+v, want %+v", i, *got[i], w)
+		}
+	}
+
+	// 5xx responses are slower than 2xx ones
+	if got[2].P95 <= got[0].P95 {
+		t.Errorf("5xx p95 %v isn't above the 2xx p95 %v", got[2].P95, got[0].P95)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "LATENCY BY STATUS CLASS") || !strings.Contains(buf.String(), "5xx: mean 3.000000") {
+		t.Errorf("latency by class missing from the report:\n%s", buf.String())
+	}
+}
+
+func TestLatencyByClassHidden(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	addClassLatencyFixture(m)
+
+	if got := m.Analyze().ClassLatencies; got != nil {
+		t.Errorf("class latencies = %v without LatencyByClass", got)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "LATENCY BY STATUS CLASS") {
+		t.Errorf("latency by class shown without LatencyByClass:\n%s", buf.String())
+	}
+}
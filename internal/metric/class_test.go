@@ -0,0 +1,77 @@
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestClassSummary(t *testing.T) {
+	statuses := []int64{200, 201, 204, 200, 301, 304, 404, 429, 500, 502, 503, parser.StatusNoResponse}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for i, status := range statuses {
+		path := "/a"
+
+		if i%2 == 0 {
+			path = "/b"
+		}
+
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: path},
+			RequestTime:    0.1,
+			UpstreamStatus: status,
+			TimedOut:       status == parser.StatusNoResponse,
+		}, "")
+	}
+
+	want := ClassSummary{Success: 4, Redirection: 2, ClientError: 2, ServerError: 3, Other: 1}
+
+	summary := m.Analyze().StatusClasses
+
+	if *summary != want {
+		t.Errorf("class summary = %+v, want %+v", *summary, want)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteClassSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != "2xx=4 3xx=2 4xx=2 5xx=3 other=1\n" {
+		t.Errorf("class summary line = %q", got)
+	}
+
+	buf.Reset()
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "Responses by status class: 2xx=4 3xx=2 4xx=2 5xx=3 other=1\n") {
+		t.Errorf("class summary missing from the report:\n%s", buf.String())
+	}
+}
+
+func TestClassSummaryString(t *testing.T) {
+	tests := []struct {
+		summary ClassSummary
+		want    string
+	}{
+		{ClassSummary{}, "2xx=0 3xx=0 4xx=0 5xx=0"},
+		{ClassSummary{Informational: 1, Success: 2}, "1xx=1 2xx=2 3xx=0 4xx=0 5xx=0"},
+		{ClassSummary{ServerError: 3, Other: 2}, "2xx=0 3xx=0 4xx=0 5xx=3 other=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.summary.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
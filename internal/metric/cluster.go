@@ -0,0 +1,225 @@
+package metric
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultClusterMaxDistinct is the default number of values before a segment is clustered
+const DefaultClusterMaxDistinct = 10
+
+const clusterWildcard = "*"
+
+// ClusterPaths infers a template for every path, replacing ID-like segments by *
+func ClusterPaths(paths []string, maxDistinct int) map[string]string {
+	segmentsByPath := make(map[string][]string, len(paths))
+
+	for _, path := range paths {
+		segmentsByPath[path] = strings.Split(path, "/")
+	}
+
+	for {
+		changed := false
+
+		// contexts maps a position and its other segments to the values taken there
+		contexts := make(map[string]map[string]bool)
+
+		for _, segments := range segmentsByPath {
+			for i := range segments {
+				key := clusterContext(segments, i)
+
+				if contexts[key] == nil {
+					contexts[key] = make(map[string]bool)
+				}
+
+				contexts[key][segments[i]] = true
+			}
+		}
+
+		for _, segments := range segmentsByPath {
+			for i, segment := range segments {
+				if segment == clusterWildcard || segment == "" {
+					continue
+				}
+
+				if isVariableSegment(contexts[clusterContext(segments, i)], maxDistinct) {
+					segments[i] = clusterWildcard
+					changed = true
+				}
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	res := make(map[string]string, len(paths))
+
+	for path, segments := range segmentsByPath {
+		res[path] = strings.Join(segments, "/")
+	}
+
+	return res
+}
+
+func clusterContext(segments []string, position int) string {
+	var sb strings.Builder
+
+	sb.WriteString(strconv.Itoa(len(segments)))
+
+	for i, segment := range segments {
+		sb.WriteByte('/')
+
+		switch {
+		case i == position:
+			sb.WriteByte('?')
+		case hasDigit(segment):
+			sb.WriteByte('#')
+		default:
+			sb.WriteString(segment)
+		}
+	}
+
+	return sb.String()
+}
+
+func isVariableSegment(values map[string]bool, maxDistinct int) bool {
+	if len(values) < 2 {
+		return false
+	}
+
+	if maxDistinct > 0 && len(values) > maxDistinct {
+		return true
+	}
+
+	for value := range values {
+		if value != clusterWildcard && !hasDigit(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasDigit(segment string) bool {
+	for _, r := range segment {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clusterGroups merges path groups into the groups of their inferred templates
+func (m *MetricCollector) clusterGroups() {
+	maxDistinct := m.ClusterMaxDistinct
+
+	if maxDistinct <= 0 {
+		maxDistinct = DefaultClusterMaxDistinct
+	}
+
+	paths := m.groups()
+	templates := ClusterPaths(paths, maxDistinct)
+
+	// merge in path order, so ties are settled the same way every run
+	for _, path := range paths {
+		if template := templates[path]; path != template {
+			m.mergeGroup(path, template)
+		}
+	}
+}
+
+// mergeGroup moves the metrics of group from into group to
+func (m *MetricCollector) mergeGroup(from, to string) {
+	if bucket, exists := m.latencyData[from]; exists {
+		if toBucket, exists := m.latencyData[to]; exists {
+			toBucket.merge(bucket, m.rand)
+		} else {
+			m.latencyData[to] = bucket
+		}
+
+		delete(m.latencyData, from)
+	}
+
+	if respBucket, exists := m.responseData[from]; exists {
+		toRespBucket, exists := m.responseData[to]
+
+		if !exists {
+			toRespBucket = make(ResponseMetric)
+			m.responseData[to] = toRespBucket
+		}
+
+		for code, num := range respBucket {
+			toRespBucket[code] += num
+		}
+
+		delete(m.responseData, from)
+	}
+
+	if respBucket, exists := m.timedOutStatusData[from]; exists {
+		delete(m.timedOutStatusData, from)
+		m.mergeTimedOutStatus(to, respBucket)
+	}
+
+	if timedOutMetric, exists := m.timedOutData[from]; exists {
+		toTimedOutMetric := m.timedOutData[to]
+		toTimedOutMetric.Count += timedOutMetric.Count
+		toTimedOutMetric.Total += timedOutMetric.Total
+		m.timedOutData[to] = toTimedOutMetric
+
+		delete(m.timedOutData, from)
+	}
+
+	if timing, exists := m.timingData[from]; exists {
+		if toTiming, exists := m.timingData[to]; exists {
+			toTiming.merge(timing)
+		} else {
+			m.timingData[to] = timing
+		}
+
+		delete(m.timingData, from)
+	}
+
+	if count, exists := m.routingFailureData[from]; exists {
+		m.routingFailureData[to] += count
+		delete(m.routingFailureData, from)
+	}
+
+	for _, burst := range m.bursts {
+		if burst.Group == from {
+			burst.Group = to
+		}
+	}
+
+	if window, exists := m.windowData[from]; exists {
+		if toWindow, exists := m.windowData[to]; exists {
+			toWindow.latencies = append(toWindow.latencies, window.latencies...)
+			toWindow.sortByTime()
+		} else {
+			m.windowData[to] = window
+		}
+
+		delete(m.windowData, from)
+	}
+
+	if size, exists := m.sizeData[from]; exists {
+		if toSize, exists := m.sizeData[to]; exists {
+			toSize.merge(size)
+		} else {
+			m.sizeData[to] = size
+		}
+
+		delete(m.sizeData, from)
+	}
+
+	m.mergeWorstGroup(from, to)
+	m.mergeSlowClientGroup(from, to)
+
+	if accumulators, exists := m.pluginData[from]; exists {
+		delete(m.pluginData, from)
+		m.mergePluginGroup(to, accumulators)
+	}
+}
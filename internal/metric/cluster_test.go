@@ -0,0 +1,86 @@
+package metric
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestClusterPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		paths       []string
+		maxDistinct int
+		want        map[string]string
+	}{
+		{
+			name:  "numeric ids",
+			paths: []string{"/a/1/b", "/a/2/b", "/a/3/c"},
+			want:  map[string]string{"/a/1/b": "/a/*/b", "/a/2/b": "/a/*/b", "/a/3/c": "/a/3/c"},
+		},
+		{
+			name:  "several id segments",
+			paths: []string{"/users/1/orders/10", "/users/2/orders/20"},
+			want:  map[string]string{"/users/1/orders/10": "/users/*/orders/*", "/users/2/orders/20": "/users/*/orders/*"},
+		},
+		{
+			name:  "different structure",
+			paths: []string{"/a/1/b", "/c/2/b", "/a/1/b/c"},
+			want:  map[string]string{"/a/1/b": "/a/1/b", "/c/2/b": "/c/2/b", "/a/1/b/c": "/a/1/b/c"},
+		},
+		{
+			name:  "words",
+			paths: []string{"/api/users", "/api/orders", "/api/health"},
+			want:  map[string]string{"/api/users": "/api/users", "/api/orders": "/api/orders", "/api/health": "/api/health"},
+		},
+		{
+			name:        "words over the distinct limit",
+			paths:       []string{"/u/alice", "/u/bob", "/u/carol"},
+			maxDistinct: 2,
+			want:        map[string]string{"/u/alice": "/u/*", "/u/bob": "/u/*", "/u/carol": "/u/*"},
+		},
+		{
+			name:  "single path",
+			paths: []string{"/a/1/b"},
+			want:  map[string]string{"/a/1/b": "/a/1/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxDistinct := tt.maxDistinct
+
+			if maxDistinct == 0 {
+				maxDistinct = DefaultClusterMaxDistinct
+			}
+
+			if got := ClusterPaths(tt.paths, maxDistinct); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("templates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterGroups(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.ClusterPaths = true
+
+	for i := 0; i < 5; i++ {
+		addPaths(m, fmt.Sprintf("/orders/%d", i))
+	}
+
+	addPaths(m, "/health", "/health")
+
+	report := m.Analyze()
+
+	if got, want := groupKeys(m), []string{"/health", "/orders/*"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("groups = %v, want %v", got, want)
+	}
+
+	orders := report.Groups[1]
+
+	if orders.LatencyCount != 5 || orders.ResponseTotal != 5 || orders.TimedOut.Total != 5 {
+		t.Errorf("/orders/* has %d latencies, %d responses and %d requests, want 5 of each",
+			orders.LatencyCount, orders.ResponseTotal, orders.TimedOut.Total)
+	}
+}
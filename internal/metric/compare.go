@@ -0,0 +1,148 @@
+package metric
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// GroupComparison compares a group before and after the collector's SplitAt
+type GroupComparison struct {
+	Group string
+
+	BeforeRequests int
+	AfterRequests  int
+
+	BeforeErrorRate float64
+	AfterErrorRate  float64
+
+	BeforeP95 *float64
+	AfterP95  *float64
+}
+
+// splitCollector returns the collector the result is aggregated into with SplitAt set
+func (m *MetricCollector) splitCollector(result *parser.NginxResult) *MetricCollector {
+	if m.SplitAt.IsZero() {
+		return m
+	}
+
+	if !result.TimeLocal.IsZero() {
+		m.splitPassed = !result.TimeLocal.Before(m.SplitAt)
+	}
+
+	if !m.splitPassed {
+		return m
+	}
+
+	if m.after == nil {
+		m.after = m.Shard()
+		m.after.SplitAt = time.Time{}
+	}
+
+	return m.after
+}
+
+// Comparison compares every group of either side of SplitAt, sorted by group
+func (m *MetricCollector) Comparison() []*GroupComparison {
+	after := m.after
+
+	if after == nil {
+		after = m.Shard()
+	}
+
+	groups := m.groups()
+
+	for _, group := range after.groups() {
+		if _, exists := m.timedOutData[group]; !exists {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Strings(groups)
+
+	res := make([]*GroupComparison, 0, len(groups))
+
+	for _, group := range groups {
+		res = append(res, &GroupComparison{
+			Group:           group,
+			BeforeRequests:  m.timedOutData[group].Total,
+			AfterRequests:   after.timedOutData[group].Total,
+			BeforeErrorRate: m.errorRate(group),
+			AfterErrorRate:  after.errorRate(group),
+			BeforeP95:       m.p95(group),
+			AfterP95:        after.p95(group),
+		})
+	}
+
+	return res
+}
+
+func (m *MetricCollector) p95(group string) *float64 {
+	bucket, exists := m.latencyData[group]
+
+	if !exists || len(bucket.Latencies) == 0 {
+		return nil
+	}
+
+	p95 := m.listPercentiles(bucket, 95)[0]
+
+	return &p95
+}
+
+// WriteComparison writes the comparison of the groups before and after SplitAt
+func (m *MetricCollector) WriteComparison(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "\n---------------------------------\nBEFORE AND AFTER %s\n---------------------------------\t\n", m.SplitAt.Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	for _, c := range m.Comparison() {
+		_, err := fmt.Fprintf(w, "%s: requests %d -> %d, error rate %.4f -> %.4f (%+.4f), p95 %s -> %s (%s)\n",
+			c.Group, c.BeforeRequests, c.AfterRequests,
+			c.BeforeErrorRate, c.AfterErrorRate, c.AfterErrorRate-c.BeforeErrorRate,
+			m.formatOptionalLatency(c.BeforeP95), m.formatOptionalLatency(c.AfterP95), m.latencyDelta(c.BeforeP95, c.AfterP95))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MetricCollector) formatOptionalLatency(latency *float64) string {
+	if latency == nil {
+		return "-"
+	}
+
+	return m.formatLatency(*latency)
+}
+
+func (m *MetricCollector) latencyDelta(before, after *float64) string {
+	if before == nil || after == nil {
+		return "-"
+	}
+
+	delta := *after - *before
+
+	if delta >= 0 {
+		return "+" + m.formatLatency(delta)
+	}
+
+	return "-" + m.formatLatency(-delta)
+}
+
+func (m *MetricCollector) mergeSplit(other *MetricCollector) {
+	if other.after == nil {
+		return
+	}
+
+	if m.after == nil {
+		m.after = m.Shard()
+		m.after.SplitAt = time.Time{}
+	}
+
+	m.after.Merge(other.after)
+}
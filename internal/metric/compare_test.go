@@ -0,0 +1,117 @@
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestSplitAtComparison(t *testing.T) {
+	split := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	requests := []struct {
+		path    string
+		offset  time.Duration
+		status  int64
+		latency float64
+	}{
+		{"/a", -2 * time.Minute, 200, 0.1},
+		{"/a", -time.Minute, 200, 0.1},
+		{"/a", 0, 502, 0.5},
+		{"/a", time.Minute, 200, 0.5},
+		{"/old", -time.Minute, 200, 0.2},
+		{"/new", time.Minute, 200, 0.3},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.SplitAt = split
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			TimeLocal:      split.Add(req.offset),
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    req.latency,
+			UpstreamStatus: req.status,
+		}, "")
+	}
+
+	// error log lines aren't timestamped, and go to the side of the previous result
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/new"},
+		UpstreamStatus: parser.StatusNoResponse,
+		TimedOut:       true,
+	}, "")
+
+	comparison := m.Comparison()
+
+	if len(comparison) != 3 {
+		t.Fatalf("got %d groups, want 3", len(comparison))
+	}
+
+	a, newGroup, old := comparison[0], comparison[1], comparison[2]
+
+	if a.Group != "/a" || a.BeforeRequests != 2 || a.AfterRequests != 2 {
+		t.Errorf("/a requests = %d -> %d, want 2 -> 2", a.BeforeRequests, a.AfterRequests)
+	}
+
+	if a.BeforeErrorRate != 0 || a.AfterErrorRate != 0.5 {
+		t.Errorf("/a error rate = %g -> %g, want 0 -> 0.5", a.BeforeErrorRate, a.AfterErrorRate)
+	}
+
+	if a.BeforeP95 == nil || *a.BeforeP95 != 0.1 || a.AfterP95 == nil || *a.AfterP95 != 0.5 {
+		t.Errorf("/a p95 = %v -> %v, want 0.1 -> 0.5", a.BeforeP95, a.AfterP95)
+	}
+
+	if newGroup.Group != "/new" || newGroup.BeforeRequests != 0 || newGroup.AfterRequests != 2 || newGroup.BeforeP95 != nil {
+		t.Errorf("/new = %+v, want only requests after the split", newGroup)
+	}
+
+	if old.Group != "/old" || old.BeforeRequests != 1 || old.AfterRequests != 0 || old.AfterP95 != nil {
+		t.Errorf("/old = %+v, want only requests before the split", old)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteComparison(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"BEFORE AND AFTER 2026-10-14T10:00:00Z",
+		"/a: requests 2 -> 2, error rate 0.0000 -> 0.5000 (+0.5000), p95 0.100000 -> 0.500000 (+0.400000)",
+		"/old: requests 1 -> 0, error rate 0.0000 -> 0.0000 (+0.0000), p95 0.200000 -> - (-)",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("comparison is missing %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestSplitAtMerge(t *testing.T) {
+	split := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.SplitAt = split
+
+	for _, offset := range []time.Duration{-time.Minute, time.Minute} {
+		shard := m.Shard()
+
+		shard.AddLine(&parser.NginxResult{
+			TimeLocal:      split.Add(offset),
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		}, "")
+
+		m.Merge(shard)
+	}
+
+	comparison := m.Comparison()
+
+	if len(comparison) != 1 || comparison[0].BeforeRequests != 1 || comparison[0].AfterRequests != 1 {
+		t.Errorf("merged comparison = %+v, want a request on each side", comparison[0])
+	}
+}
@@ -0,0 +1,123 @@
+package metric
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SummaryColumns are the columns of the summary CSV, after the group column
+var SummaryColumns = []string{"count", "mean", "p95", "error_rate", "timeout_rate"}
+
+// summaryColumnValues formats each summary column of a group
+var summaryColumnValues = map[string]func(m *MetricCollector, group *GroupReport) string{
+	"count": func(m *MetricCollector, group *GroupReport) string {
+		return strconv.Itoa(group.TimedOut.Total)
+	},
+	"mean": func(m *MetricCollector, group *GroupReport) string {
+		if group.LatencyCount == 0 {
+			return ""
+		}
+
+		return m.formatLatency(group.MeanLatency)
+	},
+	"p95": func(m *MetricCollector, group *GroupReport) string {
+		if group.LatencyCount == 0 {
+			return ""
+		}
+
+		return m.formatLatency(group.P95Latency)
+	},
+	"error_rate": func(m *MetricCollector, group *GroupReport) string {
+		return strconv.FormatFloat(group.ErrorRate, 'f', 4, 64)
+	},
+	"timeout_rate": func(m *MetricCollector, group *GroupReport) string {
+		return strconv.FormatFloat(group.TimeoutRate, 'f', 4, 64)
+	},
+}
+
+// ValidateSummaryColumns returns an error for columns that aren't SummaryColumns
+func ValidateSummaryColumns(columns []string) error {
+	for _, column := range columns {
+		if _, exists := summaryColumnValues[column]; !exists {
+			return fmt.Errorf("unknown summary column %s", column)
+		}
+	}
+
+	return nil
+}
+
+// WriteSummaryCSV writes one row of aggregates per reported group
+func (m *MetricCollector) WriteSummaryCSV(w io.Writer, columns []string) error {
+	if len(columns) == 0 {
+		columns = SummaryColumns
+	}
+
+	if err := ValidateSummaryColumns(columns); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(append([]string{"group"}, columns...)); err != nil {
+		return err
+	}
+
+	for _, group := range m.Analyze().Groups {
+		row := []string{group.Key}
+
+		for _, column := range columns {
+			row = append(row, summaryColumnValues[column](m, group))
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteToCSV writes every tracked latency sample to the file at path
+func (m *MetricCollector) WriteToCSV(path string) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	if err := writer.Write([]string{"group", "time", "latency"}); err != nil {
+		return err
+	}
+
+	for _, group := range sortedKeys(m.latencyData) {
+		for _, latency := range m.latencyData[group].Latencies {
+			timestamp := ""
+
+			if !latency.time.IsZero() {
+				timestamp = m.displayTime(latency.time).Format(time.RFC3339)
+			}
+
+			if err := writer.Write([]string{group, timestamp, strconv.FormatFloat(latency.latency, 'f', -1, 64)}); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,160 @@
+package metric
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestWriteSummaryCSV(t *testing.T) {
+	requests := []struct {
+		path     string
+		latency  float64
+		status   int64
+		timedOut bool
+	}{
+		{"/api", 0.1, 200, false},
+		{"/api", 0.3, 200, false},
+		{"/api", 0.2, 502, false},
+		{"/api", 0.4, 200, false},
+		{"/slow", 0.5, 200, false},
+		{"/slow", 0, 504, true},
+		{"/down", 0, parser.StatusNoResponse, true},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    req.latency,
+			UpstreamStatus: req.status,
+			UpstreamAddr:   "10.0.0.1:80",
+			TimedOut:       req.timedOut,
+		}, "")
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{
+			name: "default columns",
+			want: "group,count,mean,p95,error_rate,timeout_rate\n" +
+				"/api,4,0.250000,0.400000,0.2500,0.0000\n" +
+				"/down,1,,,1.0000,1.0000\n" +
+				"/slow,2,0.500000,0.500000,0.5000,0.5000\n",
+		},
+		{
+			name:    "selected columns",
+			columns: []string{"timeout_rate", "count"},
+			want: "group,timeout_rate,count\n" +
+				"/api,0.0000,4\n" +
+				"/down,1.0000,1\n" +
+				"/slow,0.5000,2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			if err := m.WriteSummaryCSV(&buf, tt.columns); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("summary CSV =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSummaryCSVInvalidColumn(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	if err := m.WriteSummaryCSV(&bytes.Buffer{}, []string{"count", "median"}); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
+
+func TestWriteToCSV(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.FixedZone("", 0))
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		want     string
+	}{
+		{
+			name: "log offset",
+			want: "group,time,latency\n" +
+				"/a,2026-10-14T10:00:00Z,0.1\n" +
+				"/a,2026-10-14T10:00:01Z,0.25\n" +
+				"/b,2026-10-14T10:00:02Z,0.5\n",
+		},
+		{
+			name:     "display zone",
+			location: newYork,
+			want: "group,time,latency\n" +
+				"/a,2026-10-14T06:00:00-04:00,0.1\n" +
+				"/a,2026-10-14T06:00:01-04:00,0.25\n" +
+				"/b,2026-10-14T06:00:02-04:00,0.5\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Location = tt.location
+
+			for i, req := range []struct {
+				path    string
+				latency float64
+			}{{"/a", 0.1}, {"/a", 0.25}, {"/b", 0.5}} {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      start.Add(time.Duration(i) * time.Second),
+					Request:        &parser.Request{Method: "GET", Path: req.path},
+					RequestTime:    req.latency,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			path := filepath.Join(t.TempDir(), "latencies.csv")
+
+			if err := m.WriteToCSV(path); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(path)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteToCSVUnwritable(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	if err := m.WriteToCSV(filepath.Join(t.TempDir(), "missing", "latencies.csv")); err == nil {
+		t.Error("expected an error writing to a missing directory")
+	}
+}
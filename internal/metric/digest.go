@@ -0,0 +1,183 @@
+package metric
+
+import (
+	"math"
+	"sort"
+)
+
+// digestCompression is the compression of the latency t-digests
+const digestCompression = 100
+
+// digestBuffer is the number of values buffered before they're merged into centroids
+const digestBuffer = 500
+
+// tDigest is a merging t-digest, estimating latency quantiles in bounded memory
+type tDigest struct {
+	centroids []centroid
+	unmerged  []centroid
+	min       float64
+	max       float64
+}
+
+type centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+// add adds the value with the weight
+func (d *tDigest) add(value, weight float64) {
+	if len(d.centroids) == 0 && len(d.unmerged) == 0 {
+		d.min, d.max = value, value
+	}
+
+	d.min = math.Min(d.min, value)
+	d.max = math.Max(d.max, value)
+	d.unmerged = append(d.unmerged, centroid{value, weight})
+
+	if len(d.unmerged) >= digestBuffer {
+		d.compress()
+	}
+}
+
+// merge adds the values of other to the digest
+func (d *tDigest) merge(other *tDigest) {
+	if other.empty() {
+		return
+	}
+
+	if d.empty() {
+		d.min, d.max = other.min, other.max
+	}
+
+	d.min = math.Min(d.min, other.min)
+	d.max = math.Max(d.max, other.max)
+	d.unmerged = append(d.unmerged, other.centroids...)
+	d.unmerged = append(d.unmerged, other.unmerged...)
+	d.compress()
+}
+
+func (d *tDigest) empty() bool {
+	return len(d.centroids) == 0 && len(d.unmerged) == 0
+}
+
+// compress merges the buffered values into the centroids
+func (d *tDigest) compress() {
+	if len(d.unmerged) == 0 {
+		return
+	}
+
+	all := append(d.centroids, d.unmerged...)
+	d.unmerged = nil
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Mean < all[j].Mean
+	})
+
+	var total float64
+
+	for _, c := range all {
+		total += c.Weight
+	}
+
+	merged := make([]centroid, 0, digestCompression)
+	current := all[0]
+	before := 0.0
+	limit := total * digestQuantileLimit(0)
+
+	for _, c := range all[1:] {
+		if before+current.Weight+c.Weight <= limit {
+			current.Weight += c.Weight
+			current.Mean += (c.Mean - current.Mean) * c.Weight / current.Weight
+			continue
+		}
+
+		before += current.Weight
+		merged = append(merged, current)
+		limit = total * digestQuantileLimit(before/total)
+		current = c
+	}
+
+	d.centroids = append(merged, current)
+}
+
+// digestQuantileLimit returns the quantile one unit of the k1 scale function past q
+func digestQuantileLimit(q float64) float64 {
+	k := digestCompression / (2 * math.Pi) * math.Asin(2*q-1)
+
+	return (math.Sin(math.Min(k+1, digestCompression/4)*2*math.Pi/digestCompression) + 1) / 2
+}
+
+// quantile returns the estimated value at quantile q, or 0 if the digest is empty
+func (d *tDigest) quantile(q float64) float64 {
+	d.compress()
+
+	if len(d.centroids) == 0 {
+		return 0
+	}
+
+	var total float64
+
+	for _, c := range d.centroids {
+		total += c.Weight
+	}
+
+	target := q * total
+	first, last := d.centroids[0], d.centroids[len(d.centroids)-1]
+
+	// the tails interpolate between the extreme values and the outermost centers
+	if target <= first.Weight/2 {
+		if first.Weight <= 1 {
+			return first.Mean
+		}
+
+		return d.min + (first.Mean-d.min)*target/(first.Weight/2)
+	}
+
+	if target >= total-last.Weight/2 {
+		if last.Weight <= 1 {
+			return last.Mean
+		}
+
+		return d.max - (d.max-last.Mean)*(total-target)/(last.Weight/2)
+	}
+
+	center := first.Weight / 2
+
+	for i := 1; i < len(d.centroids); i++ {
+		prev, c := d.centroids[i-1], d.centroids[i]
+		next := center + (prev.Weight+c.Weight)/2
+
+		if target <= next {
+			return prev.Mean + (c.Mean-prev.Mean)*(target-center)/(next-center)
+		}
+
+		center = next
+	}
+
+	return last.Mean
+}
+
+// digestState is the state of a t-digest, with exported fields for gob
+type digestState struct {
+	Centroids []centroid
+	Min       float64
+	Max       float64
+}
+
+func encodeDigest(d *tDigest) *digestState {
+	if d == nil {
+		return nil
+	}
+
+	d.compress()
+
+	return &digestState{d.centroids, d.min, d.max}
+}
+
+func decodeDigest(state *digestState) *tDigest {
+	if state == nil {
+		return nil
+	}
+
+	return &tDigest{centroids: state.Centroids, min: state.Min, max: state.Max}
+}
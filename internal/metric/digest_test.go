@@ -0,0 +1,112 @@
+package metric
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDigestQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		values func(n int) []float64
+		n      int
+		shards int
+	}{
+		{
+			name: "uniform",
+			n:    100000,
+			values: func(n int) []float64 {
+				res := make([]float64, n)
+
+				for i := range res {
+					res[i] = rng.Float64()
+				}
+
+				return res
+			},
+		},
+		{
+			name:   "exponential shards",
+			n:      100000,
+			shards: 8,
+			values: func(n int) []float64 {
+				res := make([]float64, n)
+
+				for i := range res {
+					res[i] = rng.ExpFloat64() * 0.2
+				}
+
+				return res
+			},
+		},
+		{
+			name: "few values",
+			n:    10,
+			values: func(n int) []float64 {
+				res := make([]float64, n)
+
+				for i := range res {
+					res[i] = float64(i + 1)
+				}
+
+				return res
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values(tt.n)
+			digest := &tDigest{}
+
+			if tt.shards == 0 {
+				for _, value := range values {
+					digest.add(value, 1)
+				}
+			} else {
+				step := len(values) / tt.shards
+
+				for i := 0; i < tt.shards; i++ {
+					shard := &tDigest{}
+
+					for _, value := range values[i*step : (i+1)*step] {
+						shard.add(value, 1)
+					}
+
+					digest.merge(shard)
+				}
+			}
+
+			if len(digest.centroids) > digestCompression {
+				t.Errorf("got %d centroids, want at most %d", len(digest.centroids), digestCompression)
+			}
+
+			sort.Float64s(values)
+
+			for _, q := range []float64{0, 0.01, 0.5, 0.95, 0.99, 0.999, 1} {
+				estimate := digest.quantile(q)
+				// the rank of the estimate among the values
+				rank := float64(sort.SearchFloat64s(values, estimate)) / float64(len(values))
+				tolerance := 0.002
+
+				if len(values) < digestCompression {
+					tolerance = 1 / float64(len(values))
+				}
+
+				if math.Abs(rank-q) > tolerance {
+					t.Errorf("q%g = %g with rank %g, want a rank within %g", q, estimate, rank, tolerance)
+				}
+			}
+		})
+	}
+}
+
+func TestDigestEmpty(t *testing.T) {
+	if got := (&tDigest{}).quantile(0.5); got != 0 {
+		t.Errorf("empty digest median = %g, want 0", got)
+	}
+}
@@ -0,0 +1,71 @@
+package metric
+
+import "sort"
+
+// DefaultReqIDCap is the default number of distinct request IDs tracked for duplicates
+const DefaultReqIDCap = 100000
+
+// trackReqID counts occurrences of the request ID, up to ReqIDCap distinct IDs
+func (m *MetricCollector) trackReqID(reqID string) {
+	if reqID == "" || m.ReqIDCap <= 0 {
+		return
+	}
+
+	if m.reqIDData == nil {
+		m.reqIDData = make(map[string]uint)
+	}
+
+	count, exists := m.reqIDData[reqID]
+
+	if !exists && len(m.reqIDData) >= m.ReqIDCap {
+		m.reqIDsCapped = true
+		return
+	}
+
+	m.reqIDData[reqID] = count + 1
+}
+
+// mergeReqIDs adds the request ID counts of other, within ReqIDCap
+func (m *MetricCollector) mergeReqIDs(other *MetricCollector) {
+	m.reqIDsCapped = m.reqIDsCapped || other.reqIDsCapped
+
+	if len(other.reqIDData) == 0 {
+		return
+	}
+
+	if m.reqIDData == nil {
+		m.reqIDData = make(map[string]uint)
+	}
+
+	// add the IDs in sorted order, so the same IDs are dropped at the cap every time
+	reqIDs := make([]string, 0, len(other.reqIDData))
+
+	for reqID := range other.reqIDData {
+		reqIDs = append(reqIDs, reqID)
+	}
+
+	sort.Strings(reqIDs)
+
+	for _, reqID := range reqIDs {
+		count, exists := m.reqIDData[reqID]
+
+		if !exists && len(m.reqIDData) >= m.ReqIDCap {
+			m.reqIDsCapped = true
+			continue
+		}
+
+		m.reqIDData[reqID] = count + other.reqIDData[reqID]
+	}
+}
+
+// duplicateReqIDs returns the number of duplicate request IDs and of their lines
+func (m *MetricCollector) duplicateReqIDs() (ids uint, lines uint) {
+	for _, count := range m.reqIDData {
+		if count > 1 {
+			ids++
+			lines += count - 1
+		}
+	}
+
+	return ids, lines
+}
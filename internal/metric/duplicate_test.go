@@ -0,0 +1,61 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// addReqIDs adds a request for each request ID to the collector
+func addReqIDs(m *MetricCollector, reqIDs ...string) {
+	for _, reqID := range reqIDs {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+			ReqID:          reqID,
+		}, "")
+	}
+}
+
+func TestDuplicateReqIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		reqIDCap   int
+		reqIDs     []string
+		wantIDs    uint
+		wantLines  uint
+		wantCapped bool
+	}{
+		{"unique", DefaultReqIDCap, []string{"a", "b", "c"}, 0, 0, false},
+		{"duplicates", DefaultReqIDCap, []string{"a", "b", "a", "c", "a", "b"}, 2, 3, false},
+		{"empty ids are ignored", DefaultReqIDCap, []string{"", "", "a"}, 0, 0, false},
+		{"disabled", 0, []string{"a", "a"}, 0, 0, false},
+		{"tracked ids are still counted at the cap", 2, []string{"a", "b", "a", "b"}, 2, 2, false},
+		{"ids over the cap are not tracked", 2, []string{"a", "b", "c", "c", "a"}, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.ReqIDCap = tt.reqIDCap
+
+			addReqIDs(m, tt.reqIDs...)
+
+			report := m.Analyze()
+
+			if report.DuplicateReqIDs != tt.wantIDs || report.DuplicateReqIDLines != tt.wantLines {
+				t.Errorf("duplicates = %d ids and %d lines, want %d and %d",
+					report.DuplicateReqIDs, report.DuplicateReqIDLines, tt.wantIDs, tt.wantLines)
+			}
+
+			if report.ReqIDsCapped != tt.wantCapped {
+				t.Errorf("ReqIDsCapped = %t, want %t", report.ReqIDsCapped, tt.wantCapped)
+			}
+
+			if tt.reqIDCap > 0 && len(m.reqIDData) > tt.reqIDCap {
+				t.Errorf("tracked %d request IDs, over the cap of %d", len(m.reqIDData), tt.reqIDCap)
+			}
+		})
+	}
+}
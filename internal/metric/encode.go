@@ -0,0 +1,362 @@
+package metric
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+// collectorState is the aggregated state of a collector, with exported fields for gob
+type collectorState struct {
+	Latency       map[string]*latencyListState
+	Response      map[string]ResponseMetric
+	TimedOut      map[string]TimedOutMetric
+	TimedOutCodes map[string]ResponseMetric
+	ErrorCategory map[string]uint
+	Timing        map[string]*timingState
+	Size          map[string]*sizeState
+	Talkers       map[string]*talkerState
+	SlowClients   []*SlowClientCount
+	Worst         []*WorstRequest
+	Upstreams     map[string]TimedOutMetric
+	Routing       map[string]uint
+	Window        map[string][]latencyState
+	Latest        time.Time
+	ClassLatency  map[int64]*latencyListState
+	Bursts        []*Burst
+	Heatmap       map[heatmapCell]uint
+	HeatmapStart  time.Time
+	Plugin        map[string]map[string]PluginAccumulator
+	// After is the encoded collector of the results after SplitAt
+	After []byte
+
+	Approximate       bool
+	RejectedLatencies uint
+	Earliest          time.Time
+	WarmupSkipped     uint
+	ReqIDs            map[string]uint
+	ReqIDsCapped      bool
+	Untimed           uint
+}
+
+type latencyListState struct {
+	IP           string
+	Latencies    []latencyState
+	Capacity     int
+	Count        int
+	Sum          float64
+	Over2s       int
+	Digest       *digestState
+	ArrivalFirst time.Time
+	ArrivalLast  time.Time
+	ArrivalGaps  []int
+	Trend        trendState
+}
+
+// trendState holds the origin and sums of a trend line fit
+type trendState struct {
+	Origin time.Time
+	Sums   [5]float64
+}
+
+type latencyState struct {
+	Latency float64
+	Time    time.Time
+}
+
+// timingState holds the sum and count of the connect, header, response and total timings
+type timingState struct {
+	Sums   [4]float64
+	Counts [4]int
+}
+
+type sizeState struct {
+	Count         int
+	BodyBytes     int64
+	UpstreamBytes int64
+}
+
+type talkerState struct {
+	Capacity int
+	Counts   map[string]uint
+}
+
+func (t *TimingMetric) sums() [4]*timingSum {
+	return [4]*timingSum{&t.connect, &t.header, &t.response, &t.total}
+}
+
+func encodeLatencyList(bucket *LatencyMetricList) *latencyListState {
+	latencies := make([]latencyState, len(bucket.Latencies))
+
+	for i, latency := range bucket.Latencies {
+		latencies[i] = latencyState{latency.latency, latency.time}
+	}
+
+	return &latencyListState{
+		IP:           bucket.IP,
+		Latencies:    latencies,
+		Capacity:     bucket.capacity,
+		Count:        bucket.count,
+		Sum:          bucket.sum,
+		Over2s:       bucket.over2s,
+		Digest:       encodeDigest(bucket.digest),
+		ArrivalFirst: bucket.arrival.first,
+		ArrivalLast:  bucket.arrival.last,
+		ArrivalGaps:  bucket.arrival.gaps,
+		Trend:        encodeTrend(&bucket.trend),
+	}
+}
+
+func decodeLatencyList(bucket *latencyListState) *LatencyMetricList {
+	latencies := make([]*LatencyMetric, len(bucket.Latencies))
+
+	for i, latency := range bucket.Latencies {
+		latencies[i] = &LatencyMetric{latency.Latency, latency.Time}
+	}
+
+	return &LatencyMetricList{
+		IP:        bucket.IP,
+		Latencies: latencies,
+		capacity:  bucket.Capacity,
+		count:     bucket.Count,
+		sum:       bucket.Sum,
+		over2s:    bucket.Over2s,
+		digest:    decodeDigest(bucket.Digest),
+		arrival:   arrivalCounter{bucket.ArrivalFirst, bucket.ArrivalLast, bucket.ArrivalGaps},
+		trend:     decodeTrend(bucket.Trend),
+	}
+}
+
+func encodeTrend(trend *trendSums) trendState {
+	return trendState{trend.origin, [5]float64{trend.n, trend.sumX, trend.sumY, trend.sumXY, trend.sumXX}}
+}
+
+func decodeTrend(state trendState) trendSums {
+	return trendSums{state.Origin, state.Sums[0], state.Sums[1], state.Sums[2], state.Sums[3], state.Sums[4]}
+}
+
+// GobEncode encodes the metrics aggregated by the collector, but not its configuration
+func (m *MetricCollector) GobEncode() ([]byte, error) {
+	state := &collectorState{
+		Latency:           make(map[string]*latencyListState, len(m.latencyData)),
+		Response:          m.responseData,
+		TimedOutCodes:     m.timedOutStatusData,
+		TimedOut:          m.timedOutData,
+		ErrorCategory:     m.errorCategoryData,
+		Timing:            make(map[string]*timingState, len(m.timingData)),
+		Size:              make(map[string]*sizeState, len(m.sizeData)),
+		Talkers:           make(map[string]*talkerState, len(m.talkersData)),
+		Approximate:       m.approximate,
+		RejectedLatencies: m.rejectedLatencies,
+		Earliest:          m.earliest,
+		WarmupSkipped:     m.warmupSkipped,
+		ReqIDs:            m.reqIDData,
+		ReqIDsCapped:      m.reqIDsCapped,
+		Untimed:           m.untimed,
+		Upstreams:         m.upstreamTimeoutData,
+		Routing:           m.routingFailureData,
+		Window:            make(map[string][]latencyState, len(m.windowData)),
+		Latest:            m.latest,
+		ClassLatency:      make(map[int64]*latencyListState, len(m.classLatencyData)),
+		Bursts:            m.bursts,
+		Heatmap:           m.heatmapData,
+		HeatmapStart:      m.heatmapStart,
+		Plugin:            m.pluginData,
+	}
+
+	if m.after != nil {
+		after, err := m.after.GobEncode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		state.After = after
+	}
+
+	for group, window := range m.windowData {
+		for _, latency := range window.latencies {
+			state.Window[group] = append(state.Window[group], latencyState{latency.latency, latency.time})
+		}
+	}
+
+	for group, bucket := range m.latencyData {
+		state.Latency[group] = encodeLatencyList(bucket)
+	}
+
+	for class, bucket := range m.classLatencyData {
+		state.ClassLatency[class] = encodeLatencyList(bucket)
+	}
+
+	for group, timing := range m.timingData {
+		timingState := &timingState{}
+
+		for i, sum := range timing.sums() {
+			timingState.Sums[i] = sum.sum
+			timingState.Counts[i] = sum.count
+		}
+
+		state.Timing[group] = timingState
+	}
+
+	for group, size := range m.sizeData {
+		state.Size[group] = &sizeState{size.count, size.bodyBytes, size.upstreamBytes}
+	}
+
+	for kind, counter := range m.talkersData {
+		state.Talkers[kind] = &talkerState{counter.capacity, counter.counts()}
+	}
+
+	for key, count := range m.slowClientData {
+		state.SlowClients = append(state.SlowClients, &SlowClientCount{key.group, key.clientIP, count})
+	}
+
+	for _, group := range sortedFloatKeys(m.worstData) {
+		line, _ := m.sampledLine(worstLineKey(group))
+		state.Worst = append(state.Worst, &WorstRequest{group, m.worstData[group], line})
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the metrics of the collector with the encoded ones
+func (m *MetricCollector) GobDecode(data []byte) error {
+	state := &collectorState{}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(state); err != nil {
+		return err
+	}
+
+	m.latencyData = make(map[string]*LatencyMetricList, len(state.Latency))
+	m.responseData = state.Response
+	m.timedOutData = state.TimedOut
+	m.timedOutStatusData = state.TimedOutCodes
+	m.errorCategoryData = state.ErrorCategory
+	m.timingData = make(map[string]*TimingMetric, len(state.Timing))
+	m.sizeData = make(map[string]*SizeMetric, len(state.Size))
+	m.talkersData = nil
+	m.slowClientData = nil
+	m.worstData = nil
+	m.lineSamples = nil
+	m.approximate = state.Approximate
+	m.rejectedLatencies = state.RejectedLatencies
+	m.earliest = state.Earliest
+	m.warmupSkipped = state.WarmupSkipped
+	m.reqIDData = state.ReqIDs
+	m.reqIDsCapped = state.ReqIDsCapped
+	m.untimed = state.Untimed
+	m.upstreamTimeoutData = state.Upstreams
+	m.routingFailureData = state.Routing
+	m.windowData = nil
+	m.latest = state.Latest
+
+	for group, latencies := range state.Window {
+		if m.windowData == nil {
+			m.windowData = make(map[string]*latencyWindow)
+		}
+
+		window := &latencyWindow{}
+
+		for _, latency := range latencies {
+			window.add(&LatencyMetric{latency.Latency, latency.Time})
+		}
+
+		m.windowData[group] = window
+	}
+
+	// gob leaves empty maps nil, and AddLine expects these to be set
+	if m.responseData == nil {
+		m.responseData = make(map[string]ResponseMetric)
+	}
+
+	if m.timedOutData == nil {
+		m.timedOutData = make(map[string]TimedOutMetric)
+	}
+
+	if m.errorCategoryData == nil {
+		m.errorCategoryData = make(map[string]uint)
+	}
+
+	for group, bucket := range state.Latency {
+		m.latencyData[group] = decodeLatencyList(bucket)
+	}
+
+	m.classLatencyData = nil
+	m.burstData = nil
+	m.bursts = state.Bursts
+	m.heatmapData = state.Heatmap
+	m.heatmapStart = state.HeatmapStart
+	m.pluginData = state.Plugin
+	m.after = nil
+
+	if state.After != nil {
+		m.after = m.Shard()
+		m.after.SplitAt = time.Time{}
+
+		if err := m.after.GobDecode(state.After); err != nil {
+			return err
+		}
+	}
+
+	for class, bucket := range state.ClassLatency {
+		if m.classLatencyData == nil {
+			m.classLatencyData = make(map[int64]*LatencyMetricList)
+		}
+
+		m.classLatencyData[class] = decodeLatencyList(bucket)
+	}
+
+	for group, timingState := range state.Timing {
+		timing := &TimingMetric{}
+
+		for i, sum := range timing.sums() {
+			sum.sum = timingState.Sums[i]
+			sum.count = timingState.Counts[i]
+		}
+
+		m.timingData[group] = timing
+	}
+
+	for group, size := range state.Size {
+		m.sizeData[group] = &SizeMetric{size.Count, size.BodyBytes, size.UpstreamBytes}
+	}
+
+	if len(state.Talkers) > 0 {
+		m.talkersData = make(map[string]*talkerCounter, len(state.Talkers))
+
+		for kind, talkers := range state.Talkers {
+			counter := newTalkerCounter(talkers.Capacity)
+
+			for ip, count := range talkers.Counts {
+				counter.insert(ip, count)
+			}
+
+			m.talkersData[kind] = counter
+		}
+	}
+
+	for _, slowClient := range state.SlowClients {
+		if m.slowClientData == nil {
+			m.slowClientData = make(map[slowClientKey]uint)
+		}
+
+		m.slowClientData[slowClientKey{slowClient.Group, slowClient.ClientIP}] = slowClient.Count
+	}
+
+	for _, worst := range state.Worst {
+		if m.worstData == nil {
+			m.worstData = make(map[string]float64)
+		}
+
+		m.worstData[worst.Group] = worst.Latency
+		m.sampleLine(worstLineKey(worst.Group), worst.Line)
+	}
+
+	return nil
+}
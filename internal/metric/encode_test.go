@@ -0,0 +1,77 @@
+package metric
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGobRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(m *MetricCollector)
+	}{
+		{"default", func(m *MetricCollector) {}},
+		{"talkers", func(m *MetricCollector) { m.Talkers = 3 }},
+		{"worst requests", func(m *MetricCollector) { m.WorstRequests = true }},
+		{"slow clients", func(m *MetricCollector) { m.SlowClients = &SlowClientConfig{MinRequestTime: 1, MaxBytes: 1024} }},
+		{"window", func(m *MetricCollector) { m.Window = time.Minute }},
+		{"response sizes", func(m *MetricCollector) { m.ResponseSizes = true }},
+		{"sla tiers", func(m *MetricCollector) { m.SLATiers = []float64{0.1, 1} }},
+		{"latency by class", func(m *MetricCollector) { m.LatencyByClass = true }},
+		{"bursts", func(m *MetricCollector) { m.BurstSize, m.BurstWindow = 2, time.Minute }},
+		{"reservoir", func(m *MetricCollector) { m.Reservoir = 10 }},
+		{"split", func(m *MetricCollector) { m.SplitAt = time.Date(2026, 10, 14, 10, 3, 0, 0, time.UTC) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newCollector := func() *MetricCollector {
+				m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+				tt.configure(m)
+				return m
+			}
+
+			m := newCollector()
+			collectFixture(t, m)
+
+			data, err := m.GobEncode()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded := newCollector()
+
+			if err := decoded.GobDecode(data); err != nil {
+				t.Fatal(err)
+			}
+
+			// the decoded aggregates are merged elsewhere, like with --merge-aggregate
+			merged := newCollector()
+			merged.Merge(decoded)
+
+			want := reportJSON(t, m)
+
+			if got := reportJSON(t, decoded); got != want {
+				t.Errorf("decoded report =\n%s\nwant\n%s", got, want)
+			}
+
+			if got := reportJSON(t, merged); got != want {
+				t.Errorf("merged report =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func reportJSON(t *testing.T, m *MetricCollector) string {
+	t.Helper()
+
+	data, err := json.Marshal(m.Analyze())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,38 @@
+package metric
+
+import "regexp"
+
+const errorCategoryOther = "other"
+
+// ErrorCategory groups error log messages matching Pattern under Name
+type ErrorCategory struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultErrorCategories are the error log message categories, matched in order
+var DefaultErrorCategories = []ErrorCategory{
+	{"upstream timed out", regexp.MustCompile(`(?i)upstream timed out`)},
+	{"connection refused", regexp.MustCompile(`(?i)connection refused`)},
+	{"connection reset", regexp.MustCompile(`(?i)connection reset by peer`)},
+	{"no live upstreams", regexp.MustCompile(`(?i)no live upstreams`)},
+	{"upstream prematurely closed", regexp.MustCompile(`(?i)upstream prematurely closed`)},
+	// matched last and only on nginx's own SSL phrasing
+	{"ssl error", regexp.MustCompile(`(?i)SSL_do_handshake\(\)|SSL_read\(\)|SSL_write\(\)|ssl handshake`)},
+}
+
+func (m *MetricCollector) categorizeError(message string) string {
+	categories := m.ErrorCategories
+
+	if categories == nil {
+		categories = DefaultErrorCategories
+	}
+
+	for _, category := range categories {
+		if category.Pattern.MatchString(message) {
+			return category.Name
+		}
+	}
+
+	return errorCategoryOther
+}
@@ -0,0 +1,110 @@
+package metric
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestCategorizeError(t *testing.T) {
+	const suffix = `, client: 10.0.0.1, server: example.com, request: "GET /api/ssl-certs HTTP/1.1", upstream: "http://sslproxy.default.svc:8443/api/ssl-certs", host: "example.com"`
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "upstream timed out",
+			message: `upstream timed out (110: Connection timed out) while reading response header from upstream`,
+			want:    "upstream timed out",
+		},
+		{
+			name:    "connection refused",
+			message: `connect() failed (111: Connection refused) while connecting to upstream`,
+			want:    "connection refused",
+		},
+		{
+			name:    "connection reset",
+			message: `recv() failed (104: Connection reset by peer) while reading response header from upstream`,
+			want:    "connection reset",
+		},
+		{
+			name:    "no live upstreams",
+			message: `no live upstreams while connecting to upstream`,
+			want:    "no live upstreams",
+		},
+		{
+			name:    "upstream prematurely closed",
+			message: `upstream prematurely closed connection while reading response header from upstream`,
+			want:    "upstream prematurely closed",
+		},
+		{
+			name:    "ssl handshake",
+			message: `SSL_do_handshake() failed (SSL: error:1408F10B:SSL routines:ssl3_get_record:wrong version number) while SSL handshaking to upstream`,
+			want:    "ssl error",
+		},
+		{
+			name:    "ssl read",
+			message: `SSL_read() failed (SSL: error:0A000126:SSL routines::unexpected eof while reading) while reading upstream`,
+			want:    "ssl error",
+		},
+		{
+			name:    "peer closed in ssl handshake",
+			message: `peer closed connection in SSL handshake while SSL handshaking to upstream`,
+			want:    "ssl error",
+		},
+		{
+			name:    "timed out while ssl handshaking",
+			message: `upstream timed out (110: Connection timed out) while SSL handshaking to upstream`,
+			want:    "upstream timed out",
+		},
+		{
+			name:    "other",
+			message: `client intended to send too large body: 1048577 bytes`,
+			want:    errorCategoryOther,
+		},
+	}
+
+	factory := &parser.NginxParserFactory{}
+	factory.Init(map[string]interface{}{})
+	p := factory.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a full error log line, with an upstream, host and path that all contain "ssl"
+			line := `2026/10/14 10:00:00 [error] 31#31: *12345 ` + tt.message + suffix
+
+			res, err := p.Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.ErrorMessage == "" {
+				t.Fatalf("no error message parsed from %q", line)
+			}
+
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.AddLine(res, line)
+
+			if m.errorCategoryData[tt.want] != 1 {
+				t.Errorf("categories = %v, want 1 %q", m.errorCategoryData, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategorizeErrorCustom(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.ErrorCategories = append([]ErrorCategory{{"limited", regexp.MustCompile(`limiting requests`)}}, DefaultErrorCategories...)
+
+	if got := m.categorizeError(`limiting requests, excess: 10.500 by zone "api"`); got != "limited" {
+		t.Errorf("category = %q, want limited", got)
+	}
+
+	if got := m.categorizeError(`no live upstreams while connecting to upstream`); got != "no live upstreams" {
+		t.Errorf("category = %q, want no live upstreams", got)
+	}
+}
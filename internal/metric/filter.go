@@ -0,0 +1,96 @@
+package metric
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// StatusRange is an inclusive range of status codes
+type StatusRange struct {
+	Min int64
+	Max int64
+}
+
+func (r StatusRange) Contains(status int64) bool {
+	return status >= r.Min && status <= r.Max
+}
+
+// ParseStatusRanges parses statuses like 304, 300-399 or 3xx into status ranges
+func ParseStatusRanges(specs []string) ([]StatusRange, error) {
+	res := make([]StatusRange, 0, len(specs))
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+
+		if len(spec) == 3 && strings.HasSuffix(strings.ToLower(spec), "xx") {
+			class, err := strconv.ParseInt(spec[:1], 10, 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid status class %s", spec)
+			}
+
+			res = append(res, StatusRange{class * 100, class*100 + 99})
+			continue
+		}
+
+		bounds := strings.SplitN(spec, "-", 2)
+
+		min, err := strconv.ParseInt(bounds[0], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %s", spec)
+		}
+
+		max := min
+
+		if len(bounds) == 2 {
+			if max, err = strconv.ParseInt(bounds[1], 10, 64); err != nil || max < min {
+				return nil, fmt.Errorf("invalid status range %s", spec)
+			}
+		}
+
+		res = append(res, StatusRange{min, max})
+	}
+
+	return res, nil
+}
+
+func statusInRanges(status int64, ranges []StatusRange) bool {
+	for _, r := range ranges {
+		if r.Contains(status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// methodFiltered returns whether the result is dropped by IncludeMethods or ExcludeMethods
+func (m *MetricCollector) methodFiltered(result *parser.NginxResult) bool {
+	if len(m.IncludeMethods) == 0 && len(m.ExcludeMethods) == 0 {
+		return false
+	}
+
+	if result.Request == nil {
+		return len(m.IncludeMethods) > 0
+	}
+
+	if len(m.IncludeMethods) > 0 && !containsFold(m.IncludeMethods, result.Request.Method) {
+		return true
+	}
+
+	return containsFold(m.ExcludeMethods, result.Request.Method)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestParseStatusRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []StatusRange
+		wantErr bool
+	}{
+		{"code", []string{"304"}, []StatusRange{{304, 304}}, false},
+		{"range", []string{"300-399"}, []StatusRange{{300, 399}}, false},
+		{"class", []string{"3xx", "4XX"}, []StatusRange{{300, 399}, {400, 499}}, false},
+		{"mixed with spaces", []string{"304", " 500-504"}, []StatusRange{{304, 304}, {500, 504}}, false},
+		{"invalid code", []string{"abc"}, nil, true},
+		{"invalid class", []string{"zxx"}, nil, true},
+		{"reversed range", []string{"399-300"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatusRanges(tt.specs)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error for %v", tt.specs)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeStatus(t *testing.T) {
+	statuses := []int64{200, 304, 304, 404, 304, 502}
+
+	tests := []struct {
+		name      string
+		exclude   []string
+		wantCodes []int64
+		wantTotal int
+	}{
+		{"none", nil, []int64{200, 304, 404, 502}, 6},
+		{"304", []string{"304"}, []int64{200, 404, 502}, 3},
+		{"3xx and 5xx", []string{"3xx", "500-599"}, []int64{200, 404}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := ParseStatusRanges(tt.exclude)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.ExcludeStatus = ranges
+
+			for _, status := range statuses {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    0.1,
+					UpstreamStatus: status,
+				}, "")
+			}
+
+			report := m.Analyze()
+
+			if report.TotalRequests != tt.wantTotal {
+				t.Errorf("TotalRequests = %d, want %d", report.TotalRequests, tt.wantTotal)
+			}
+
+			codes := make([]int64, 0)
+
+			for _, code := range report.Groups[0].ResponseCodes {
+				codes = append(codes, code.Code)
+			}
+
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("response codes = %v, want %v", codes, tt.wantCodes)
+			}
+
+			if report.Groups[0].TimedOut.Total != tt.wantTotal {
+				t.Errorf("timed out total = %d, want %d", report.Groups[0].TimedOut.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMethodFilters(t *testing.T) {
+	methods := []string{"GET", "OPTIONS", "options", "HEAD", "POST", "GET"}
+
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		wantTotal int
+	}{
+		{"none", nil, nil, 6},
+		{"exclude options", nil, []string{"OPTIONS"}, 4},
+		{"exclude options and head", nil, []string{"options", "Head"}, 3},
+		{"include get", []string{"get"}, nil, 2},
+		{"include and exclude", []string{"GET", "HEAD"}, []string{"HEAD"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.IncludeMethods = tt.include
+			m.ExcludeMethods = tt.exclude
+
+			for _, method := range methods {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: method, Path: "/"},
+					RequestTime:    0.1,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			if total := m.Analyze().TotalRequests; total != tt.wantTotal {
+				t.Errorf("TotalRequests = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package metric
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// GroupTemplate builds group keys from a template like "{host} {method} {path-depth-2}"
+type GroupTemplate struct {
+	parts []templatePart
+}
+
+// templatePart is either a literal, or a token rendering a value of the result
+type templatePart struct {
+	literal string
+	render  func(result *parser.NginxResult, fields map[string]interface{}) string
+}
+
+var groupTemplateToken = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var groupTemplateValues = map[string]func(result *parser.NginxResult, fields map[string]interface{}) string{
+	"host": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return result.Host
+	},
+	"method": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		if result.Request == nil {
+			return ""
+		}
+
+		return result.Request.Method
+	},
+	"path": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		if result.Request == nil {
+			return ""
+		}
+
+		return result.Request.Path
+	},
+	"status": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return strconv.FormatInt(result.UpstreamStatus, 10)
+	},
+	"upstream": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return result.UpstreamAddr
+	},
+	"upstream_name": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return result.ProxyUpstreamName
+	},
+	"client_ip": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return result.ClientIP
+	},
+	"referer_host": func(result *parser.NginxResult, _ map[string]interface{}) string {
+		return refererHost(result.Referer)
+	},
+}
+
+// ParseGroupTemplate compiles a group template, returning an error for unknown tokens
+func ParseGroupTemplate(spec string) (*GroupTemplate, error) {
+	tmpl := &GroupTemplate{}
+	last := 0
+
+	for _, match := range groupTemplateToken.FindAllStringSubmatchIndex(spec, -1) {
+		if match[0] > last {
+			tmpl.parts = append(tmpl.parts, templatePart{literal: spec[last:match[0]]})
+		}
+
+		render, err := groupTemplateRender(spec[match[2]:match[3]])
+
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl.parts = append(tmpl.parts, templatePart{render: render})
+		last = match[1]
+	}
+
+	if last < len(spec) {
+		tmpl.parts = append(tmpl.parts, templatePart{literal: spec[last:]})
+	}
+
+	if len(tmpl.parts) == 0 {
+		return nil, fmt.Errorf("invalid empty group template")
+	}
+
+	// braces left in literals are tokens that weren't closed
+	for _, part := range tmpl.parts {
+		if strings.ContainsAny(part.literal, "{}") {
+			return nil, fmt.Errorf("invalid group template %s, unbalanced braces", spec)
+		}
+	}
+
+	return tmpl, nil
+}
+
+func groupTemplateRender(token string) (func(result *parser.NginxResult, fields map[string]interface{}) string, error) {
+	if render, exists := groupTemplateValues[token]; exists {
+		return render, nil
+	}
+
+	if strings.HasPrefix(token, "path-depth-") {
+		depth, err := strconv.Atoi(strings.TrimPrefix(token, "path-depth-"))
+
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid group template token {%s}, the depth must be a positive number", token)
+		}
+
+		return func(result *parser.NginxResult, _ map[string]interface{}) string {
+			if result.Request == nil {
+				return ""
+			}
+
+			return truncatePath(result.Request.Path, depth)
+		}, nil
+	}
+
+	if strings.HasPrefix(token, "field:") && token != "field:" {
+		field := strings.TrimPrefix(token, "field:")
+
+		return func(_ *parser.NginxResult, fields map[string]interface{}) string {
+			value, exists := fields[field]
+
+			if !exists {
+				return ""
+			}
+
+			return fmt.Sprint(value)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown group template token {%s}", token)
+}
+
+// key renders the group key of the result
+func (t *GroupTemplate) key(result *parser.NginxResult, fields map[string]interface{}) string {
+	var b strings.Builder
+
+	for _, part := range t.parts {
+		if part.render == nil {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		value := part.render(result, fields)
+
+		if value == "" {
+			value = "-"
+		}
+
+		b.WriteString(value)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestGroupTemplateKeys(t *testing.T) {
+	result := &parser.NginxResult{
+		Host:              "api.example.com",
+		Request:           &parser.Request{Method: "GET", Path: "/v1/orders/42/items"},
+		UpstreamStatus:    502,
+		UpstreamAddr:      "10.1.0.5:8080",
+		ProxyUpstreamName: "default-api-80",
+		ClientIP:          "10.0.0.1",
+		Referer:           "https://shop.example.com/cart",
+	}
+
+	fields := map[string]interface{}{"req_id": "req1", "request_length": int64(120)}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{host}{method}{path-depth-2}", "api.example.comGET/v1/orders"},
+		{"{host} {method} {path}", "api.example.com GET /v1/orders/42/items"},
+		{"{status}@{upstream} ({upstream_name})", "502@10.1.0.5:8080 (default-api-80)"},
+		{"{client_ip} from {referer_host}", "10.0.0.1 from shop.example.com"},
+		{"{field:request_length}/{field:missing}", "120/-"},
+		{"static", "static"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := ParseGroupTemplate(tt.template)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tmpl.key(result, fields); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupTemplateGroups(t *testing.T) {
+	tmpl, err := ParseGroupTemplate("{method} {path-depth-1}")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMetricCollector(GroupKindTemplate, MetricKindLatency)
+	m.GroupTemplate = tmpl
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/orders"},
+		{"GET", "/api/users"},
+		{"POST", "/api/orders"},
+		{"GET", "/health"},
+	}
+
+	for _, req := range requests {
+		m.AddLineWithFields(&parser.NginxResult{
+			Request:        &parser.Request{Method: req.method, Path: req.path},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		}, nil, "")
+	}
+
+	want := []string{"GET /api", "GET /health", "POST /api"}
+
+	if got := groupKeys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestParseGroupTemplateInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"{unknown}",
+		"{path-depth-0}",
+		"{path-depth-x}",
+		"{field:}",
+		"{host",
+		"host}",
+	}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseGroupTemplate(spec); err == nil {
+				t.Errorf("expected an error for %q", spec)
+			}
+		})
+	}
+}
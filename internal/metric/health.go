@@ -0,0 +1,117 @@
+package metric
+
+import "github.com/abelanger5/nginx-ingress-parser/internal/parser"
+
+// HealthConfig configures the non-negative weights of HealthScore
+type HealthConfig struct {
+	ErrorWeight   float64
+	TimeoutWeight float64
+	LatencyWeight float64
+
+	// LatencyThreshold is the p95 latency in seconds above which the score drops
+	LatencyThreshold float64
+
+	// Ascending sorts the health scores in the report worst-first
+	Ascending bool
+}
+
+var DefaultHealthConfig = HealthConfig{
+	ErrorWeight:      1,
+	TimeoutWeight:    1,
+	LatencyWeight:    1,
+	LatencyThreshold: 1,
+	Ascending:        true,
+}
+
+// HealthScore returns the weighted score of the group, from 0 (unhealthy) to 100 (healthy)
+func (m *MetricCollector) HealthScore(group string) float64 {
+	cfg := m.Health
+	totalWeight := cfg.ErrorWeight + cfg.TimeoutWeight + cfg.LatencyWeight
+
+	if totalWeight <= 0 {
+		return 100
+	}
+
+	errorRate, timeoutRate := m.errorRate(group), m.timeoutRate(group)
+
+	var latencyPenalty float64
+
+	if bucket, exists := m.latencyData[group]; exists && cfg.LatencyThreshold > 0 {
+		p95 := m.listPercentiles(bucket, 95)[0]
+		latencyPenalty = (p95 - cfg.LatencyThreshold) / cfg.LatencyThreshold
+
+		if latencyPenalty < 0 {
+			latencyPenalty = 0
+		} else if latencyPenalty > 1 {
+			latencyPenalty = 1
+		}
+	}
+
+	penalty := (cfg.ErrorWeight*errorRate + cfg.TimeoutWeight*timeoutRate + cfg.LatencyWeight*latencyPenalty) / totalWeight
+
+	return 100 * (1 - penalty)
+}
+
+// errorRate returns the fraction of 5XX or timed out responses of the group
+func (m *MetricCollector) errorRate(group string) float64 {
+	var numErrors, numResponses uint
+
+	for code, num := range m.responseData[group] {
+		if statusInRanges(code, m.ErrorRateExcludeStatus) {
+			continue
+		}
+
+		if code >= 500 {
+			numErrors += num
+		} else {
+			// time outs can be logged with a status below 500
+			numErrors += m.timedOutStatusData[group][code]
+		}
+
+		numResponses += num
+	}
+
+	if numResponses == 0 {
+		return 0
+	}
+
+	return float64(numErrors) / float64(numResponses)
+}
+
+// addTimedOutStatus counts the timed out result by upstream status
+func (m *MetricCollector) addTimedOutStatus(group string, result *parser.NginxResult) {
+	if m.timedOutStatusData == nil {
+		m.timedOutStatusData = make(map[string]ResponseMetric)
+	}
+
+	if m.timedOutStatusData[group] == nil {
+		m.timedOutStatusData[group] = make(ResponseMetric)
+	}
+
+	m.timedOutStatusData[group][result.UpstreamStatus]++
+}
+
+func (m *MetricCollector) mergeTimedOutStatus(group string, respBucket ResponseMetric) {
+	if m.timedOutStatusData == nil {
+		m.timedOutStatusData = make(map[string]ResponseMetric)
+	}
+
+	if m.timedOutStatusData[group] == nil {
+		m.timedOutStatusData[group] = make(ResponseMetric)
+	}
+
+	for code, num := range respBucket {
+		m.timedOutStatusData[group][code] += num
+	}
+}
+
+// timeoutRate returns the fraction of the group's requests that timed out
+func (m *MetricCollector) timeoutRate(group string) float64 {
+	timedOutMetric, exists := m.timedOutData[group]
+
+	if !exists || timedOutMetric.Total == 0 {
+		return 0
+	}
+
+	return float64(timedOutMetric.Count) / float64(timedOutMetric.Total)
+}
@@ -0,0 +1,245 @@
+package metric
+
+import (
+	"math"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestHealthScore(t *testing.T) {
+	type request struct {
+		status   int64
+		latency  float64
+		timedOut bool
+	}
+
+	healthy := []request{{200, 0.1, false}, {201, 0.2, false}, {204, 0.5, false}, {200, 0.9, false}}
+	timedOut := []request{{504, 0, true}, {504, 0, true}}
+	slowErrors := []request{{500, 5, false}, {502, 3, false}, {503, 2.5, false}}
+
+	tests := []struct {
+		name     string
+		requests []request
+		config   HealthConfig
+		want     float64
+	}{
+		{
+			name:     "all healthy",
+			requests: healthy,
+			config:   DefaultHealthConfig,
+			want:     100,
+		},
+		{
+			// timed out requests have no latency, so only the error and timeout rates weigh
+			name:     "all timed out",
+			requests: timedOut,
+			config:   DefaultHealthConfig,
+			want:     100.0 / 3,
+		},
+		{
+			name:     "all timed out without the latency weight",
+			requests: timedOut,
+			config:   HealthConfig{ErrorWeight: 1, TimeoutWeight: 1, LatencyThreshold: 1},
+			want:     0,
+		},
+		{
+			name:     "all slow 5XX",
+			requests: slowErrors,
+			config:   DefaultHealthConfig,
+			want:     100.0 / 3,
+		},
+		{
+			name:     "all slow 5XX without the timeout weight",
+			requests: slowErrors,
+			config:   HealthConfig{ErrorWeight: 1, LatencyWeight: 1, LatencyThreshold: 1},
+			want:     0,
+		},
+		{
+			name:     "only the latency weight",
+			requests: []request{{200, 1.5, false}, {500, 0.1, false}},
+			config:   HealthConfig{LatencyWeight: 2, LatencyThreshold: 1},
+			want:     50,
+		},
+		{
+			name:     "only the error weight",
+			requests: []request{{200, 10, false}, {500, 10, false}, {504, 0, true}, {200, 10, false}},
+			config:   HealthConfig{ErrorWeight: 1, LatencyThreshold: 1},
+			want:     50,
+		},
+		{
+			name:     "all weights 0",
+			requests: append(timedOut, slowErrors...),
+			config:   HealthConfig{LatencyThreshold: 1},
+			want:     100,
+		},
+		{
+			name:     "no latency threshold",
+			requests: slowErrors,
+			config:   HealthConfig{LatencyWeight: 1},
+			want:     100,
+		},
+		{
+			name:   "no requests",
+			config: DefaultHealthConfig,
+			want:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Health = tt.config
+
+			for _, req := range tt.requests {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: "/a"},
+					RequestTime:    req.latency,
+					UpstreamStatus: req.status,
+					TimedOut:       req.timedOut,
+				}, "")
+			}
+
+			if got := m.HealthScore("/a"); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("health score = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	type request struct {
+		status   int64
+		timedOut bool
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+		cluster  bool
+		exclude  []string
+		want     float64
+	}{
+		{
+			name:     "2XX",
+			requests: []request{{200, false}, {201, false}},
+			want:     0,
+		},
+		{
+			name:     "3XX",
+			requests: []request{{301, false}, {304, false}},
+			want:     0,
+		},
+		{
+			name:     "4XX",
+			requests: []request{{404, false}, {200, false}},
+			want:     0,
+		},
+		{
+			name:     "5XX",
+			requests: []request{{200, false}, {502, false}, {200, false}, {404, false}},
+			want:     0.25,
+		},
+		{
+			name:     "timed out without a response",
+			requests: []request{{200, false}, {parser.StatusNoResponse, true}},
+			want:     0.5,
+		},
+		{
+			name:     "timed out with a 2XX status",
+			requests: []request{{200, true}, {200, false}, {200, false}, {200, false}},
+			want:     0.25,
+		},
+		{
+			name:     "timed out with a 5XX status counted once",
+			requests: []request{{200, false}, {504, true}, {504, false}, {200, false}},
+			want:     0.5,
+		},
+		{
+			name:     "timed out with a 4XX status",
+			requests: []request{{499, true}, {499, false}, {200, false}, {200, false}},
+			want:     0.25,
+		},
+		{
+			name:     "clustered",
+			requests: []request{{499, true}, {200, false}, {200, false}, {200, false}},
+			cluster:  true,
+			want:     0.25,
+		},
+		{
+			name:     "429 counted",
+			requests: []request{{200, false}, {429, false}, {503, false}, {200, false}},
+			want:     0.25,
+		},
+		{
+			name:     "429 excluded",
+			requests: []request{{200, false}, {429, false}, {503, false}, {200, false}},
+			exclude:  []string{"429"},
+			want:     1.0 / 3,
+		},
+		{
+			name:     "rate limiting 503 excluded",
+			requests: []request{{200, false}, {429, false}, {503, false}, {200, false}},
+			exclude:  []string{"429", "503"},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excluded, err := ParseStatusRanges(tt.exclude)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.ClusterPaths = tt.cluster
+			m.ErrorRateExcludeStatus = excluded
+
+			// apart from the excluded statuses, the error rate counts the same requests as
+			// failed as NginxResult.IsError
+			numErrors, numCounted := 0, 0
+
+			for i, req := range tt.requests {
+				path := "/a"
+
+				if tt.cluster {
+					path += "/" + string(rune('1'+i))
+				}
+
+				result := &parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: path},
+					RequestTime:    0.1,
+					Status:         req.status,
+					UpstreamStatus: req.status,
+					TimedOut:       req.timedOut,
+				}
+
+				if !statusInRanges(req.status, excluded) {
+					numCounted++
+
+					if result.IsError() {
+						numErrors++
+					}
+				}
+
+				m.AddLine(result, "")
+			}
+
+			groups := m.Analyze().Groups
+
+			if len(groups) != 1 {
+				t.Fatalf("got %d groups, want 1", len(groups))
+			}
+
+			if groups[0].ErrorRate != tt.want {
+				t.Errorf("error rate = %g, want %g", groups[0].ErrorRate, tt.want)
+			}
+
+			if isError := float64(numErrors) / float64(numCounted); groups[0].ErrorRate != isError {
+				t.Errorf("error rate = %g, but IsError is true for %g of the requests", groups[0].ErrorRate, isError)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// MaxHeatmapTimeBuckets and MaxHeatmapLatencyBuckets bound the size of the heatmap matrix
+const (
+	MaxHeatmapTimeBuckets    = 10000
+	MaxHeatmapLatencyBuckets = 1000
+)
+
+// Heatmap counts requests by time bucket (rows) and latency bucket (columns), the last
+// row or column overflowing past the Max bounds
+type Heatmap struct {
+	TimeBuckets     []time.Time `json:"time_buckets"`
+	LatencyBuckets  []float64   `json:"latency_buckets"`
+	Counts          [][]uint    `json:"counts"`
+	TimeOverflow    bool        `json:"time_overflow,omitempty"`
+	LatencyOverflow bool        `json:"latency_overflow,omitempty"`
+}
+
+// heatmapCell is a time bucket start and latency bucket index
+type heatmapCell struct {
+	Start   time.Time
+	Latency int
+}
+
+// addHeatmap counts the latency into its heatmap bucket
+func (m *MetricCollector) addHeatmap(latency *LatencyMetric) {
+	if m.HeatmapTimeBucket <= 0 || m.HeatmapLatencyBucket <= 0 || latency.time.IsZero() {
+		return
+	}
+
+	if m.heatmapData == nil {
+		m.heatmapData = make(map[heatmapCell]uint)
+	}
+
+	start := latency.time.Truncate(m.HeatmapTimeBucket)
+
+	// the earliest bucket keeps the offset it was logged with, for display
+	if m.heatmapStart.IsZero() || start.Before(m.heatmapStart) {
+		m.heatmapStart = start
+	}
+
+	index := MaxHeatmapLatencyBuckets
+
+	if latency.latency/m.HeatmapLatencyBucket < MaxHeatmapLatencyBuckets {
+		index = latencyBucketIndex(latency.latency, m.HeatmapLatencyBucket)
+	}
+
+	m.heatmapData[heatmapCell{start.UTC(), index}]++
+}
+
+// mergeHeatmap adds the heatmap counts of other to m
+func (m *MetricCollector) mergeHeatmap(other *MetricCollector) {
+	for cell, count := range other.heatmapData {
+		if m.heatmapData == nil {
+			m.heatmapData = make(map[heatmapCell]uint)
+		}
+
+		m.heatmapData[cell] += count
+	}
+
+	if m.heatmapStart.IsZero() || (!other.heatmapStart.IsZero() && other.heatmapStart.Before(m.heatmapStart)) {
+		m.heatmapStart = other.heatmapStart
+	}
+}
+
+// Heatmap returns the request counts in the collector's heatmap buckets
+func (m *MetricCollector) Heatmap() (*Heatmap, error) {
+	timeBucket, latencyBucket := m.HeatmapTimeBucket, m.HeatmapLatencyBucket
+
+	if timeBucket <= 0 || latencyBucket <= 0 {
+		return nil, fmt.Errorf("heatmap bucket sizes must be positive")
+	}
+
+	heatmap := &Heatmap{
+		TimeBuckets:    make([]time.Time, 0),
+		LatencyBuckets: make([]float64, 0),
+		Counts:         make([][]uint, 0),
+	}
+
+	if len(m.heatmapData) == 0 {
+		return heatmap, nil
+	}
+
+	minTime := m.heatmapStart
+	maxTime := minTime
+	maxIndex := 0
+
+	for cell := range m.heatmapData {
+		if cell.Start.After(maxTime) {
+			maxTime = cell.Start
+		}
+
+		if cell.Latency > maxIndex {
+			maxIndex = cell.Latency
+		}
+	}
+
+	// compare durations first, the bucket count can overflow an int
+	numTimeBuckets := MaxHeatmapTimeBuckets
+
+	if span := maxTime.Sub(minTime) / timeBucket; span < MaxHeatmapTimeBuckets {
+		numTimeBuckets = int(span) + 1
+	} else {
+		heatmap.TimeOverflow = true
+	}
+
+	numLatencyBuckets := MaxHeatmapLatencyBuckets
+
+	if maxIndex < MaxHeatmapLatencyBuckets {
+		numLatencyBuckets = maxIndex + 1
+	} else {
+		heatmap.LatencyOverflow = true
+	}
+
+	for i := 0; i < numTimeBuckets; i++ {
+		heatmap.TimeBuckets = append(heatmap.TimeBuckets, m.displayTime(minTime.Add(time.Duration(i)*timeBucket)))
+		heatmap.Counts = append(heatmap.Counts, make([]uint, numLatencyBuckets))
+	}
+
+	for i := 0; i < numLatencyBuckets; i++ {
+		heatmap.LatencyBuckets = append(heatmap.LatencyBuckets, float64(i)*latencyBucket)
+	}
+
+	for cell, count := range m.heatmapData {
+		timeIndex := numTimeBuckets - 1
+
+		if span := cell.Start.Sub(minTime) / timeBucket; span < time.Duration(timeIndex) {
+			timeIndex = int(span)
+		}
+
+		latencyIndex := numLatencyBuckets - 1
+
+		if cell.Latency < latencyIndex {
+			latencyIndex = cell.Latency
+		}
+
+		heatmap.Counts[timeIndex][latencyIndex] += count
+	}
+
+	return heatmap, nil
+}
+
+// WriteHeatmap writes the heatmap matrix to path as JSON
+func (m *MetricCollector) WriteHeatmap(path string) error {
+	heatmap, err := m.Heatmap()
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(heatmap)
+}
+
+func latencyBucketIndex(latency, latencyBucket float64) int {
+	if latency < 0 {
+		return 0
+	}
+
+	return int(latency / latencyBucket)
+}
@@ -0,0 +1,432 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestHeatmap(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 30, 0, time.UTC)
+
+	requests := []struct {
+		path    string
+		offset  time.Duration
+		latency float64
+	}{
+		{"/a", 0, 0.05},
+		{"/a", 10 * time.Second, 0.15},
+		{"/b", 20 * time.Second, 0.05},
+		{"/a", 40 * time.Second, 0.25},
+		{"/b", 2 * time.Minute, 0.1},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.HeatmapTimeBucket = time.Minute
+	m.HeatmapLatencyBucket = 0.1
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			TimeLocal:      start.Add(req.offset),
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    req.latency,
+			UpstreamStatus: 200,
+			UpstreamAddr:   "10.0.0.1:80",
+		}, "")
+	}
+
+	heatmap, err := m.Heatmap()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first time bucket starts at the truncated earliest timestamp
+	wantTimes := []time.Time{
+		start.Truncate(time.Minute),
+		start.Truncate(time.Minute).Add(time.Minute),
+		start.Truncate(time.Minute).Add(2 * time.Minute),
+	}
+
+	if !reflect.DeepEqual(heatmap.TimeBuckets, wantTimes) {
+		t.Errorf("time buckets = %v, want %v", heatmap.TimeBuckets, wantTimes)
+	}
+
+	if !reflect.DeepEqual(heatmap.LatencyBuckets, []float64{0, 0.1, 0.2}) {
+		t.Errorf("latency buckets = %v, want [0 0.1 0.2]", heatmap.LatencyBuckets)
+	}
+
+	wantCounts := [][]uint{
+		{2, 1, 0},
+		{0, 0, 1},
+		{0, 1, 0},
+	}
+
+	if !reflect.DeepEqual(heatmap.Counts, wantCounts) {
+		t.Errorf("counts = %v, want %v", heatmap.Counts, wantCounts)
+	}
+}
+
+func TestHeatmapEmpty(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.HeatmapTimeBucket = time.Minute
+	m.HeatmapLatencyBucket = 0.1
+
+	heatmap, err := m.Heatmap()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(heatmap.TimeBuckets) != 0 || len(heatmap.Counts) != 0 {
+		t.Errorf("got %d time buckets for no requests", len(heatmap.TimeBuckets))
+	}
+}
+
+func TestHeatmapInvalidBuckets(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	if _, err := m.Heatmap(); err == nil {
+		t.Error("expected an error for unset bucket sizes")
+	}
+
+	m.HeatmapTimeBucket = time.Minute
+	m.HeatmapLatencyBucket = -1
+
+	if _, err := m.Heatmap(); err == nil {
+		t.Error("expected an error for a negative latency bucket")
+	}
+}
+
+func TestHeatmapLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// logged with a fixed +0000 offset, like the ingress controller does
+	logged := time.FixedZone("", 0)
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		// start is the UTC time of the first request, the others follow an hour apart
+		start     time.Time
+		wantTimes []string
+	}{
+		{
+			name:      "log offset",
+			start:     time.Date(2026, 3, 8, 6, 0, 0, 0, logged),
+			wantTimes: []string{"2026-03-08T06:00:00Z", "2026-03-08T07:00:00Z", "2026-03-08T08:00:00Z"},
+		},
+		{
+			name:      "spring forward",
+			location:  newYork,
+			start:     time.Date(2026, 3, 8, 6, 0, 0, 0, logged),
+			wantTimes: []string{"2026-03-08T01:00:00-05:00", "2026-03-08T03:00:00-04:00", "2026-03-08T04:00:00-04:00"},
+		},
+		{
+			name:      "fall back",
+			location:  newYork,
+			start:     time.Date(2026, 11, 1, 5, 0, 0, 0, logged),
+			wantTimes: []string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:00:00-05:00", "2026-11-01T02:00:00-05:00"},
+		},
+		{
+			name:      "half hour offset",
+			location:  kolkata,
+			start:     time.Date(2026, 3, 8, 6, 0, 0, 0, logged),
+			wantTimes: []string{"2026-03-08T11:30:00+05:30", "2026-03-08T12:30:00+05:30", "2026-03-08T13:30:00+05:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.HeatmapTimeBucket = time.Hour
+			m.HeatmapLatencyBucket = 0.1
+			m.Location = tt.location
+
+			for i := 0; i < 3; i++ {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      tt.start.Add(time.Duration(i) * time.Hour),
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    0.05,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			heatmap, err := m.Heatmap()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(heatmap.TimeBuckets))
+
+			for _, ts := range heatmap.TimeBuckets {
+				got = append(got, ts.Format(time.RFC3339))
+			}
+
+			if !reflect.DeepEqual(got, tt.wantTimes) {
+				t.Errorf("time buckets = %v, want %v", got, tt.wantTimes)
+			}
+
+			// the display zone doesn't move requests between buckets
+			for i, row := range heatmap.Counts {
+				if row[0] != 1 {
+					t.Errorf("time bucket %d has %d requests, want 1", i, row[0])
+				}
+			}
+		})
+	}
+}
+
+func TestHeatmapSkipsUntimedLatencies(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		times      []time.Time
+		wantCounts [][]uint
+	}{
+		{
+			name:  "only untimed",
+			times: []time.Time{{}, {}},
+		},
+		{
+			name:       "timed and untimed",
+			times:      []time.Time{start, {}, start.Add(time.Minute)},
+			wantCounts: [][]uint{{1}, {1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.HeatmapTimeBucket = time.Minute
+			m.HeatmapLatencyBucket = 0.1
+
+			for _, ts := range tt.times {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      ts,
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    0.05,
+					Status:         200,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			heatmap, err := m.Heatmap()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(heatmap.Counts) != len(tt.wantCounts) {
+				t.Fatalf("got %d time buckets, want %d", len(heatmap.Counts), len(tt.wantCounts))
+			}
+
+			for i, row := range tt.wantCounts {
+				for j, count := range row {
+					if heatmap.Counts[i][j] != count {
+						t.Errorf("count [%d][%d] = %d, want %d", i, j, heatmap.Counts[i][j], count)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHeatmapOverflow(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	type request struct {
+		time    time.Time
+		latency float64
+	}
+
+	tests := []struct {
+		name                string
+		requests            []request
+		wantTimeBuckets     int
+		wantLatencyBuckets  int
+		wantTimeOverflow    bool
+		wantLatencyOverflow bool
+		// wantLast is the count of the last time and latency bucket
+		wantLast uint
+	}{
+		{
+			name:               "within the caps",
+			requests:           []request{{start, 0.05}, {start.Add(time.Hour), 0.25}},
+			wantTimeBuckets:    61,
+			wantLatencyBuckets: 3,
+			wantLast:           1,
+		},
+		{
+			name:               "distant timestamp",
+			requests:           []request{{start, 0.05}, {start.AddDate(300, 0, 0), 0.05}, {start.AddDate(1, 0, 0), 0.05}},
+			wantTimeBuckets:    MaxHeatmapTimeBuckets,
+			wantLatencyBuckets: 1,
+			wantTimeOverflow:   true,
+			wantLast:           2,
+		},
+		{
+			name:                "huge latency",
+			requests:            []request{{start, 0.05}, {start, 1e9}, {start, 500}},
+			wantTimeBuckets:     1,
+			wantLatencyBuckets:  MaxHeatmapLatencyBuckets,
+			wantLatencyOverflow: true,
+			wantLast:            2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.HeatmapTimeBucket = time.Minute
+			m.HeatmapLatencyBucket = 0.1
+
+			for _, req := range tt.requests {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      req.time,
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    req.latency,
+					Status:         200,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			heatmap, err := m.Heatmap()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(heatmap.TimeBuckets) != tt.wantTimeBuckets || len(heatmap.LatencyBuckets) != tt.wantLatencyBuckets {
+				t.Fatalf("got %dx%d buckets, want %dx%d", len(heatmap.TimeBuckets), len(heatmap.LatencyBuckets), tt.wantTimeBuckets, tt.wantLatencyBuckets)
+			}
+
+			if heatmap.TimeOverflow != tt.wantTimeOverflow || heatmap.LatencyOverflow != tt.wantLatencyOverflow {
+				t.Errorf("overflow = %t/%t, want %t/%t", heatmap.TimeOverflow, heatmap.LatencyOverflow, tt.wantTimeOverflow, tt.wantLatencyOverflow)
+			}
+
+			if last := heatmap.Counts[tt.wantTimeBuckets-1][tt.wantLatencyBuckets-1]; last != tt.wantLast {
+				t.Errorf("last bucket count = %d, want %d", last, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestHeatmapCountsSampledRequests(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		reservoir int
+		shards    int
+	}{
+		{name: "reservoir", reservoir: 10, shards: 1},
+		{name: "merged shards", reservoir: 10, shards: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.HeatmapTimeBucket = time.Minute
+			m.HeatmapLatencyBucket = 0.1
+			m.Reservoir = tt.reservoir
+
+			for shard := 0; shard < tt.shards; shard++ {
+				s := m.Shard()
+
+				for i := 0; i < 1000/tt.shards; i++ {
+					s.AddLine(&parser.NginxResult{
+						TimeLocal:      start.Add(time.Duration(shard*1000/tt.shards+i) * time.Second),
+						Request:        &parser.Request{Method: "GET", Path: "/"},
+						RequestTime:    0.05,
+						Status:         200,
+						UpstreamStatus: 200,
+						UpstreamAddr:   "10.0.0.1:80",
+					}, "")
+				}
+
+				m.Merge(s)
+			}
+
+			heatmap, err := m.Heatmap()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var total uint
+
+			for _, row := range heatmap.Counts {
+				for _, count := range row {
+					total += count
+				}
+			}
+
+			if total != 1000 {
+				t.Errorf("heatmap counts %d requests, want 1000", total)
+			}
+		})
+	}
+}
+
+func TestHeatmapGobRoundTrip(t *testing.T) {
+	newCollector := func() *MetricCollector {
+		m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+		m.HeatmapTimeBucket = time.Minute
+		m.HeatmapLatencyBucket = 0.1
+
+		return m
+	}
+
+	m := newCollector()
+	collectFixture(t, m)
+
+	data, err := m.GobEncode()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := newCollector()
+
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := m.Heatmap()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decoded.Heatmap()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Counts, want.Counts) || !reflect.DeepEqual(got.LatencyBuckets, want.LatencyBuckets) {
+		t.Errorf("decoded heatmap = %+v, want %+v", got, want)
+	}
+
+	if len(got.TimeBuckets) != len(want.TimeBuckets) || (len(want.TimeBuckets) > 0 && !got.TimeBuckets[0].Equal(want.TimeBuckets[0])) {
+		t.Errorf("decoded time buckets = %v, want %v", got.TimeBuckets, want.TimeBuckets)
+	}
+}
@@ -0,0 +1,88 @@
+package metric
+
+import (
+	_ "embed"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// ReportSchemaVersion is the version of the JSON report schema
+const ReportSchemaVersion = 2
+
+// ReportSchema is the JSON schema the JSON report validates against
+//
+//go:embed report.schema.json
+var ReportSchema []byte
+
+// JSONReport is the report written by WriteJSON
+type JSONReport struct {
+	SchemaVersion int          `json:"schema_version"`
+	TotalRequests int          `json:"total_requests"`
+	Groups        []*JSONGroup `json:"groups"`
+
+	// Options holds the options of the run, and is left out if they aren't recorded
+	Options []*ManifestOption `json:"options,omitempty"`
+}
+
+// JSONGroup holds the metrics of a group in the JSON report, latencies in seconds
+type JSONGroup struct {
+	Key           string             `json:"key"`
+	Requests      int                `json:"requests"`
+	ResponseCodes map[string]uint    `json:"response_codes"`
+	TimedOut      int                `json:"timed_out"`
+	LatencyCount  int                `json:"latency_count"`
+	MeanLatency   *float64           `json:"mean_latency"`
+	P95Latency    *float64           `json:"p95_latency"`
+	Percentiles   map[string]float64 `json:"percentiles"`
+	ErrorRate     float64            `json:"error_rate"`
+	TimeoutRate   float64            `json:"timeout_rate"`
+}
+
+func newJSONReport(report *Report) *JSONReport {
+	res := &JSONReport{
+		SchemaVersion: ReportSchemaVersion,
+		TotalRequests: report.TotalRequests,
+		Groups:        make([]*JSONGroup, 0, len(report.Groups)),
+		Options:       report.Manifest,
+	}
+
+	for _, group := range report.Groups {
+		jsonGroup := &JSONGroup{
+			Key:           group.Key,
+			Requests:      group.TimedOut.Total,
+			ResponseCodes: make(map[string]uint, len(group.ResponseCodes)),
+			Percentiles:   make(map[string]float64, len(group.Percentiles)),
+			TimedOut:      group.TimedOut.Count,
+			LatencyCount:  group.LatencyCount,
+			ErrorRate:     group.ErrorRate,
+			TimeoutRate:   group.TimeoutRate,
+		}
+
+		for _, code := range group.ResponseCodes {
+			jsonGroup.ResponseCodes[strconv.FormatInt(code.Code, 10)] = code.Count
+		}
+
+		for _, p := range group.Percentiles {
+			jsonGroup.Percentiles["p"+strconv.FormatFloat(p.Percentile, 'f', -1, 64)] = p.Latency
+		}
+
+		if group.LatencyCount > 0 {
+			mean, p95 := group.MeanLatency, group.P95Latency
+			jsonGroup.MeanLatency = &mean
+			jsonGroup.P95Latency = &p95
+		}
+
+		res.Groups = append(res.Groups, jsonGroup)
+	}
+
+	return res
+}
+
+// WriteJSON writes the groups of the report as a JSON document following ReportSchema
+func (m *MetricCollector) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(newJSONReport(m.Analyze()))
+}
@@ -0,0 +1,262 @@
+package metric
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// schemaValidator validates JSON documents against the subset of JSON schema keywords
+// report.schema.json uses, and fails on any other keyword so the subset can't silently
+// fall behind the schema
+type schemaValidator struct {
+	root map[string]interface{}
+}
+
+var schemaAnnotations = map[string]bool{"$schema": true, "$id": true, "$defs": true, "title": true, "description": true}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) error {
+	keywords := make([]string, 0, len(schema))
+
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+
+	// required and properties are checked before additionalProperties, for clearer errors
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		if err := v.validateKeyword(schema, keyword, value, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *schemaValidator) validateKeyword(schema map[string]interface{}, keyword string, value interface{}, path string) error {
+	arg := schema[keyword]
+	object, isObject := value.(map[string]interface{})
+	number, isNumber := value.(json.Number)
+
+	switch {
+	case schemaAnnotations[keyword]:
+		return nil
+	case keyword == "$ref":
+		ref := arg.(string)
+		name, ok := strings.CutPrefix(ref, "#/$defs/")
+
+		if !ok {
+			return fmt.Errorf("unsupported $ref %s", ref)
+		}
+
+		return v.validate(v.root["$defs"].(map[string]interface{})[name].(map[string]interface{}), value, path)
+	case keyword == "type":
+		types, ok := arg.([]interface{})
+
+		if !ok {
+			types = []interface{}{arg}
+		}
+
+		for _, t := range types {
+			if schemaType(value) == t || (t == "number" && schemaType(value) == "integer") {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%s: %s isn't of type %v", path, schemaType(value), arg)
+	case keyword == "const":
+		if fmt.Sprint(value) != fmt.Sprint(arg) {
+			return fmt.Errorf("%s: %v isn't %v", path, value, arg)
+		}
+	case keyword == "minimum" || keyword == "maximum":
+		if !isNumber {
+			return nil
+		}
+
+		got, _ := number.Float64()
+		bound, _ := arg.(json.Number).Float64()
+
+		if (keyword == "minimum" && got < bound) || (keyword == "maximum" && got > bound) {
+			return fmt.Errorf("%s: %g is out of the %s %g", path, got, keyword, bound)
+		}
+	case keyword == "required":
+		for _, name := range arg.([]interface{}) {
+			if _, exists := object[name.(string)]; isObject && !exists {
+				return fmt.Errorf("%s: missing %s", path, name)
+			}
+		}
+	case keyword == "properties":
+		for name, property := range object {
+			if propertySchema, exists := arg.(map[string]interface{})[name]; exists {
+				if err := v.validate(propertySchema.(map[string]interface{}), property, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case keyword == "additionalProperties":
+		properties, _ := schema["properties"].(map[string]interface{})
+
+		for name, property := range object {
+			if _, exists := properties[name]; exists {
+				continue
+			}
+
+			if arg == false {
+				return fmt.Errorf("%s: unexpected property %s", path, name)
+			}
+
+			if additional, ok := arg.(map[string]interface{}); ok {
+				if err := v.validate(additional, property, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case keyword == "propertyNames":
+		pattern := regexp.MustCompile(arg.(map[string]interface{})["pattern"].(string))
+
+		for name := range object {
+			if !pattern.MatchString(name) {
+				return fmt.Errorf("%s: property name %s doesn't match %s", path, name, pattern)
+			}
+		}
+	case keyword == "items":
+		items, _ := value.([]interface{})
+
+		for i, item := range items {
+			if err := v.validate(arg.(map[string]interface{}), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported schema keyword %s", keyword)
+	}
+
+	return nil
+}
+
+func schemaType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return "number"
+		}
+
+		return "integer"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func decodeJSONNumbers(t *testing.T, data []byte) interface{} {
+	t.Helper()
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func validateReportJSON(t *testing.T, data []byte) error {
+	t.Helper()
+
+	schema := decodeJSONNumbers(t, ReportSchema).(map[string]interface{})
+	v := &schemaValidator{root: schema}
+
+	return v.validate(schema, decodeJSONNumbers(t, data), "$")
+}
+
+func TestWriteJSONSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		collect func(t *testing.T, m *MetricCollector)
+	}{
+		{"fixture", collectFixture},
+		{"empty", func(t *testing.T, m *MetricCollector) {}},
+		{"custom percentiles", func(t *testing.T, m *MetricCollector) {
+			m.Percentiles = []float64{50, 99.9}
+			collectFixture(t, m)
+		}},
+		{"manifest", func(t *testing.T, m *MetricCollector) {
+			m.Manifest = []*ManifestOption{{Name: "group-by", Value: "path", Set: false}}
+			collectFixture(t, m)
+		}},
+		{"no tracked latencies", func(t *testing.T, m *MetricCollector) {
+			m.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: "/slow"},
+				RequestTime:    60,
+				Status:         504,
+				UpstreamStatus: 504,
+				TimedOut:       true,
+			}, "")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			tt.collect(t, m)
+
+			var buf bytes.Buffer
+
+			if err := m.WriteJSON(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := validateReportJSON(t, buf.Bytes()); err != nil {
+				t.Errorf("report doesn't validate: %v\n%s", err, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestReportSchemaRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+	}{
+		{"other schema version", `{"schema_version": 1, "total_requests": 0, "groups": []}`},
+		{"missing groups", `{"schema_version": 2, "total_requests": 0}`},
+		{"unknown field", `{"schema_version": 2, "total_requests": 0, "groups": [], "extra": 1}`},
+		{"fractional count", `{"schema_version": 2, "total_requests": 1.5, "groups": []}`},
+		{
+			"invalid group",
+			`{"schema_version": 2, "total_requests": 0, "groups": [{"key": "/a", "requests": 1, "response_codes": {"OK": 1},
+			"timed_out": 0, "latency_count": 0, "mean_latency": null, "p95_latency": null, "percentiles": {}, "error_rate": 0,
+			"timeout_rate": 0}]}`,
+		},
+		{
+			"rate above 1",
+			`{"schema_version": 2, "total_requests": 0, "groups": [{"key": "/a", "requests": 1, "response_codes": {},
+			"timed_out": 0, "latency_count": 0, "mean_latency": null, "p95_latency": null, "percentiles": {}, "error_rate": 2,
+			"timeout_rate": 0}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateReportJSON(t, []byte(tt.report)); err == nil {
+				t.Error("report validates, want an error")
+			}
+		})
+	}
+}
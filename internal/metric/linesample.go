@@ -0,0 +1,82 @@
+package metric
+
+import "container/list"
+
+// DefaultMaxLineSample is the default number of bytes of raw lines retained
+const DefaultMaxLineSample = 1 << 20
+
+// lineSampler retains raw log lines by key, evicting the least recently stored first
+type lineSampler struct {
+	maxBytes int
+	bytes    int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lineSample struct {
+	key  string
+	line string
+}
+
+func newLineSampler(maxBytes int) *lineSampler {
+	return &lineSampler{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// put stores the line under the key, evicting lines until the cap is respected
+func (s *lineSampler) put(key, line string) {
+	s.remove(key)
+
+	if len(line) > s.maxBytes {
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&lineSample{key, line})
+	s.bytes += len(line)
+
+	for s.bytes > s.maxBytes {
+		s.remove(s.order.Back().Value.(*lineSample).key)
+	}
+}
+
+// get returns the line stored under the key, or false if there's none or it was evicted
+func (s *lineSampler) get(key string) (string, bool) {
+	elem, exists := s.entries[key]
+
+	if !exists {
+		return "", false
+	}
+
+	return elem.Value.(*lineSample).line, true
+}
+
+func (s *lineSampler) remove(key string) {
+	elem, exists := s.entries[key]
+
+	if !exists {
+		return
+	}
+
+	s.bytes -= len(elem.Value.(*lineSample).line)
+	s.order.Remove(elem)
+	delete(s.entries, key)
+}
+
+func (m *MetricCollector) sampleLine(key, line string) {
+	if m.lineSamples == nil {
+		m.lineSamples = newLineSampler(m.MaxLineSample)
+	}
+
+	m.lineSamples.put(key, line)
+}
+
+func (m *MetricCollector) sampledLine(key string) (string, bool) {
+	if m.lineSamples == nil {
+		return "", false
+	}
+
+	return m.lineSamples.get(key)
+}
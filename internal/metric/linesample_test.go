@@ -0,0 +1,108 @@
+package metric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestLineSampler(t *testing.T) {
+	s := newLineSampler(10)
+
+	s.put("a", "aaaa")
+	s.put("b", "bbbb")
+
+	// replacing a line frees the bytes of the previous one
+	s.put("a", "AAAA")
+
+	if s.bytes != 8 {
+		t.Errorf("bytes = %d, want 8", s.bytes)
+	}
+
+	// c doesn't fit along with b and a, so b, the least recently stored, is evicted
+	s.put("c", "cc")
+	s.put("d", "dd")
+
+	if _, ok := s.get("b"); ok {
+		t.Error("least recently stored line not evicted")
+	}
+
+	for key, want := range map[string]string{"a": "AAAA", "c": "cc", "d": "dd"} {
+		if line, ok := s.get(key); !ok || line != want {
+			t.Errorf("get(%q) = %q, %t, want %q", key, line, ok, want)
+		}
+	}
+
+	// a line over the cap isn't retained, and doesn't evict the others
+	s.put("e", strings.Repeat("e", 11))
+
+	if _, ok := s.get("e"); ok {
+		t.Error("line over the cap retained")
+	}
+
+	if s.bytes != 8 || len(s.entries) != 3 {
+		t.Errorf("got %d lines of %d bytes, want 3 lines of 8 bytes", len(s.entries), s.bytes)
+	}
+}
+
+func TestWorstRequestsMaxLineSample(t *testing.T) {
+	const lineSize = 100
+
+	tests := []struct {
+		name          string
+		maxLineSample int
+		wantRetained  int
+	}{
+		{"under the cap", 10 * lineSize, 10},
+		{"at the cap", 4 * lineSize, 4},
+		{"none", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.WorstRequests = true
+			m.MaxLineSample = tt.maxLineSample
+
+			for i := 0; i < 10; i++ {
+				path := fmt.Sprintf("/%d", i)
+				line := path + strings.Repeat(" ", lineSize-len(path))
+
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: path},
+					RequestTime:    float64(i),
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, line)
+			}
+
+			if m.lineSamples.bytes > tt.maxLineSample {
+				t.Errorf("retained %d bytes, over the cap of %d", m.lineSamples.bytes, tt.maxLineSample)
+			}
+
+			worst := m.Analyze().WorstRequests
+
+			if len(worst) != 10 {
+				t.Fatalf("got %d worst requests, want 10", len(worst))
+			}
+
+			retained := 0
+
+			for _, req := range worst {
+				if req.Line != "" {
+					retained++
+
+					if !strings.HasPrefix(req.Line, req.Group+" ") {
+						t.Errorf("worst request of %s has the line %q", req.Group, req.Line)
+					}
+				}
+			}
+
+			if retained != tt.wantRetained {
+				t.Errorf("retained %d lines, want %d", retained, tt.wantRetained)
+			}
+		})
+	}
+}
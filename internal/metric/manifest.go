@@ -0,0 +1,8 @@
+package metric
+
+// ManifestOption is an option of the run, recorded in the report manifest
+type ManifestOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Set   bool   `json:"set"`
+}
@@ -0,0 +1,122 @@
+package metric
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// unescapedPipe matches the pipes separating Markdown table cells
+var unescapedPipe = regexp.MustCompile(`(^|[^\\])\|`)
+
+var markdownDelimiterCell = regexp.MustCompile(`^ *:?-{3,}:? *$`)
+
+// markdownTables returns the tables of a Markdown document as rows of cells, checking
+// that every table has a header, a delimiter row and rows of the same width
+func markdownTables(t *testing.T, doc string) [][][]string {
+	t.Helper()
+
+	var tables [][][]string
+	var table [][]string
+
+	for _, line := range append(strings.Split(doc, "\n"), "") {
+		if !strings.HasPrefix(line, "|") {
+			if table != nil {
+				tables = append(tables, table)
+				table = nil
+			}
+
+			continue
+		}
+
+		if !strings.HasSuffix(line, "|") {
+			t.Fatalf("table row %q doesn't end with a pipe", line)
+		}
+
+		// split on the pipes that aren't escaped, leaving out the outer ones
+		inner := line[1 : len(line)-1]
+		marked := unescapedPipe.ReplaceAllString(inner, "$1\x00")
+		cells := strings.Split(marked, "\x00")
+
+		if table != nil && len(cells) != len(table[0]) {
+			t.Fatalf("table row %q has %d cells, want %d", line, len(cells), len(table[0]))
+		}
+
+		if len(table) == 1 {
+			for _, cell := range cells {
+				if !markdownDelimiterCell.MatchString(cell) {
+					t.Fatalf("invalid delimiter row %q", line)
+				}
+			}
+		}
+
+		table = append(table, cells)
+	}
+
+	return tables
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	collectFixture(t, m)
+
+	var buf bytes.Buffer
+
+	if err := m.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := markdownTables(t, buf.String())
+
+	if len(tables) != 3 {
+		t.Fatalf("got %d tables, want 3:\n%s", len(tables), buf.String())
+	}
+
+	wantHeaders := []string{
+		"| Group | Status | Count |",
+		"| Group | Requests | Mean | p95 | p99 |",
+		"| Group | Timed out | Total | Percent |",
+	}
+
+	for i, want := range wantHeaders {
+		if got := "|" + strings.Join(tables[i][0], "|") + "|"; got != want {
+			t.Errorf("table %d header = %s, want %s", i, got, want)
+		}
+	}
+
+	// a row per group, after the header and delimiter rows
+	if rows := len(tables[2]) - 2; rows != 3 {
+		t.Errorf("time outs table has %d rows, want 3", rows)
+	}
+
+	for _, section := range []string{"## Overview", "## Response status codes", "## Latency", "## Time outs"} {
+		if !strings.Contains(buf.String(), section+"\n") {
+			t.Errorf("markdown is missing the %s section", section)
+		}
+	}
+}
+
+func TestWriteMarkdownEscapesCells(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/a|b"},
+		RequestTime:    0.1,
+		UpstreamStatus: 200,
+	}, "")
+
+	var buf bytes.Buffer
+
+	if err := m.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	markdownTables(t, buf.String())
+
+	if !strings.Contains(buf.String(), `| /a\|b | 1 |`) {
+		t.Errorf("pipe in group key isn't escaped:\n%s", buf.String())
+	}
+}
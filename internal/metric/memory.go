@@ -0,0 +1,80 @@
+package metric
+
+import "runtime"
+
+// MemoryReader returns the number of bytes of memory in use
+type MemoryReader func() uint64
+
+// DefaultApproximateCapacity is the default capacity of approximate aggregation
+const DefaultApproximateCapacity = 1000
+
+// memoryCheckInterval is the number of lines between memory checks
+const memoryCheckInterval = 1000
+
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return stats.HeapAlloc
+}
+
+// checkMemory switches to approximate aggregation once MaxMemory is reached
+func (m *MetricCollector) checkMemory() {
+	if m.MaxMemory == 0 || m.approximate {
+		return
+	}
+
+	m.linesSinceMemoryCheck++
+
+	if m.linesSinceMemoryCheck < memoryCheckInterval {
+		return
+	}
+
+	m.linesSinceMemoryCheck = 0
+
+	readMemory := m.MemoryReader
+
+	if readMemory == nil {
+		readMemory = heapAlloc
+	}
+
+	if readMemory() >= m.MaxMemory/10*9 {
+		m.switchToApproximate()
+	}
+}
+
+// switchToApproximate bounds the memory used by every group
+func (m *MetricCollector) switchToApproximate() {
+	m.approximate = true
+
+	capacity := m.approximateCapacity()
+
+	for _, bucket := range m.latencyData {
+		bucket.startDigest()
+
+		// a smaller Reservoir already bounds the bucket
+		if bucket.capacity > 0 && bucket.capacity <= capacity {
+			continue
+		}
+
+		bucket.downsample(capacity, m.rand)
+	}
+
+	if m.TalkersCapacity <= 0 {
+		m.TalkersCapacity = capacity
+
+		for _, counter := range m.talkersData {
+			counter.trim(capacity)
+		}
+	}
+
+	m.approximatePlugins(capacity)
+}
+
+func (m *MetricCollector) approximateCapacity() int {
+	if m.ApproximateCapacity <= 0 {
+		return DefaultApproximateCapacity
+	}
+
+	return m.ApproximateCapacity
+}
@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestMaxMemorySwitchover(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxMemory uint64
+		inUse     uint64
+		lines     int
+		want      bool
+	}{
+		{name: "no limit", inUse: 1 << 40, lines: 5000},
+		{name: "under 90% of the limit", maxMemory: 1000, inUse: 899, lines: 5000},
+		{name: "at 90% of the limit", maxMemory: 1000, inUse: 900, lines: 5000, want: true},
+		{name: "over the limit", maxMemory: 1000, inUse: 2000, lines: 5000, want: true},
+		{name: "before the first check", maxMemory: 1000, inUse: 2000, lines: memoryCheckInterval - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.MaxMemory = tt.maxMemory
+			m.ApproximateCapacity = 100
+			m.MemoryReader = func() uint64 { return tt.inUse }
+
+			for i := 0; i < tt.lines; i++ {
+				m.AddLine(&parser.NginxResult{
+					ClientIP:       "10.0.0.1",
+					Request:        &parser.Request{Method: "GET", Path: "/a"},
+					RequestTime:    float64(i%1000) / 1000,
+					Status:         200,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			report := m.Analyze()
+
+			if report.Approximate != tt.want {
+				t.Fatalf("approximate = %t, want %t", report.Approximate, tt.want)
+			}
+
+			bucket := m.latencyData["/a"]
+
+			if !tt.want {
+				if bucket.digest != nil || len(bucket.Latencies) != tt.lines {
+					t.Errorf("kept %d latencies with a digest %t, want every latency exactly", len(bucket.Latencies), bucket.digest != nil)
+				}
+
+				return
+			}
+
+			if len(bucket.Latencies) > m.ApproximateCapacity {
+				t.Errorf("kept %d latencies, want at most %d", len(bucket.Latencies), m.ApproximateCapacity)
+			}
+
+			group := report.Groups[0]
+
+			if group.LatencyCount != tt.lines {
+				t.Errorf("latency count = %d, want %d", group.LatencyCount, tt.lines)
+			}
+
+			// the latencies are uniform over [0, 1), so the p95 should be about 0.95
+			if math.Abs(group.P95Latency-0.95) > 0.01 {
+				t.Errorf("p95 = %g, want about 0.95", group.P95Latency)
+			}
+		})
+	}
+}
+
+func TestMaxMemoryBoundsTalkers(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Talkers = 1
+	m.MaxMemory = 1000
+	m.ApproximateCapacity = 10
+	m.MemoryReader = func() uint64 { return 1000 }
+
+	// every client is seen once, apart from the noisy one
+	for i := 0; i < memoryCheckInterval; i++ {
+		addClients(m, 200, "10.0.0.1", fmt.Sprintf("10.1.%d.%d", i/256, i%256))
+	}
+
+	if !m.approximate {
+		t.Fatal("expected the collector to switch to approximate aggregation")
+	}
+
+	if len(m.talkersData["all"].entries) > 10 {
+		t.Errorf("tracked %d clients, want at most 10", len(m.talkersData["all"].entries))
+	}
+
+	if top := m.Analyze().Talkers.All[0]; top.ClientIP != "10.0.0.1" {
+		t.Errorf("top talker = %s, want 10.0.0.1", top.ClientIP)
+	}
+}
+
+func TestApproximateAggregateRoundTrip(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.MaxMemory = 1
+	m.MemoryReader = func() uint64 { return 1 }
+
+	for i := 0; i < 20000; i++ {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:    float64(i%1000) / 1000,
+			Status:         200,
+			UpstreamStatus: 200,
+		}, "")
+	}
+
+	data, err := m.GobEncode()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := decoded.Analyze().Groups[0].P99Latency, m.Analyze().Groups[0].P99Latency; got != want {
+		t.Errorf("decoded p99 = %g, want %g", got, want)
+	}
+}
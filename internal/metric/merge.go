@@ -0,0 +1,197 @@
+package metric
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Shard returns an empty collector with the configuration of m, to merge back with Merge
+func (m *MetricCollector) Shard() *MetricCollector {
+	shard := *m
+
+	shard.latencyData = nil
+	shard.responseData = nil
+	shard.timedOutData = nil
+	shard.timedOutStatusData = nil
+	shard.errorCategoryData = nil
+	shard.timingData = nil
+	shard.sizeData = nil
+	shard.talkersData = nil
+	shard.slowClientData = nil
+	shard.worstData = nil
+	shard.upstreamTimeoutData = nil
+	shard.routingFailureData = nil
+	shard.windowData = nil
+	shard.classLatencyData = nil
+	shard.burstData = nil
+	shard.pluginData = nil
+	shard.bursts = nil
+	shard.heatmapData = nil
+	shard.heatmapStart = time.Time{}
+	shard.after = nil
+	shard.splitPassed = false
+	shard.latest = time.Time{}
+	shard.lineSamples = nil
+	shard.approximate = false
+	shard.linesSinceMemoryCheck = 0
+	shard.rand = rand.New(rand.NewSource(1))
+	shard.rejectedLatencies = 0
+	shard.earliest = time.Time{}
+	shard.warmupSkipped = 0
+	shard.reqIDData = nil
+	shard.reqIDsCapped = false
+	shard.untimed = 0
+
+	return &shard
+}
+
+// Merge adds the metrics of other into m, after which other must not be used
+func (m *MetricCollector) Merge(other *MetricCollector) {
+	if m.latencyData == nil {
+		m.latencyData = make(map[string]*LatencyMetricList)
+	}
+
+	if m.timedOutData == nil {
+		m.timedOutData = make(map[string]TimedOutMetric)
+	}
+
+	if m.responseData == nil {
+		m.responseData = make(map[string]ResponseMetric)
+	}
+
+	if m.errorCategoryData == nil {
+		m.errorCategoryData = make(map[string]uint)
+	}
+
+	if m.timingData == nil {
+		m.timingData = make(map[string]*TimingMetric)
+	}
+
+	m.approximate = m.approximate || other.approximate
+
+	// iterate in sorted order, since sampling into bounded buckets draws random numbers
+	for _, group := range sortedKeys(other.latencyData) {
+		bucket := other.latencyData[group]
+
+		if toBucket, exists := m.latencyData[group]; exists {
+			toBucket.merge(bucket, m.rand)
+		} else {
+			m.latencyData[group] = bucket
+		}
+	}
+
+	for group, respBucket := range other.responseData {
+		toRespBucket, exists := m.responseData[group]
+
+		if !exists {
+			toRespBucket = make(ResponseMetric)
+			m.responseData[group] = toRespBucket
+		}
+
+		for code, num := range respBucket {
+			toRespBucket[code] += num
+		}
+	}
+
+	for group, respBucket := range other.timedOutStatusData {
+		m.mergeTimedOutStatus(group, respBucket)
+	}
+
+	for group, timedOutMetric := range other.timedOutData {
+		toTimedOutMetric := m.timedOutData[group]
+		toTimedOutMetric.Count += timedOutMetric.Count
+		toTimedOutMetric.Total += timedOutMetric.Total
+		m.timedOutData[group] = toTimedOutMetric
+	}
+
+	for group, timing := range other.timingData {
+		if toTiming, exists := m.timingData[group]; exists {
+			toTiming.merge(timing)
+		} else {
+			m.timingData[group] = timing
+		}
+	}
+
+	for group, size := range other.sizeData {
+		if m.sizeData == nil {
+			m.sizeData = make(map[string]*SizeMetric)
+		}
+
+		if toSize, exists := m.sizeData[group]; exists {
+			toSize.merge(size)
+		} else {
+			m.sizeData[group] = size
+		}
+	}
+
+	for name, count := range other.errorCategoryData {
+		m.errorCategoryData[name] += count
+	}
+
+	if other.talkersData != nil {
+		if m.talkersData == nil {
+			m.talkersData = other.talkersData
+		} else {
+			for kind, counter := range other.talkersData {
+				m.talkersData[kind].merge(counter)
+			}
+		}
+	}
+
+	for key, count := range other.slowClientData {
+		if m.slowClientData == nil {
+			m.slowClientData = make(map[slowClientKey]uint)
+		}
+
+		m.slowClientData[key] += count
+	}
+
+	m.mergeReqIDs(other)
+	m.mergeWorst(other)
+	m.mergeWindows(other)
+	m.mergeClassLatencies(other)
+	m.mergeBursts(other)
+	m.mergeHeatmap(other)
+	m.mergePluginResults(other)
+	m.mergeSplit(other)
+
+	for group, count := range other.routingFailureData {
+		if m.routingFailureData == nil {
+			m.routingFailureData = make(map[string]uint)
+		}
+
+		m.routingFailureData[group] += count
+	}
+
+	for addr, timedOut := range other.upstreamTimeoutData {
+		if m.upstreamTimeoutData == nil {
+			m.upstreamTimeoutData = make(map[string]TimedOutMetric)
+		}
+
+		toTimedOut := m.upstreamTimeoutData[addr]
+		toTimedOut.Count += timedOut.Count
+		toTimedOut.Total += timedOut.Total
+		m.upstreamTimeoutData[addr] = toTimedOut
+	}
+
+	m.rejectedLatencies += other.rejectedLatencies
+	m.warmupSkipped += other.warmupSkipped
+	m.untimed += other.untimed
+
+	if m.earliest.IsZero() || (!other.earliest.IsZero() && other.earliest.Before(m.earliest)) {
+		m.earliest = other.earliest
+	}
+}
+
+func sortedKeys(data map[string]*LatencyMetricList) []string {
+	keys := make([]string, 0, len(data))
+
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -2,6 +2,14 @@ package metric
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
@@ -17,18 +25,190 @@ const (
 type GroupKind string
 
 const (
+	// GroupKindUpstreamIP groups by the upstream address, or __none__ without one
 	GroupKindUpstreamIP GroupKind = "upstream_ip"
-	GroupKindPath       GroupKind = "path"
+	// GroupKindClientIP groups by the client IP, with results without one under __none__
+	GroupKindClientIP GroupKind = "client_ip"
+	GroupKindPath     GroupKind = "path"
+	// GroupKindField groups by the value of the parsed log field named by GroupField
+	GroupKindField GroupKind = "field"
+	// GroupKindRefererHost groups by the host of the Referer header, or __direct__
+	GroupKindRefererHost GroupKind = "referer_host"
+	// GroupKindNone buckets every result into a single group, for an overall aggregate
+	GroupKindNone GroupKind = "none"
+	// GroupKindTemplate builds group keys with the collector's GroupTemplate
+	GroupKindTemplate GroupKind = "template"
+	// GroupKindUpstreamService groups by the namespace/service of the upstream name
+	GroupKindUpstreamService GroupKind = "upstream_service"
+	// GroupKindLatencyBucket groups by the request time rounded to LatencyBucketSize
+	GroupKindLatencyBucket GroupKind = "latency_bucket"
 )
 
+// groupNone is the group key of results missing the value they're grouped by
+const groupNone = "__none__"
+
+// groupDirect is the group key of requests without a referer host
+const groupDirect = "__direct__"
+
+// groupAll is the group key of every result with GroupKindNone
+const groupAll = "__all__"
+
+// ParseGroupBy parses a group kind, or field:<name> to group by a parsed log field
+func ParseGroupBy(spec string) (GroupKind, string, error) {
+	if strings.HasPrefix(spec, "field:") {
+		field := strings.TrimPrefix(spec, "field:")
+
+		if field == "" {
+			return "", "", fmt.Errorf("missing field name in group %s", spec)
+		}
+
+		return GroupKindField, field, nil
+	}
+
+	switch group := GroupKind(spec); group {
+	case GroupKindPath, GroupKindUpstreamIP, GroupKindClientIP, GroupKindNone, GroupKindRefererHost, GroupKindUpstreamService, GroupKindLatencyBucket:
+		return group, "", nil
+	}
+
+	return "", "", fmt.Errorf("unknown group %s", spec)
+}
+
 type LatencyMetric struct {
 	latency float64
 	time    time.Time
 }
 
 type LatencyMetricList struct {
-	IP        string
+	IP string
+	// Latencies holds every latency of the group, or a sample once it has a capacity
 	Latencies []*LatencyMetric
+
+	capacity int
+	count    int
+	sum      float64
+	over2s   int
+	// digest estimates the percentiles once the collector aggregates approximately
+	digest *tDigest
+	// arrival and trend are computed from every timed latency, not the sample
+	arrival arrivalCounter
+	trend   trendSums
+}
+
+func (l *LatencyMetricList) add(latency *LatencyMetric, rng *rand.Rand) {
+	l.count++
+	l.sum += latency.latency
+
+	if latency.latency > 2000 {
+		l.over2s++
+	}
+
+	if l.digest != nil {
+		l.digest.add(latency.latency, 1)
+	}
+
+	l.arrival.add(latency.time)
+	l.trend.add(latency.time, latency.latency)
+	l.sample(latency, rng)
+}
+
+// merge adds the latencies of other to the list
+func (l *LatencyMetricList) merge(other *LatencyMetricList, rng *rand.Rand) {
+	if other.digest != nil {
+		l.startDigest()
+		l.digest.merge(other.digest)
+	} else if l.digest != nil {
+		other.addToDigest(l.digest)
+	}
+
+	l.mergeSample(other, rng)
+	l.count += other.count
+	l.sum += other.sum
+	l.over2s += other.over2s
+	l.arrival.merge(&other.arrival)
+	l.trend.merge(&other.trend)
+}
+
+// mergeSample samples both lists in proportion to their counts
+func (l *LatencyMetricList) mergeSample(other *LatencyMetricList, rng *rand.Rand) {
+	if l.capacity <= 0 || len(l.Latencies)+len(other.Latencies) <= l.capacity {
+		l.Latencies = append(l.Latencies, other.Latencies...)
+		return
+	}
+
+	// draw capacity latencies without replacement from the latencies of both lists
+	fromL, left, right := 0, l.count, other.count
+
+	for i := 0; i < l.capacity && left+right > 0; i++ {
+		if rng.Intn(left+right) < left {
+			fromL++
+			left--
+		} else {
+			right--
+		}
+	}
+
+	fromL = min(max(fromL, l.capacity-len(other.Latencies)), len(l.Latencies))
+
+	otherLatencies := append([]*LatencyMetric(nil), other.Latencies...)
+
+	rng.Shuffle(len(l.Latencies), func(i, j int) {
+		l.Latencies[i], l.Latencies[j] = l.Latencies[j], l.Latencies[i]
+	})
+	rng.Shuffle(len(otherLatencies), func(i, j int) {
+		otherLatencies[i], otherLatencies[j] = otherLatencies[j], otherLatencies[i]
+	})
+
+	l.Latencies = append(l.Latencies[:fromL], otherLatencies[:l.capacity-fromL]...)
+}
+
+func (l *LatencyMetricList) sample(latency *LatencyMetric, rng *rand.Rand) {
+	if l.capacity <= 0 || len(l.Latencies) < l.capacity {
+		l.Latencies = append(l.Latencies, latency)
+		return
+	}
+
+	// reservoir sampling: replace a sampled latency with probability capacity/count
+	if i := rng.Intn(l.count); i < l.capacity {
+		l.Latencies[i] = latency
+	}
+}
+
+// startDigest starts a t-digest seeded with the latencies of the list
+func (l *LatencyMetricList) startDigest() {
+	if l.digest != nil {
+		return
+	}
+
+	l.digest = &tDigest{}
+	l.addToDigest(l.digest)
+}
+
+// addToDigest adds the latencies of the list to the digest, weighted if sampled
+func (l *LatencyMetricList) addToDigest(digest *tDigest) {
+	if len(l.Latencies) == 0 {
+		return
+	}
+
+	weight := float64(l.count) / float64(len(l.Latencies))
+
+	for _, latency := range l.Latencies {
+		digest.add(latency.latency, weight)
+	}
+}
+
+// downsample keeps a random sample of capacity latencies
+func (l *LatencyMetricList) downsample(capacity int, rng *rand.Rand) {
+	l.capacity = capacity
+
+	if len(l.Latencies) <= capacity {
+		return
+	}
+
+	rng.Shuffle(len(l.Latencies), func(i, j int) {
+		l.Latencies[i], l.Latencies[j] = l.Latencies[j], l.Latencies[i]
+	})
+
+	l.Latencies = l.Latencies[:capacity]
 }
 
 type ResponseMetric map[int64]uint
@@ -38,23 +218,282 @@ type TimedOutMetric struct {
 	Total int
 }
 
+// Sink receives every aggregated result along with its group
+type Sink interface {
+	Observe(group string, result *parser.NginxResult)
+}
+
 type MetricCollector struct {
-	group        GroupKind
-	metric       MetricKind
-	latencyData  map[string]*LatencyMetricList
-	responseData map[string]ResponseMetric
-	timedOutData map[string]TimedOutMetric
+	// Location is the timezone timestamps are displayed in, nil for the logged offset
+	Location *time.Location
+
+	// Sparkline appends a sparkline of the latency distribution to each path in the report
+	Sparkline bool
+
+	// Breakdown adds the mean connect, header, response and total latency of each group
+	Breakdown bool
+
+	// ResponseSizes adds the mean upstream and client response sizes of each group
+	ResponseSizes bool
+
+	// CaseInsensitivePaths lowercases request paths before grouping by them
+	CaseInsensitivePaths bool
+
+	// IncludeQuery adds the query parameters, or only QueryParams, to path group keys
+	IncludeQuery bool
+	QueryParams  []string
+
+	// RedactQueryValues redacts the query values in group keys, except KeepQueryValues
+	RedactQueryValues bool
+	KeepQueryValues   []string
+
+	// PathDepth truncates request paths to their first segments, zero keeps them whole
+	PathDepth int
+
+	// ErrorCategories categorize error log messages, nil for DefaultErrorCategories
+	ErrorCategories []ErrorCategory
+
+	// SaneLatency, if set, drops latencies outside of the range from the latency metrics
+	SaneLatency *LatencyRange
+
+	// RoundLatency is the number of decimal places of latencies, negative to not round
+	RoundLatency int
+
+	// ExcludeStatus drops results with an upstream status in any of the ranges
+	ExcludeStatus []StatusRange
+
+	// ErrorRateExcludeStatus leaves statuses in any of the ranges out of error rates
+	ErrorRateExcludeStatus []StatusRange
+
+	// IncludeStream aggregates the sessions of L4 stream services too
+	IncludeStream bool
+
+	// IncludeMethods keeps and ExcludeMethods drops results by request method
+	IncludeMethods []string
+	ExcludeMethods []string
+
+	// Warmup drops results logged within this duration of the earliest timestamp seen
+	Warmup time.Duration
+
+	// ReqIDCap bounds the request IDs tracked for duplicates, zero disables tracking
+	ReqIDCap int
+
+	// GroupField is the parsed log field results are grouped by, with GroupKindField
+	GroupField string
+
+	// GroupTemplate builds the group keys of results with GroupKindTemplate
+	GroupTemplate *GroupTemplate
+
+	// SubnetPrefixIPv4 and SubnetPrefixIPv6 mask grouped addresses to subnets, zero to not
+	SubnetPrefixIPv4 int
+	SubnetPrefixIPv6 int
+
+	// LatencyBucketSize is the size in seconds of GroupKindLatencyBucket buckets
+	LatencyBucketSize float64
+
+	// UpstreamNamespaces are the namespaces with hyphens, to split upstream names
+	UpstreamNamespaces []string
+
+	// Talkers is the number of clients with the most requests to report
+	Talkers int
+
+	// TalkersCapacity bounds the clients tracked for talkers, zero tracks every client
+	TalkersCapacity int
+
+	// MaxMemory is the memory in bytes above which the collector aggregates approximately
+	MaxMemory           uint64
+	ApproximateCapacity int
+
+	// MemoryReader reads the memory in use, nil for the runtime heap allocation
+	MemoryReader MemoryReader
+
+	// ClusterPaths merges path groups into inferred templates before reporting
+	ClusterPaths       bool
+	ClusterMaxDistinct int
+
+	// Sinks receive every aggregated result
+	Sinks []Sink
+
+	// Manifest holds the effective options of the run, shown at the top of the report
+	Manifest []*ManifestOption
+
+	// Plugins compute custom metrics of each group, shown in the report
+	Plugins []MetricPlugin
+
+	// Percentiles are the latency percentiles reported for each group, e.g. 99.9
+	Percentiles []float64
+
+	// PercentileMethod is how latency percentiles are computed, nearest rank if empty
+	PercentileMethod PercentileMethod
+
+	// MinRequests is the number of requests a group needs to exceed to be listed
+	MinRequests int
+
+	// MaxReportGroups caps the number of groups in the report, zero reports every group
+	MaxReportGroups int
+
+	// SortKey orders the groups of the report, by name if empty
+	SortKey        SortKey
+	SortDescending bool
+
+	// Template renders the report. If nil, DefaultReportTemplate is used.
+	Template *template.Template
+
+	// Trends adds the latency trend of each group to the report
+	Trends             bool
+	TrendFlatThreshold float64
+
+	// HealthScores adds the health score of each group to the report, configured by Health
+	HealthScores bool
+	Health       HealthConfig
+
+	// ArrivalHistogram adds a histogram of the gaps between requests of each group
+	ArrivalHistogram bool
+
+	// Window adds the p95 and p99 latencies of each group over the latest window
+	Window time.Duration
+
+	// SplitAt aggregates the results from it on separately, for WriteComparison
+	SplitAt time.Time
+
+	// Reservoir bounds the latencies kept per group to a random sample of this size
+	Reservoir int
+
+	// TopErrors is the number of groups with the most 5XX responses to report
+	TopErrors int
+
+	// BurstSize reports runs of this many failed requests of a group within BurstWindow
+	BurstSize   int
+	BurstWindow time.Duration
+
+	// LatencyByClass adds the mean and p95 latencies of each status class
+	LatencyByClass bool
+
+	// SLATiers are ascending latency thresholds in seconds to report compliance with
+	SLATiers []float64
+
+	// WorstRequests reports the raw line of the slowest request of each group
+	WorstRequests bool
+
+	// MaxLineSample caps the total bytes of raw lines retained
+	MaxLineSample int
+
+	// SlowClients reports the requests matching the slow client signature
+	SlowClients *SlowClientConfig
+
+	// HeatmapTimeBucket and HeatmapLatencyBucket are the bucket sizes of Heatmap
+	HeatmapTimeBucket    time.Duration
+	HeatmapLatencyBucket float64
+
+	group               GroupKind
+	metric              MetricKind
+	latencyData         map[string]*LatencyMetricList
+	responseData        map[string]ResponseMetric
+	timedOutData        map[string]TimedOutMetric
+	timedOutStatusData  map[string]ResponseMetric
+	errorCategoryData   map[string]uint
+	timingData          map[string]*TimingMetric
+	sizeData            map[string]*SizeMetric
+	talkersData         map[string]*talkerCounter
+	slowClientData      map[slowClientKey]uint
+	worstData           map[string]float64
+	upstreamTimeoutData map[string]TimedOutMetric
+	routingFailureData  map[string]uint
+	windowData          map[string]*latencyWindow
+	classLatencyData    map[int64]*LatencyMetricList
+	burstData           map[string]*burstState
+	pluginData          map[string]map[string]PluginAccumulator
+	bursts              []*Burst
+	heatmapData         map[heatmapCell]uint
+	heatmapStart        time.Time
+	// after aggregates the results after SplitAt, once splitPassed
+	after       *MetricCollector
+	splitPassed bool
+	latest      time.Time
+	lineSamples *lineSampler
+
+	approximate           bool
+	linesSinceMemoryCheck int
+	rand                  *rand.Rand
+	rejectedLatencies     uint
+	earliest              time.Time
+	warmupSkipped         uint
+	reqIDData             map[string]uint
+	reqIDsCapped          bool
+	// untimed counts the access log lines without a time_local
+	untimed uint
+}
+
+// LatencyRange is an inclusive range of latencies, in seconds
+type LatencyRange struct {
+	Min float64
+	Max float64
+}
+
+func (r *LatencyRange) Contains(latency float64) bool {
+	return latency >= r.Min && latency <= r.Max
 }
 
 func NewMetricCollector(group GroupKind, metric MetricKind) *MetricCollector {
-	return &MetricCollector{group, metric, nil, nil, nil}
+	return &MetricCollector{
+		RoundLatency:        -1,
+		ReqIDCap:            DefaultReqIDCap,
+		Health:              DefaultHealthConfig,
+		ApproximateCapacity: DefaultApproximateCapacity,
+		TrendFlatThreshold:  DefaultTrendFlatThreshold,
+		MaxLineSample:       DefaultMaxLineSample,
+		group:               group,
+		metric:              metric,
+		rand:                rand.New(rand.NewSource(1)),
+		BurstWindow:         DefaultBurstWindow,
+		LatencyBucketSize:   DefaultLatencyBucketSize,
+		MinRequests:         DefaultMinRequests,
+		Percentiles:         DefaultPercentiles,
+	}
+}
+
+func (m *MetricCollector) displayTime(t time.Time) time.Time {
+	if m.Location == nil {
+		return t
+	}
+
+	return t.In(m.Location)
 }
 
 func (m *MetricCollector) AddLine(result *parser.NginxResult, rawLine string) {
+	m.AddLineWithFields(result, nil, rawLine)
+}
+
+// AddLineWithFields adds a result along with the typed field map it was parsed from
+func (m *MetricCollector) AddLineWithFields(result *parser.NginxResult, fields map[string]interface{}, rawLine string) {
 	if result == nil {
 		return
 	}
 
+	if split := m.splitCollector(result); split != m {
+		split.AddLineWithFields(result, fields, rawLine)
+		return
+	}
+
+	if result.Stream && !m.IncludeStream {
+		return
+	}
+
+	if statusInRanges(result.UpstreamStatus, m.ExcludeStatus) || m.methodFiltered(result) {
+		return
+	}
+
+	if result.TimeLocal.IsZero() && result.ErrorMessage == "" {
+		m.untimed++
+	}
+
+	if m.inWarmup(result.TimeLocal) {
+		m.warmupSkipped++
+		return
+	}
+
+	m.checkMemory()
+
 	if m.latencyData == nil {
 		m.latencyData = make(map[string]*LatencyMetricList)
 	}
@@ -67,30 +506,85 @@ func (m *MetricCollector) AddLine(result *parser.NginxResult, rawLine string) {
 		m.responseData = make(map[string]ResponseMetric)
 	}
 
-	// TODO: figure out which field to group by
-	if result.Request == nil {
+	if m.errorCategoryData == nil {
+		m.errorCategoryData = make(map[string]uint)
+	}
+
+	m.trackReqID(result.ReqID)
+	m.addTalker(result)
+	m.addUpstreamTimeout(result)
+
+	if result.ErrorMessage != "" {
+		m.errorCategoryData[m.categorizeError(result.ErrorMessage)]++
+	}
+
+	group, ok := m.groupKey(result, fields)
+
+	if !ok {
 		return
 	}
 
-	group := result.Request.Path
+	for _, sink := range m.Sinks {
+		sink.Observe(group, result)
+	}
+
+	m.addSlowClient(group, result)
+	m.addSize(group, result)
+	m.trackWorst(group, result, rawLine)
+	m.addRoutingFailure(group, result)
+	m.addBurst(group, result)
+	m.addPluginResult(group, result)
 
-	// only include in latency data if it didn't time out
-	if !result.TimedOut {
+	saneLatency := m.SaneLatency == nil || m.SaneLatency.Contains(result.RequestTime)
+
+	if result.HasLatency() && !saneLatency {
+		m.rejectedLatencies++
+	}
+
+	// only include in latency data if it didn't time out and logged a latency
+	if result.HasLatency() && saneLatency {
 		bucket, exists := m.latencyData[group]
 
 		if !exists {
 			bucket = &LatencyMetricList{
 				IP:        result.UpstreamAddr,
 				Latencies: make([]*LatencyMetric, 0),
+				capacity:  m.Reservoir,
 			}
 
 			m.latencyData[group] = bucket
 		}
 
-		bucket.Latencies = append(bucket.Latencies, &LatencyMetric{
+		if m.approximate && bucket.capacity <= 0 {
+			bucket.capacity = m.approximateCapacity()
+		}
+
+		if m.approximate {
+			bucket.startDigest()
+		}
+
+		latency := &LatencyMetric{
 			latency: result.RequestTime,
 			time:    result.TimeLocal,
-		})
+		}
+
+		bucket.add(latency, m.rand)
+		m.addToWindow(group, latency)
+		m.addClassLatency(result, latency)
+		m.addHeatmap(latency)
+
+		if m.timingData == nil {
+			m.timingData = make(map[string]*TimingMetric)
+		}
+
+		timing, exists := m.timingData[group]
+
+		if !exists {
+			timing = &TimingMetric{}
+			m.timingData[group] = timing
+		}
+
+		timing.add(result)
 	}
 
 	respBucket, exists := m.responseData[group]
@@ -121,6 +615,7 @@ func (m *MetricCollector) AddLine(result *parser.NginxResult, rawLine string) {
 
 	if result.TimedOut {
 		timedOutMetric.Count++
+		m.addTimedOutStatus(group, result)
 	}
 
 	m.timedOutData[group] = timedOutMetric
@@ -128,126 +623,249 @@ func (m *MetricCollector) AddLine(result *parser.NginxResult, rawLine string) {
 	return
 }
 
-func (m *MetricCollector) GetInfo() {
-	// fmt.Println("number of pods listed:", len(m.latencyData))
-	fmt.Printf(`
----------------------------------
-OVERVIEW
----------------------------------	
-`)
+func (m *MetricCollector) inWarmup(t time.Time) bool {
+	if m.Warmup <= 0 || t.IsZero() {
+		return false
+	}
 
-	countReqs := 0
+	if m.earliest.IsZero() || t.Before(m.earliest) {
+		m.earliest = t
+	}
+
+	return t.Before(m.earliest.Add(m.Warmup))
+}
 
-	for _, bucket := range m.latencyData {
-		countReqs += len(bucket.Latencies)
+// groupKey returns the key the result is bucketed under, or false if there's none
+func (m *MetricCollector) groupKey(result *parser.NginxResult, fields map[string]interface{}) (string, bool) {
+	if m.group == GroupKindNone {
+		return groupAll, true
 	}
 
-	fmt.Println("Total number of requests tracked:", countReqs)
+	if m.group == GroupKindRefererHost {
+		return refererHost(result.Referer), true
+	}
 
-	fmt.Printf(`
----------------------------------
-RESPONSE STATUS CODE METRICS
----------------------------------	
-`)
+	if m.group == GroupKindTemplate {
+		// field tokens render raw values, which may hold request URIs
+		return m.RedactLine(m.GroupTemplate.key(result, fields)), true
+	}
 
-	for path, bucket := range m.responseData {
-		has4XXOr5XX := false
-		var totReqs uint = 0
+	if m.group == GroupKindUpstreamService {
+		return m.upstreamService(result.ProxyUpstreamName), true
+	}
 
-		for code, num := range bucket {
-			has4XXOr5XX = has4XXOr5XX || (code >= 400)
-			totReqs += num
+	if m.group == GroupKindLatencyBucket {
+		if result.LatencyMissing {
+			return groupNone, true
 		}
 
-		if has4XXOr5XX && totReqs > 100 {
-			fmt.Printf("%s:\n", path)
+		return latencyBucket(result.RequestTime, m.LatencyBucketSize), true
+	}
 
-			for code, num := range bucket {
-				fmt.Printf("  %d -- %d\n", code, num)
-			}
+	if m.group == GroupKindUpstreamIP {
+		return m.groupIP(result.UpstreamAddr), true
+	}
+
+	if m.group == GroupKindClientIP {
+		return m.groupIP(result.ClientIP), true
+	}
+
+	if m.group == GroupKindField {
+		value, exists := fields[m.GroupField]
 
-			fmt.Printf("Total: %d \n\n", totReqs)
+		if !exists {
+			return groupNone, true
 		}
+
+		return m.RedactLine(fmt.Sprint(value)), true
+	}
+
+	if result.Stream {
+		return "stream:" + result.Protocol, true
+	}
+
+	if result.Request == nil {
+		return "", false
+	}
+
+	path := result.Request.Path
+
+	if m.CaseInsensitivePaths {
+		path = strings.ToLower(path)
 	}
 
-	fmt.Printf(`
----------------------------------
-TIME OUT PERCENTAGES
----------------------------------	
-`)
+	if m.PathDepth > 0 {
+		path = truncatePath(path, m.PathDepth)
+	}
 
-	for path, timedOutMetric := range m.timedOutData {
-		if timedOutMetric.Count > 0 && timedOutMetric.Total > 100 {
-			fmt.Printf("%s: %d / %d (%.2f%%)\n", path, timedOutMetric.Count, timedOutMetric.Total, 100.0*float64(timedOutMetric.Count)/float64(timedOutMetric.Total))
+	if m.IncludeQuery {
+		if query := m.groupQuery(result.Request.Query); query != "" {
+			path += "?" + query
 		}
 	}
 
-	numOver2s := 0
+	return path, true
+}
+
+// DefaultMinRequests is the default of MinRequests
+const DefaultMinRequests = 100
+
+// DefaultLatencyBucketSize is the default size of latency buckets, in seconds
+const DefaultLatencyBucketSize = 0.1
+
+// latencyBucket returns the key of the bucket the latency rounds to
+func latencyBucket(latency, size float64) string {
+	if size <= 0 {
+		size = DefaultLatencyBucketSize
+	}
+
+	return fmt.Sprintf("%.3fs", math.Round(latency/size)*size)
+}
+
+// upstreamService returns the namespace/service of the upstream name
+func (m *MetricCollector) upstreamService(name string) string {
+	if name == "" {
+		return groupNone
+	}
+
+	upstream, ok := parser.ParseUpstreamName(name, m.UpstreamNamespaces)
+
+	if !ok {
+		return name
+	}
+
+	return upstream.Namespace + "/" + upstream.Service
+}
+
+// RedactedQueryValue replaces query values redacted from group keys
+const RedactedQueryValue = "REDACTED"
+
+// refererHost returns the host of the referer, or groupDirect
+func refererHost(referer string) string {
+	if referer == "" || referer == "-" {
+		return groupDirect
+	}
 
-	for path, bucket := range m.latencyData {
-		var totLatency float64 = 0
-		var totReqs float64 = float64(len(bucket.Latencies))
+	refererURL, err := url.Parse(referer)
+
+	if err != nil || refererURL.Hostname() == "" {
+		return groupDirect
+	}
+
+	return strings.ToLower(refererURL.Hostname())
+}
+
+// groupQuery returns the query parameters included in group keys, in a canonical order
+func (m *MetricCollector) groupQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+
+	// a redacted query only keeps the parameters that could be parsed
+	if err != nil && !m.RedactQueryValues {
+		return rawQuery
+	}
 
-		for _, latency := range bucket.Latencies {
-			totLatency += latency.latency
+	if len(m.QueryParams) > 0 {
+		included := make(url.Values)
 
-			if latency.latency > 2000 {
-				numOver2s++
+		for _, param := range m.QueryParams {
+			if value, exists := values[param]; exists {
+				included[param] = value
 			}
 		}
 
-		fmt.Printf("%s: %f (tot %.0f) \n", path, totLatency/totReqs, totReqs)
+		values = included
 	}
 
-	fmt.Printf("number of requests over 2 seconds: %d %.4f\n", numOver2s, 100*float64(numOver2s)/float64(countReqs))
+	if m.RedactQueryValues {
+		m.redactQueryValues(values)
+	}
+
+	return values.Encode()
 }
 
-// func (m *MetricCollector) WriteToCSV() {
-// 	data := make([][]string, 0)
+func (m *MetricCollector) redactQueryValues(values url.Values) {
+	for param, paramValues := range values {
+		if containsString(m.KeepQueryValues, param) {
+			continue
+		}
 
-// 	for _, bucket := range m.latencyData {
-// 		for _, latency := range bucket.Latencies {
-// 			data = append(data, []string{bucket.IP, latency.time.String(), fmt.Sprintf("%f", latency.latency)})
-// 		}
-// 	}
+		for i := range paramValues {
+			paramValues[i] = RedactedQueryValue
+		}
+	}
+}
 
-// 	file, err := os.Create("results-all.csv")
-// 	checkError("Cannot create file", err)
-// 	defer file.Close()
+// lineQuery matches the queries of the request URIs and URLs of a raw log line
+var lineQuery = regexp.MustCompile(`\?[^\s"]*`)
 
-// 	writer := csv.NewWriter(file)
-// 	defer writer.Flush()
+// RedactLine redacts the query values of the raw log line when RedactQueryValues is set
+func (m *MetricCollector) RedactLine(line string) string {
+	if !m.RedactQueryValues {
+		return line
+	}
 
-// 	for _, value := range data {
-// 		err := writer.Write(value)
-// 		checkError("Cannot write to file", err)
-// 	}
+	return lineQuery.ReplaceAllStringFunc(line, func(query string) string {
+		values, _ := url.ParseQuery(query[1:])
+		m.redactQueryValues(values)
 
-// 	dataGreater10s := make([][]string, 0)
+		return "?" + values.Encode()
+	})
+}
 
-// 	for _, bucket := range m.buckets {
-// 		for _, latency := range bucket.Latencies {
-// 			if latency.latency > 2 {
-// 				dataGreater10s = append(dataGreater10s, []string{bucket.IP, latency.time.String(), fmt.Sprintf("%f", latency.latency)})
-// 			}
-// 		}
-// 	}
+func containsString(values []string, str string) bool {
+	for _, value := range values {
+		if value == str {
+			return true
+		}
+	}
+
+	return false
+}
 
-// 	file, err = os.Create("results-greater-2s.csv")
-// 	checkError("Cannot create file", err)
-// 	defer file.Close()
+// truncatePath returns the first depth segments of path
+func truncatePath(path string, depth int) string {
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", depth+1)
 
-// 	writer = csv.NewWriter(file)
-// 	defer writer.Flush()
+	if len(segments) <= depth {
+		return path
+	}
+
+	return "/" + strings.Join(segments[:depth], "/")
+}
 
-// 	for _, value := range dataGreater10s {
-// 		err := writer.Write(value)
-// 		checkError("Cannot write to file", err)
-// 	}
-// }
+// groups returns every group key in sorted order, so that the report is deterministic
+func (m *MetricCollector) groups() []string {
+	res := make([]string, 0, len(m.timedOutData))
 
-// func checkError(message string, err error) {
-// 	if err != nil {
-// 		log.Fatal(message, err)
-// 	}
-// }
+	for group := range m.timedOutData {
+		res = append(res, group)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// codes returns the response codes in ascending order
+func (r ResponseMetric) codes() []int64 {
+	res := make([]int64, 0, len(r))
+
+	for code := range r {
+		res = append(res, code)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+
+	return res
+}
+
+// formatLatency formats a latency for the report, rounded to RoundLatency decimal places
+func (m *MetricCollector) formatLatency(latency float64) string {
+	if m.RoundLatency < 0 {
+		return fmt.Sprintf("%f", latency)
+	}
+
+	return strconv.FormatFloat(latency, 'f', m.RoundLatency, 64)
+}
@@ -0,0 +1,609 @@
+package metric
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// groupKeys returns the sorted keys the collector grouped its lines under
+func groupKeys(m *MetricCollector) []string {
+	keys := make([]string, 0, len(m.timedOutData))
+
+	for key := range m.timedOutData {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// addPaths adds a request for each path to the collector
+func addPaths(m *MetricCollector, paths ...string) {
+	for _, path := range paths {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: path},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		}, "")
+	}
+}
+
+func TestGroupCaseInsensitive(t *testing.T) {
+	paths := []string{"/API/Orders", "/api/orders", "/Api/ORDERS", "/api/users"}
+
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		want            []string
+	}{
+		{"off", false, []string{"/API/Orders", "/Api/ORDERS", "/api/orders", "/api/users"}},
+		{"on", true, []string{"/api/orders", "/api/users"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.CaseInsensitivePaths = tt.caseInsensitive
+
+			addPaths(m, paths...)
+
+			if got := groupKeys(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+
+			if tt.caseInsensitive && m.timedOutData["/api/orders"].Total != 3 {
+				t.Errorf("/api/orders has %d requests, want 3", m.timedOutData["/api/orders"].Total)
+			}
+		})
+	}
+}
+
+func TestSaneLatency(t *testing.T) {
+	latencies := []float64{-1, 0, 0.25, 3600, 3600.5, 1e12}
+
+	tests := []struct {
+		name         string
+		sane         *LatencyRange
+		wantKept     []float64
+		wantRejected uint
+	}{
+		{"off", nil, latencies, 0},
+		{"default range", &LatencyRange{Min: 0, Max: 3600}, []float64{0, 0.25, 3600}, 3},
+		{"narrow range", &LatencyRange{Min: 0.1, Max: 1}, []float64{0.25}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.SaneLatency = tt.sane
+
+			for _, latency := range latencies {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    latency,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			// a timed out request isn't counted as rejected, its latency is never used
+			m.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: "/"},
+				RequestTime:    -1,
+				UpstreamStatus: 504,
+				TimedOut:       true,
+			}, "")
+
+			kept := make([]float64, 0)
+
+			for _, latency := range m.latencyData["/"].Latencies {
+				kept = append(kept, latency.latency)
+			}
+
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("kept latencies = %v, want %v", kept, tt.wantKept)
+			}
+
+			if m.rejectedLatencies != tt.wantRejected {
+				t.Errorf("rejected %d latencies, want %d", m.rejectedLatencies, tt.wantRejected)
+			}
+
+			// rejected latencies still count as requests
+			if total := m.timedOutData["/"].Total; total != len(latencies)+1 {
+				t.Errorf("got %d requests, want %d", total, len(latencies)+1)
+			}
+		})
+	}
+}
+
+func TestTruncatePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		want  string
+	}{
+		{"/api/orders/123/items", 1, "/api"},
+		{"/api/orders/123/items", 2, "/api/orders"},
+		{"/api/orders/123/items", 4, "/api/orders/123/items"},
+		{"/api/orders", 3, "/api/orders"},
+		{"/api/orders/", 2, "/api/orders"},
+		{"/", 2, "/"},
+	}
+
+	for _, tt := range tests {
+		if got := truncatePath(tt.path, tt.depth); got != tt.want {
+			t.Errorf("truncatePath(%q, %d) = %q, want %q", tt.path, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestGroupPathDepth(t *testing.T) {
+	paths := []string{"/api/orders/1", "/api/orders/2", "/api/users/1", "/health"}
+
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, []string{"/api/orders/1", "/api/orders/2", "/api/users/1", "/health"}},
+		{1, []string{"/api", "/health"}},
+		{2, []string{"/api/orders", "/api/users", "/health"}},
+		{5, []string{"/api/orders/1", "/api/orders/2", "/api/users/1", "/health"}},
+	}
+
+	for _, tt := range tests {
+		m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+		m.PathDepth = tt.depth
+
+		addPaths(m, paths...)
+
+		if got := groupKeys(m); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: groups = %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantGroup GroupKind
+		wantField string
+		wantErr   bool
+	}{
+		{"path", GroupKindPath, "", false},
+		{"none", GroupKindNone, "", false},
+		{"referer_host", GroupKindRefererHost, "", false},
+		{"upstream_service", GroupKindUpstreamService, "", false},
+		{"latency_bucket", GroupKindLatencyBucket, "", false},
+		{"field:http_x_tenant", GroupKindField, "http_x_tenant", false},
+		{"field:", "", "", true},
+		{"tenant", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			group, field, err := ParseGroupBy(tt.spec)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error for %s", tt.spec)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if group != tt.wantGroup || field != tt.wantField {
+				t.Errorf("ParseGroupBy(%s) = %s, %s, want %s, %s", tt.spec, group, field, tt.wantGroup, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestGroupByField(t *testing.T) {
+	m := NewMetricCollector(GroupKindField, MetricKindLatency)
+	m.GroupField = "http_x_tenant"
+
+	lines := []map[string]interface{}{
+		{"http_x_tenant": "acme"},
+		{"http_x_tenant": "globex"},
+		{"http_x_tenant": "acme"},
+		// typed values are grouped by their formatted value
+		{"http_x_tenant": int64(42)},
+		{},
+		nil,
+	}
+
+	for _, fields := range lines {
+		m.AddLineWithFields(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/api"},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		}, fields, "")
+	}
+
+	want := []string{"42", "__none__", "acme", "globex"}
+
+	if got := groupKeys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+
+	if total := m.timedOutData["acme"].Total; total != 2 {
+		t.Errorf("acme has %d requests, want 2", total)
+	}
+
+	if total := m.timedOutData[groupNone].Total; total != 2 {
+		t.Errorf("%s has %d requests, want 2", groupNone, total)
+	}
+}
+
+func TestGroupIncludeQuery(t *testing.T) {
+	requests := []*parser.Request{
+		{Method: "GET", Path: "/flags", Query: "b=2&a=1"},
+		{Method: "GET", Path: "/flags", Query: "a=1&b=2"},
+		{Method: "GET", Path: "/flags", Query: "a=1&debug=true"},
+		{Method: "GET", Path: "/flags"},
+	}
+
+	tests := []struct {
+		name         string
+		includeQuery bool
+		queryParams  []string
+		want         []string
+	}{
+		{"off", false, nil, []string{"/flags"}},
+		{"on", true, nil, []string{"/flags", "/flags?a=1&b=2", "/flags?a=1&debug=true"}},
+		{"whitelisted", true, []string{"a"}, []string{"/flags", "/flags?a=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.IncludeQuery = tt.includeQuery
+			m.QueryParams = tt.queryParams
+
+			for _, request := range requests {
+				m.AddLine(&parser.NginxResult{
+					Request:        request,
+					RequestTime:    0.1,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			if got := groupKeys(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactQueryValues(t *testing.T) {
+	requests := []*parser.Request{
+		{Method: "GET", Path: "/login", Query: "token=s3cr3t&user=alice&page=2"},
+		{Method: "GET", Path: "/login", Query: "token=0th3r&user=bob&page=2"},
+		// the unparseable token is left out rather than leaked with the raw query
+		{Method: "GET", Path: "/search", Query: "q=secret%zz&page=1"},
+	}
+
+	tests := []struct {
+		name       string
+		keepValues []string
+		want       []string
+	}{
+		{"redacted", nil, []string{"/login?page=REDACTED&token=REDACTED&user=REDACTED", "/search?page=REDACTED"}},
+		{"kept", []string{"page"}, []string{"/login?page=2&token=REDACTED&user=REDACTED", "/search?page=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.IncludeQuery = true
+			m.RedactQueryValues = true
+			m.KeepQueryValues = tt.keepValues
+
+			for _, request := range requests {
+				m.AddLine(&parser.NginxResult{
+					Request:        request,
+					RequestTime:    0.1,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			if got := groupKeys(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, 10 * time.Second, 29 * time.Second, 30 * time.Second, time.Minute}
+
+	tests := []struct {
+		name        string
+		warmup      time.Duration
+		wantTotal   int
+		wantSkipped uint
+	}{
+		{"disabled", 0, 5, 0},
+		{"30s", 30 * time.Second, 2, 3},
+		{"longer than the input", time.Hour, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Warmup = tt.warmup
+
+			for _, offset := range offsets {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      start.Add(offset),
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    0.1,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			report := m.Analyze()
+
+			if report.TotalRequests != tt.wantTotal {
+				t.Errorf("TotalRequests = %d, want %d", report.TotalRequests, tt.wantTotal)
+			}
+
+			if report.WarmupSkipped != tt.wantSkipped {
+				t.Errorf("WarmupSkipped = %d, want %d", report.WarmupSkipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestWarmupUntimed(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Warmup = time.Minute
+
+	// results without a timestamp can't be placed in the warmup, so they're kept
+	addPaths(m, "/a", "/b")
+
+	if report := m.Analyze(); report.TotalRequests != 2 || report.WarmupSkipped != 0 {
+		t.Errorf("kept %d requests and skipped %d, want 2 kept", report.TotalRequests, report.WarmupSkipped)
+	}
+}
+
+func TestGroupByNone(t *testing.T) {
+	m := NewMetricCollector(GroupKindNone, MetricKindLatency)
+
+	addPaths(m, "/a", "/b", "/b", "/c?x=1")
+
+	// results without a request line are grouped too
+	m.AddLine(&parser.NginxResult{RequestTime: 0.1, UpstreamStatus: 504, TimedOut: true}, "")
+
+	report := m.Analyze()
+
+	if len(report.Groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(report.Groups))
+	}
+
+	group := report.Groups[0]
+
+	if group.Key != groupAll || group.TimedOut.Total != 5 || group.TimedOut.Count != 1 {
+		t.Errorf("group %s has %d of %d requests timed out, want %s with 1 of 5", group.Key, group.TimedOut.Count, group.TimedOut.Total, groupAll)
+	}
+}
+
+func TestGroupByRefererHost(t *testing.T) {
+	referers := []string{
+		"https://www.example.com/pricing",
+		"https://WWW.Example.com:8443/",
+		"http://news.example.org/story?id=1",
+		"",
+		"-",
+		"not a url",
+	}
+
+	m := NewMetricCollector(GroupKindRefererHost, MetricKindLatency)
+
+	for _, referer := range referers {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			Referer:        referer,
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+		}, "")
+	}
+
+	want := []string{groupDirect, "news.example.org", "www.example.com"}
+
+	if got := groupKeys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+
+	if total := m.timedOutData[groupDirect].Total; total != 3 {
+		t.Errorf("%s has %d requests, want 3", groupDirect, total)
+	}
+}
+
+func TestGroupByUpstreamService(t *testing.T) {
+	names := []string{"default-api-80", "default-api-443", "team-a-api-80", "upstream-default-backend", ""}
+
+	m := NewMetricCollector(GroupKindUpstreamService, MetricKindLatency)
+	m.UpstreamNamespaces = []string{"team-a"}
+
+	for _, name := range names {
+		m.AddLine(&parser.NginxResult{
+			Request:           &parser.Request{Method: "GET", Path: "/"},
+			RequestTime:       0.1,
+			UpstreamStatus:    200,
+			ProxyUpstreamName: name,
+		}, "")
+	}
+
+	// the ports of a service are collapsed into one group
+	want := []string{groupNone, "default/api", "team-a/api", "upstream-default-backend"}
+
+	if got := groupKeys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestIncludeStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		includeStream bool
+		want          []string
+	}{
+		{"excluded", false, []string{"/api"}},
+		{"included", true, []string{"/api", "stream:TCP", "stream:UDP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.IncludeStream = tt.includeStream
+
+			addPaths(m, "/api")
+
+			for _, protocol := range []string{"TCP", "UDP", "TCP"} {
+				m.AddLine(&parser.NginxResult{
+					Stream:         true,
+					Protocol:       protocol,
+					RequestTime:    1.5,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			if got := groupKeys(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByLatencyBucket(t *testing.T) {
+	latencies := []float64{0.001, 0.049, 0.051, 0.12, 0.149, 0.17, 0.26, 1.234}
+
+	tests := []struct {
+		name string
+		size float64
+		want map[string]uint
+	}{
+		{"100ms", 0.1, map[string]uint{"0.000s": 2, "0.100s": 3, "0.200s": 1, "0.300s": 1, "1.200s": 1}},
+		{"250ms", 0.25, map[string]uint{"0.000s": 4, "0.250s": 3, "1.250s": 1}},
+		{"1s", 1, map[string]uint{"0.000s": 7, "1.000s": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindLatencyBucket, MetricKindLatency)
+			m.LatencyBucketSize = tt.size
+
+			for _, latency := range latencies {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    latency,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			got := make(map[string]uint)
+
+			for _, group := range m.Analyze().Groups {
+				got[group.Key] = uint(group.LatencyCount)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buckets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyMissingKeepsResponse(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	request := &parser.Request{Method: "GET", Path: "/api"}
+
+	m.AddLine(&parser.NginxResult{Request: request, RequestTime: 0.25, Status: 200, UpstreamStatus: 200}, "")
+	m.AddLine(&parser.NginxResult{Request: request, Status: 504, UpstreamStatus: 504, LatencyMissing: true}, "")
+
+	report := m.Analyze()
+
+	if report.TotalRequests != 1 {
+		t.Errorf("TotalRequests = %d, want 1 latency", report.TotalRequests)
+	}
+
+	if report.StatusClasses.ServerError != 1 {
+		t.Errorf("5xx = %d, want 1", report.StatusClasses.ServerError)
+	}
+
+	if group := report.Groups[0]; group.TimedOut.Total != 2 || group.MeanLatency != 0.25 {
+		t.Errorf("got %d requests with mean %g, want 2 requests with mean 0.25", group.TimedOut.Total, group.MeanLatency)
+	}
+}
+
+func TestRedactLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		redact bool
+		line   string
+		want   string
+	}{
+		{
+			name: "disabled",
+			line: `"GET /a?id=2 HTTP/1.1" 200 "https://b/?token=x"`,
+			want: `"GET /a?id=2 HTTP/1.1" 200 "https://b/?token=x"`,
+		},
+		{
+			name:   "request and referer",
+			redact: true,
+			line:   `"GET /a?id=2&page=3 HTTP/1.1" 200 "https://b/?token=x"`,
+			want:   `"GET /a?id=REDACTED&page=3 HTTP/1.1" 200 "https://b/?token=REDACTED"`,
+		},
+		{
+			name:   "no query",
+			redact: true,
+			line:   `"GET /a HTTP/1.1" 200 "-"`,
+			want:   `"GET /a HTTP/1.1" 200 "-"`,
+		},
+		{
+			name:   "unparseable query",
+			redact: true,
+			line:   `"GET /a?id=%zz HTTP/1.1" 200`,
+			want:   `"GET /a? HTTP/1.1" 200`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.RedactQueryValues = tt.redact
+			m.KeepQueryValues = []string{"page"}
+
+			if got := m.RedactLine(tt.line); got != tt.want {
+				t.Errorf("RedactLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldGroupRedacted(t *testing.T) {
+	m := NewMetricCollector(GroupKindField, MetricKindLatency)
+	m.GroupField = "request"
+	m.RedactQueryValues = true
+
+	m.AddLineWithFields(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/a", Query: "id=2"},
+		RequestTime:    0.1,
+		Status:         200,
+		UpstreamStatus: 200,
+	}, map[string]interface{}{"request": "GET /a?id=2 HTTP/1.1"}, "")
+
+	if got, want := m.groups(), "GET /a?id=REDACTED HTTP/1.1"; len(got) != 1 || got[0] != want {
+		t.Errorf("groups = %q, want [%q]", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// PercentileMethod is how latency percentiles are computed
+type PercentileMethod string
+
+const (
+	// PercentileNearestRank picks the smallest latency with p% of latencies at or below
+	PercentileNearestRank PercentileMethod = "nearest-rank"
+	// PercentileLinear interpolates between the closest ranks, like numpy's default
+	PercentileLinear PercentileMethod = "linear"
+)
+
+// ParsePercentileMethod parses a percentile method, returning an error for unknown methods
+func ParsePercentileMethod(spec string) (PercentileMethod, error) {
+	switch method := PercentileMethod(spec); method {
+	case PercentileNearestRank, PercentileLinear:
+		return method, nil
+	}
+
+	return "", fmt.Errorf("unknown percentile method %s, must be nearest-rank or linear", spec)
+}
+
+// sortedLatencies returns the latencies of the list in ascending order
+func sortedLatencies(latencies []*LatencyMetric) []float64 {
+	res := make([]float64, len(latencies))
+
+	for i, latency := range latencies {
+		res[i] = latency.latency
+	}
+
+	sort.Float64s(res)
+
+	return res
+}
+
+// percentile returns the p-th percentile of the sorted values with PercentileMethod
+func (m *MetricCollector) percentile(sorted []float64, p float64) float64 {
+	if m.PercentileMethod == PercentileLinear {
+		return linearPercentile(sorted, p)
+	}
+
+	return nearestRankPercentile(sorted, p)
+}
+
+// listPercentiles returns the latency at each of the percentiles of the list
+func (m *MetricCollector) listPercentiles(l *LatencyMetricList, percentiles ...float64) []float64 {
+	res := make([]float64, len(percentiles))
+
+	if l.digest != nil {
+		for i, p := range percentiles {
+			res[i] = l.digest.quantile(p / 100)
+		}
+
+		return res
+	}
+
+	sorted := sortedLatencies(l.Latencies)
+
+	for i, p := range percentiles {
+		res[i] = m.percentile(sorted, p)
+	}
+
+	return res
+}
+
+// nearestRankPercentile returns the nearest-rank p-th percentile of the sorted values
+func nearestRankPercentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(sorted) {
+		rank = len(sorted)
+	}
+
+	return sorted[rank-1]
+}
+
+// linearPercentile returns the linearly interpolated p-th percentile of the sorted values
+func linearPercentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// DefaultPercentiles are the latency percentiles reported for each group by default
+var DefaultPercentiles = []float64{50, 95, 99}
+
+// LatencyPercentile is the latency at a percentile of a group's requests
+type LatencyPercentile struct {
+	Percentile float64
+	Latency    float64
+}
+
+// latencyPercentiles returns the latency at each of the collector's Percentiles
+func (m *MetricCollector) latencyPercentiles(bucket *LatencyMetricList) []*LatencyPercentile {
+	if len(bucket.Latencies) == 0 || len(m.Percentiles) == 0 {
+		return nil
+	}
+
+	latencies := m.listPercentiles(bucket, m.Percentiles...)
+	res := make([]*LatencyPercentile, len(m.Percentiles))
+
+	for i, p := range m.Percentiles {
+		res[i] = &LatencyPercentile{p, latencies[i]}
+	}
+
+	return res
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"math"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestPercentileMethods(t *testing.T) {
+	dataset := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		name        string
+		sorted      []float64
+		p           float64
+		wantNearest float64
+		wantLinear  float64
+	}{
+		{"p0", dataset, 0, 1, 1},
+		{"p50", dataset, 50, 5, 5.5},
+		{"p90", dataset, 90, 9, 9.1},
+		{"p95", dataset, 95, 10, 9.55},
+		{"p99", dataset, 99, 10, 9.91},
+		{"p100", dataset, 100, 10, 10},
+		{"single value", []float64{3}, 99, 3, 3},
+		{"no values", nil, 99, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for method, want := range map[PercentileMethod]float64{PercentileNearestRank: tt.wantNearest, PercentileLinear: tt.wantLinear} {
+				m := &MetricCollector{PercentileMethod: method}
+
+				if got := m.percentile(tt.sorted, tt.p); math.Abs(got-want) > 1e-9 {
+					t.Errorf("%s p%g = %g, want %g", method, tt.p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportPercentileMethod(t *testing.T) {
+	tests := []struct {
+		method PercentileMethod
+		want   []float64
+	}{
+		{PercentileNearestRank, []float64{5, 10, 10}},
+		{PercentileLinear, []float64{5.5, 9.55, 9.91}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.PercentileMethod = tt.method
+
+			// added out of order, so the percentiles depend on the latencies being sorted
+			for _, latency := range []float64{7, 3, 10, 1, 5, 9, 2, 8, 4, 6} {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: "/a"},
+					RequestTime:    latency,
+					Status:         200,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			percentiles := m.Analyze().Groups[0].Percentiles
+
+			if len(percentiles) != len(DefaultPercentiles) {
+				t.Fatalf("got %d percentiles, want %d", len(percentiles), len(DefaultPercentiles))
+			}
+
+			for i, p := range percentiles {
+				if p.Percentile != DefaultPercentiles[i] || math.Abs(p.Latency-tt.want[i]) > 1e-9 {
+					t.Errorf("p%g = %g, want p%g = %g", p.Percentile, p.Latency, DefaultPercentiles[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParsePercentileMethod(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    PercentileMethod
+		wantErr bool
+	}{
+		{spec: "nearest-rank", want: PercentileNearestRank},
+		{spec: "linear", want: PercentileLinear},
+		{spec: "interpolated", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePercentileMethod(tt.spec)
+
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParsePercentileMethod(%q) = %q, %v, want %q, error %t", tt.spec, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
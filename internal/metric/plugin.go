@@ -0,0 +1,284 @@
+package metric
+
+import (
+	"encoding/gob"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// MetricPlugin computes a custom metric of each group
+type MetricPlugin interface {
+	Name() string
+	NewAccumulator() PluginAccumulator
+}
+
+// PluginAccumulator aggregates the results of a group, and must be registered with gob
+type PluginAccumulator interface {
+	Add(result *parser.NginxResult)
+	// Merge adds the state of other, an accumulator of the same plugin
+	Merge(other PluginAccumulator)
+	Value() float64
+}
+
+// PluginApproximator is implemented by accumulators that can bound their memory
+type PluginApproximator interface {
+	Approximate(capacity int)
+}
+
+// Plugins are the built-in plugins, by name
+var Plugins = map[string]MetricPlugin{
+	"unique_clients": UniqueClientsPlugin{},
+}
+
+func init() {
+	gob.Register(&uniqueClients{})
+}
+
+// UniqueClientsPlugin counts the distinct client IPs of a group
+type UniqueClientsPlugin struct{}
+
+func (UniqueClientsPlugin) Name() string {
+	return "unique_clients"
+}
+
+func (UniqueClientsPlugin) NewAccumulator() PluginAccumulator {
+	return &uniqueClients{Clients: make(map[string]bool)}
+}
+
+// uniqueClients counts distinct client IPs, with a KMV sketch once approximated
+type uniqueClients struct {
+	Clients  map[string]bool
+	Capacity int
+	// Hashes are the smallest client hashes in ascending order, once approximated
+	Hashes []uint64
+}
+
+func (u *uniqueClients) Add(result *parser.NginxResult) {
+	if u.Capacity <= 0 {
+		u.addClient(result.ClientIP)
+		return
+	}
+
+	u.addHash(clientHash(result.ClientIP))
+}
+
+func (u *uniqueClients) Merge(other PluginAccumulator) {
+	o := other.(*uniqueClients)
+
+	if u.Capacity <= 0 && o.Capacity <= 0 {
+		for client := range o.Clients {
+			u.addClient(client)
+		}
+
+		return
+	}
+
+	// the merged sketch can only be as precise as the coarser of both
+	if o.Capacity > 0 && (u.Capacity <= 0 || o.Capacity < u.Capacity) {
+		u.Approximate(o.Capacity)
+	}
+
+	for client := range o.Clients {
+		u.addHash(clientHash(client))
+	}
+
+	for _, hash := range o.Hashes {
+		u.addHash(hash)
+	}
+}
+
+func (u *uniqueClients) Value() float64 {
+	if u.Capacity <= 0 {
+		return float64(len(u.Clients))
+	}
+
+	if len(u.Hashes) < u.Capacity {
+		return float64(len(u.Hashes))
+	}
+
+	// the k-th smallest of n uniform hashes is expected around k/n of the hash space
+	return math.Round(float64(u.Capacity-1) / (float64(u.Hashes[u.Capacity-1]) / math.MaxUint64))
+}
+
+// Approximate switches to the sketch, or shrinks it to capacity
+func (u *uniqueClients) Approximate(capacity int) {
+	if capacity <= 0 || (u.Capacity > 0 && u.Capacity <= capacity) {
+		return
+	}
+
+	u.Capacity = capacity
+
+	if len(u.Hashes) > capacity {
+		u.Hashes = u.Hashes[:capacity]
+	}
+
+	for client := range u.Clients {
+		u.addHash(clientHash(client))
+	}
+
+	u.Clients = nil
+}
+
+// addClient adds an exact client, creating the set that gob leaves nil when it's empty
+func (u *uniqueClients) addClient(client string) {
+	if u.Clients == nil {
+		u.Clients = make(map[string]bool)
+	}
+
+	u.Clients[client] = true
+}
+
+func (u *uniqueClients) addHash(hash uint64) {
+	i := sort.Search(len(u.Hashes), func(i int) bool { return u.Hashes[i] >= hash })
+
+	if i < len(u.Hashes) && u.Hashes[i] == hash {
+		return
+	}
+
+	if i >= u.Capacity {
+		return
+	}
+
+	if len(u.Hashes) < u.Capacity {
+		u.Hashes = append(u.Hashes, 0)
+	}
+
+	copy(u.Hashes[i+1:], u.Hashes[i:])
+	u.Hashes[i] = hash
+}
+
+func clientHash(client string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+
+	return h.Sum64()
+}
+
+// PluginValue is the value a plugin computed for a group
+type PluginValue struct {
+	Name  string
+	Value float64
+}
+
+// addPluginResult feeds the result to the group's accumulator of each plugin
+func (m *MetricCollector) addPluginResult(group string, result *parser.NginxResult) {
+	if len(m.Plugins) == 0 {
+		return
+	}
+
+	if m.pluginData == nil {
+		m.pluginData = make(map[string]map[string]PluginAccumulator)
+	}
+
+	accumulators, exists := m.pluginData[group]
+
+	if !exists {
+		accumulators = make(map[string]PluginAccumulator, len(m.Plugins))
+		m.pluginData[group] = accumulators
+	}
+
+	for _, plugin := range m.Plugins {
+		accumulator, exists := accumulators[plugin.Name()]
+
+		if !exists {
+			accumulator = m.newPluginAccumulator(plugin)
+			accumulators[plugin.Name()] = accumulator
+		}
+
+		accumulator.Add(result)
+	}
+}
+
+// newPluginAccumulator returns a new accumulator of the plugin
+func (m *MetricCollector) newPluginAccumulator(plugin MetricPlugin) PluginAccumulator {
+	accumulator := plugin.NewAccumulator()
+
+	if approximator, ok := accumulator.(PluginApproximator); ok {
+		if m.approximate {
+			approximator.Approximate(m.approximateCapacity())
+		} else if m.Reservoir > 0 {
+			approximator.Approximate(m.Reservoir)
+		}
+	}
+
+	return accumulator
+}
+
+// approximatePlugins bounds the memory of every accumulator that supports it
+func (m *MetricCollector) approximatePlugins(capacity int) {
+	for _, accumulators := range m.pluginData {
+		for _, accumulator := range accumulators {
+			if approximator, ok := accumulator.(PluginApproximator); ok {
+				approximator.Approximate(capacity)
+			}
+		}
+	}
+}
+
+// pluginValues computes the value of each plugin for the group
+func (m *MetricCollector) pluginValues(group string) []*PluginValue {
+	if len(m.Plugins) == 0 {
+		return nil
+	}
+
+	values := make([]*PluginValue, 0, len(m.Plugins))
+
+	for _, plugin := range m.Plugins {
+		accumulator, exists := m.pluginData[group][plugin.Name()]
+
+		if !exists {
+			accumulator = plugin.NewAccumulator()
+		}
+
+		values = append(values, &PluginValue{plugin.Name(), accumulator.Value()})
+	}
+
+	return values
+}
+
+// pluginNames returns the names of the plugins, or nil if none are set
+func (m *MetricCollector) pluginNames() []string {
+	if len(m.Plugins) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(m.Plugins))
+
+	for _, plugin := range m.Plugins {
+		names = append(names, plugin.Name())
+	}
+
+	return names
+}
+
+// mergePluginResults merges the plugin accumulators of other into m's
+func (m *MetricCollector) mergePluginResults(other *MetricCollector) {
+	for group, accumulators := range other.pluginData {
+		m.mergePluginGroup(group, accumulators)
+	}
+}
+
+// mergePluginGroup merges the accumulators into the accumulators of the group
+func (m *MetricCollector) mergePluginGroup(group string, accumulators map[string]PluginAccumulator) {
+	if m.pluginData == nil {
+		m.pluginData = make(map[string]map[string]PluginAccumulator)
+	}
+
+	into, exists := m.pluginData[group]
+
+	if !exists {
+		m.pluginData[group] = accumulators
+		return
+	}
+
+	for name, accumulator := range accumulators {
+		if current, exists := into[name]; exists {
+			current.Merge(accumulator)
+		} else {
+			into[name] = accumulator
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func addPluginClients(m *MetricCollector, group string, from, to int) {
+	for i := from; i < to; i++ {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: group},
+			ClientIP:       fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff),
+			RequestTime:    0.1,
+			Status:         200,
+			UpstreamStatus: 200,
+		}, "")
+	}
+}
+
+func groupPluginValue(t *testing.T, m *MetricCollector, group string) float64 {
+	t.Helper()
+
+	for _, g := range m.Analyze().Groups {
+		if g.Key == group {
+			return g.PluginValues[0].Value
+		}
+	}
+
+	t.Fatalf("no group %s", group)
+
+	return 0
+}
+
+func TestPluginsRegistered(t *testing.T) {
+	for name, plugin := range Plugins {
+		t.Run(name, func(t *testing.T) {
+			if plugin.Name() != name {
+				t.Errorf("plugin %s is named %s", name, plugin.Name())
+			}
+
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Plugins = []MetricPlugin{plugin}
+			addPluginClients(m, "/a", 0, 10)
+
+			// every accumulator must survive --save-aggregate
+			data, err := m.GobEncode()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			decoded.Plugins = []MetricPlugin{plugin}
+
+			if err := decoded.GobDecode(data); err != nil {
+				t.Fatal(err)
+			}
+
+			addPluginClients(decoded, "/a", 10, 20)
+			addPluginClients(m, "/a", 10, 20)
+
+			if got, want := groupPluginValue(t, decoded, "/a"), groupPluginValue(t, m, "/a"); got != want {
+				t.Errorf("decoded value = %g, want %g", got, want)
+			}
+		})
+	}
+}
+
+func TestUniqueClients(t *testing.T) {
+	tests := []struct {
+		name      string
+		clients   int
+		reservoir int
+		maxMemory bool
+		shards    int
+		// tolerance is the relative error allowed from the exact count
+		tolerance float64
+	}{
+		{name: "exact", clients: 5000},
+		{name: "exact shards", clients: 5000, shards: 4},
+		{name: "below the capacity", clients: 500, reservoir: 1000},
+		{name: "reservoir", clients: 20000, reservoir: 1000, tolerance: 0.1},
+		{name: "reservoir shards", clients: 20000, reservoir: 1000, shards: 4, tolerance: 0.1},
+		{name: "max memory", clients: 20000, maxMemory: true, tolerance: 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Plugins = []MetricPlugin{UniqueClientsPlugin{}}
+			m.Reservoir = tt.reservoir
+
+			if tt.maxMemory {
+				m.MaxMemory = 1
+				m.MemoryReader = func() uint64 { return 1 }
+			}
+
+			if tt.shards == 0 {
+				addPluginClients(m, "/a", 0, tt.clients)
+			} else {
+				step := tt.clients / tt.shards
+
+				for i := 0; i < tt.shards; i++ {
+					shard := m.Shard()
+					// shards overlap, so only the union of their clients is distinct
+					addPluginClients(shard, "/a", i*step, min((i+2)*step, tt.clients))
+					m.Merge(shard)
+				}
+			}
+
+			got := groupPluginValue(t, m, "/a")
+
+			if err := math.Abs(got-float64(tt.clients)) / float64(tt.clients); err > tt.tolerance {
+				t.Errorf("unique_clients = %g, want %d within %g", got, tt.clients, tt.tolerance)
+			}
+
+			for _, accumulator := range m.pluginData["/a"] {
+				u := accumulator.(*uniqueClients)
+
+				if tt.tolerance > 0 && (len(u.Clients) > 0 || len(u.Hashes) > u.Capacity) {
+					t.Errorf("kept %d clients and %d hashes, want at most %d hashes", len(u.Clients), len(u.Hashes), u.Capacity)
+				}
+			}
+		})
+	}
+}
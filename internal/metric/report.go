@@ -0,0 +1,443 @@
+package metric
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+//go:embed report.tmpl
+var defaultReportTemplate string
+
+// DefaultReportTemplate renders the default text report
+var DefaultReportTemplate = template.Must(NewReportTemplate("report", defaultReportTemplate))
+
+//go:embed report_markdown.tmpl
+var markdownReportTemplate string
+
+// MarkdownReportTemplate renders the report as GitHub-flavored Markdown tables
+var MarkdownReportTemplate = template.Must(NewReportTemplate("markdown", markdownReportTemplate))
+
+// Report is the aggregated view of the collected metrics that report templates render
+type Report struct {
+	// TotalRequests is the number of requests with a tracked latency
+	TotalRequests int
+
+	// StatusClasses counts the responses of every group by status class
+	StatusClasses *ClassSummary
+
+	// SaneLatency is the range latencies were validated against, or nil
+	SaneLatency       *LatencyRange
+	RejectedLatencies uint
+
+	// Warmup is the warmup duration, and WarmupSkipped the number of results dropped in it
+	Warmup        time.Duration
+	WarmupSkipped uint
+
+	// Untimed is the number of lines without a timestamp, left out of time based metrics
+	Untimed uint
+
+	// DuplicateReqIDs counts the request IDs seen more than once
+	DuplicateReqIDs     uint
+	DuplicateReqIDLines uint
+	ReqIDsCapped        bool
+	TrackReqIDs         bool
+
+	// Groups holds the reported groups, and CappedBy the sort key they were capped by
+	Groups        []*GroupReport
+	OmittedGroups int
+	CappedBy      SortKey
+
+	// MinRequests is the number of requests a group needs to exceed to be listed
+	MinRequests int
+
+	// HealthScores holds every group, sorted by health score, if health scores are shown
+	HealthScores []*GroupReport
+
+	// TopErrors holds the groups with the most 5XX responses, or nil
+	TopErrors []*GroupReport
+
+	NumOver2s     int
+	Over2sPercent float64
+
+	// ErrorCategories holds the number of error log messages per category
+	ErrorCategories []*ErrorCategoryCount
+
+	// Approximate is set if the collector reached its memory limit
+	Approximate bool
+
+	// Reservoir is the number of latencies sampled per group, or zero
+	Reservoir int
+
+	// Talkers holds the clients with the most requests, or nil if talkers aren't tracked
+	Talkers *TalkersReport
+
+	// UpstreamTimeouts holds the upstream addresses with timed out requests
+	UpstreamTimeouts []*UpstreamTimeouts
+
+	// RoutingFailures holds the requests that matched no ingress rule, or nil
+	RoutingFailures *RoutingFailuresReport
+
+	// WorstRequests holds the slowest request of each reported group, or nil
+	WorstRequests []*WorstRequest
+
+	// SlowClients holds the slow client requests, or nil if they aren't detected
+	SlowClients *SlowClientsReport
+
+	// Sparkline is set when the latency sparkline of each group should be shown
+	Sparkline bool
+
+	// Breakdown is set when the latency breakdown of each group should be shown
+	Breakdown bool
+
+	// ResponseSizes is set when the response sizes of each group should be shown
+	ResponseSizes bool
+
+	// Trends is set when the latency trend of each group should be shown
+	Trends bool
+
+	// Window is the duration of the recent window percentiles, or zero
+	Window time.Duration
+
+	// Bursts are the bursts of consecutive failures, or nil if there are none
+	Bursts []*Burst
+
+	// ClassLatencies are the latencies by response status class, or nil
+	ClassLatencies []*ClassLatency
+
+	// ArrivalHistogram is set when the inter-arrival histogram of each group should be shown
+	ArrivalHistogram bool
+
+	// SLATiers is set when the latency SLA tiers of each group should be shown
+	SLATiers bool
+
+	// Plugins are the names of the collector's plugins, or nil if there are none
+	Plugins []string
+
+	// Manifest holds the options of the run, or nil if they aren't recorded
+	Manifest []*ManifestOption
+}
+
+type GroupReport struct {
+	Key string
+
+	// ResponseCodes holds the number of responses per status code, sorted by code
+	ResponseCodes []*ResponseCodeCount
+	ResponseTotal uint
+	Has4XXOr5XX   bool
+	// ServerErrors is the number of responses with a 5XX status
+	ServerErrors uint
+
+	TimedOut        TimedOutMetric
+	TimedOutPercent float64
+
+	// LatencyCount is the number of requests with a tracked latency
+	LatencyCount int
+	MeanLatency  float64
+	P95Latency   float64
+	// Percentiles holds the latency at each of the collector's Percentiles, or nil
+	Percentiles []*LatencyPercentile
+
+	// ErrorRate is the fraction of 5XX or timed out responses
+	ErrorRate   float64
+	TimeoutRate float64
+	Sparkline   string
+
+	// LatencyTrend is the latency slope in seconds per minute
+	LatencyTrend   float64
+	TrendDirection string
+
+	// Breakdown holds where the group's request time is spent, or nil
+	Breakdown *LatencyBreakdown
+
+	// Sizes holds the group's mean response sizes, or nil
+	Sizes *ResponseSizes
+
+	HealthScore float64
+
+	// PluginValues holds the value of each plugin for the group
+	PluginValues []*PluginValue
+
+	// SLATiers holds the percentage of the group's latencies under each SLA tier
+	SLATiers []*SLATier
+
+	// ArrivalGaps is the histogram of the gaps between the group's requests, or nil
+	ArrivalGaps []*ArrivalGapBucket
+
+	// WindowPercentiles are the group's percentiles over the recent window, or nil
+	P99Latency        float64
+	WindowPercentiles *WindowPercentiles
+}
+
+type ResponseCodeCount struct {
+	Code  int64
+	Count uint
+	// Label is the code as shown in the report, which is "no response" for 000
+	Label string
+}
+
+func statusLabel(code int64) string {
+	if code == parser.StatusNoResponse {
+		return "no response"
+	}
+
+	return strconv.FormatInt(code, 10)
+}
+
+type ErrorCategoryCount struct {
+	Name  string
+	Count uint
+}
+
+// NewReportTemplate parses a report template, with the latency and timing functions
+func NewReportTemplate(name, text string) (*template.Template, error) {
+	// the functions are replaced with the collector's formatting when rendering
+	return template.New(name).Funcs(reportFuncs(func(latency float64) string {
+		return fmt.Sprintf("%f", latency)
+	})).Parse(text)
+}
+
+func reportFuncs(formatLatency func(float64) string) template.FuncMap {
+	return template.FuncMap{
+		"latency": formatLatency,
+		// cell escapes a value for a Markdown table cell
+		"cell": func(value string) string {
+			return strings.NewReplacer("|", "\\|", "\n", " ").Replace(value)
+		},
+		"timing": func(latency *float64) string {
+			if latency == nil {
+				return "-"
+			}
+
+			return formatLatency(*latency)
+		},
+	}
+}
+
+// Analyze aggregates the collected metrics into a Report
+func (m *MetricCollector) Analyze() *Report {
+	if m.ClusterPaths && m.group == GroupKindPath {
+		m.clusterGroups()
+	}
+
+	report := &Report{
+		SaneLatency:       m.SaneLatency,
+		RejectedLatencies: m.rejectedLatencies,
+		Groups:            make([]*GroupReport, 0),
+		ErrorCategories:   make([]*ErrorCategoryCount, 0),
+		Sparkline:         m.Sparkline,
+		Breakdown:         m.Breakdown,
+		ResponseSizes:     m.ResponseSizes,
+		Trends:            m.Trends,
+		SLATiers:          len(m.SLATiers) > 0,
+		ArrivalHistogram:  m.ArrivalHistogram,
+		Window:            m.Window,
+		ClassLatencies:    m.classLatencies(),
+		Bursts:            m.burstsReport(),
+		ReqIDsCapped:      m.reqIDsCapped,
+		TrackReqIDs:       m.ReqIDCap > 0,
+		Talkers:           m.talkersReport(),
+		SlowClients:       m.slowClientsReport(),
+		UpstreamTimeouts:  m.upstreamTimeouts(),
+		RoutingFailures:   m.routingFailures(),
+		Approximate:       m.approximate,
+		Reservoir:         m.Reservoir,
+		Warmup:            m.Warmup,
+		WarmupSkipped:     m.warmupSkipped,
+		Untimed:           m.untimed,
+		StatusClasses:     m.classSummary(),
+		MinRequests:       m.MinRequests,
+		Plugins:           m.pluginNames(),
+		Manifest:          m.Manifest,
+	}
+
+	report.DuplicateReqIDs, report.DuplicateReqIDLines = m.duplicateReqIDs()
+
+	for _, bucket := range m.latencyData {
+		report.TotalRequests += bucket.count
+	}
+
+	for _, group := range m.groups() {
+		groupReport := &GroupReport{
+			Key:           group,
+			ResponseCodes: make([]*ResponseCodeCount, 0),
+			TimedOut:      m.timedOutData[group],
+			HealthScore:   m.HealthScore(group),
+			ErrorRate:     m.errorRate(group),
+			TimeoutRate:   m.timeoutRate(group),
+			PluginValues:  m.pluginValues(group),
+		}
+
+		if size, exists := m.sizeData[group]; exists && m.ResponseSizes {
+			groupReport.Sizes = size.sizes()
+		}
+
+		respBucket := m.responseData[group]
+
+		for _, code := range respBucket.codes() {
+			groupReport.ResponseCodes = append(groupReport.ResponseCodes, &ResponseCodeCount{code, respBucket[code], statusLabel(code)})
+			groupReport.Has4XXOr5XX = groupReport.Has4XXOr5XX || (code >= 400)
+			groupReport.ResponseTotal += respBucket[code]
+
+			if code >= 500 {
+				groupReport.ServerErrors += respBucket[code]
+			}
+		}
+
+		if groupReport.TimedOut.Total > 0 {
+			groupReport.TimedOutPercent = 100.0 * float64(groupReport.TimedOut.Count) / float64(groupReport.TimedOut.Total)
+		}
+
+		if bucket, exists := m.latencyData[group]; exists {
+			report.NumOver2s += bucket.over2s
+
+			groupReport.LatencyCount = bucket.count
+			groupReport.MeanLatency = bucket.sum / float64(bucket.count)
+			latencies := m.listPercentiles(bucket, 95, 99)
+			groupReport.P95Latency = latencies[0]
+			groupReport.P99Latency = latencies[1]
+			groupReport.Percentiles = m.latencyPercentiles(bucket)
+
+			if m.Window > 0 {
+				groupReport.WindowPercentiles = m.windowPercentiles(group)
+			}
+
+			if slope, ok := bucket.trend.slope(); ok && m.Trends {
+				groupReport.LatencyTrend = slope
+				groupReport.TrendDirection = m.trendDirection(slope)
+			}
+
+			if timing, exists := m.timingData[group]; exists && m.Breakdown {
+				groupReport.Breakdown = timing.breakdown()
+			}
+
+			groupReport.SLATiers = m.slaTiers(bucket.Latencies)
+
+			if m.ArrivalHistogram {
+				groupReport.ArrivalGaps = bucket.arrival.buckets()
+			}
+
+			if m.Sparkline {
+				groupReport.Sparkline = sparkline(histogram(bucket.Latencies, sparklineBuckets))
+			}
+		}
+
+		report.Groups = append(report.Groups, groupReport)
+	}
+
+	if report.TotalRequests > 0 {
+		report.Over2sPercent = 100 * float64(report.NumOver2s) / float64(report.TotalRequests)
+	}
+
+	report.TopErrors = m.topErrors(report.Groups)
+
+	sortGroups(report.Groups, m.SortKey, m.SortDescending)
+
+	if m.MaxReportGroups > 0 && len(report.Groups) > m.MaxReportGroups {
+		report.OmittedGroups = len(report.Groups) - m.MaxReportGroups
+
+		if m.SortKey == SortByName || m.SortKey == "" {
+			report.Groups = busiestGroups(report.Groups, m.MaxReportGroups)
+		} else {
+			report.CappedBy = m.SortKey
+			report.Groups = report.Groups[:m.MaxReportGroups]
+		}
+	}
+
+	report.WorstRequests = m.worstRequests(report.Groups)
+
+	if m.HealthScores {
+		report.HealthScores = make([]*GroupReport, len(report.Groups))
+		copy(report.HealthScores, report.Groups)
+
+		sort.SliceStable(report.HealthScores, func(i, j int) bool {
+			if m.Health.Ascending {
+				return report.HealthScores[i].HealthScore < report.HealthScores[j].HealthScore
+			}
+
+			return report.HealthScores[i].HealthScore > report.HealthScores[j].HealthScore
+		})
+	}
+
+	for name, count := range m.errorCategoryData {
+		report.ErrorCategories = append(report.ErrorCategories, &ErrorCategoryCount{name, count})
+	}
+
+	sort.Slice(report.ErrorCategories, func(i, j int) bool {
+		if report.ErrorCategories[i].Count == report.ErrorCategories[j].Count {
+			return report.ErrorCategories[i].Name < report.ErrorCategories[j].Name
+		}
+
+		return report.ErrorCategories[i].Count > report.ErrorCategories[j].Count
+	})
+
+	return report
+}
+
+// busiestGroups returns the n groups with the most requests, in the order of groups
+func busiestGroups(groups []*GroupReport, n int) []*GroupReport {
+	busiest := make([]*GroupReport, len(groups))
+	copy(busiest, groups)
+
+	sort.SliceStable(busiest, func(i, j int) bool {
+		return busiest[i].TimedOut.Total > busiest[j].TimedOut.Total
+	})
+
+	kept := make(map[*GroupReport]bool, n)
+
+	for _, group := range busiest[:n] {
+		kept[group] = true
+	}
+
+	res := make([]*GroupReport, 0, n)
+
+	for _, group := range groups {
+		if kept[group] {
+			res = append(res, group)
+		}
+	}
+
+	return res
+}
+
+// WriteReport renders the report to w with the collector's Template
+func (m *MetricCollector) WriteReport(w io.Writer) error {
+	tmpl := m.Template
+
+	if tmpl == nil {
+		tmpl = DefaultReportTemplate
+	}
+
+	return m.render(w, tmpl)
+}
+
+// WriteMarkdown renders the report to w with MarkdownReportTemplate
+func (m *MetricCollector) WriteMarkdown(w io.Writer) error {
+	return m.render(w, MarkdownReportTemplate)
+}
+
+func (m *MetricCollector) render(w io.Writer, tmpl *template.Template) error {
+	tmpl, err := tmpl.Clone()
+
+	if err != nil {
+		return err
+	}
+
+	tmpl.Funcs(reportFuncs(m.formatLatency))
+
+	return tmpl.Execute(w, m.Analyze())
+}
+
+func (m *MetricCollector) GetInfo() {
+	if err := m.WriteReport(os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,324 @@
+package metric
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// collectFixture parses testdata/access.log into the collector
+func collectFixture(t *testing.T, m *MetricCollector) {
+	t.Helper()
+
+	factory := &parser.NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := factory.New()
+	file, err := os.Open(filepath.Join("testdata", "access.log"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		res, err := p.Parse(scanner.Text())
+
+		if err != nil {
+			t.Fatalf("fixture line dropped: %v", err)
+		}
+
+		m.AddLine(res, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkGolden compares the output to the golden file, or rewrites it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended:\n%s", path, got)
+	}
+}
+
+func TestWriteReportGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		round  int
+		golden string
+	}{
+		{"one decimal", 1, "report_round1.golden"},
+		{"three decimals", 3, "report_round3.golden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the report must be identical on every run for the golden file to hold
+			for i := 0; i < 3; i++ {
+				m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+				m.RoundLatency = tt.round
+				collectFixture(t, m)
+
+				var buf bytes.Buffer
+
+				if err := m.WriteReport(&buf); err != nil {
+					t.Fatal(err)
+				}
+
+				checkGolden(t, tt.golden, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriteReportTemplate(t *testing.T) {
+	tmpl, err := NewReportTemplate("custom", `{{.TotalRequests}} requests
+{{range .Groups}}{{.Key}} {{.ResponseTotal}} {{latency .MeanLatency}}{{range .ResponseCodes}} {{.Code}}={{.Count}}{{end}}
+{{end}}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Template = tmpl
+	m.RoundLatency = 2
+	collectFixture(t, m)
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `60 requests
+/api/orders 20 0.97 200=14 404=2 502=2 503=2
+/api/users 20 0.85 200=14 404=2 502=2 503=2
+/health 20 1.00 200=14 404=2 502=2 503=2
+`
+
+	if buf.String() != want {
+		t.Errorf("report =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestNewReportTemplateInvalid(t *testing.T) {
+	if _, err := NewReportTemplate("invalid", `{{.TotalRequests`); err == nil {
+		t.Error("expected an error for an unterminated action")
+	}
+
+	tmpl, err := NewReportTemplate("unknown field", `{{.NoSuchField}}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Template = tmpl
+
+	if err := m.WriteReport(&bytes.Buffer{}); err == nil {
+		t.Error("expected an error rendering an unknown field")
+	}
+}
+
+func TestWriteReportEmpty(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	report := m.Analyze()
+
+	if report.TotalRequests != 0 || len(report.Groups) != 0 {
+		t.Errorf("report of no requests has %d requests in %d groups", report.TotalRequests, len(report.Groups))
+	}
+
+	if report.Over2sPercent != 0 {
+		t.Errorf("Over2sPercent = %g, want 0", report.Over2sPercent)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "number of requests over 2 seconds: 0 0.0000") {
+		t.Errorf("report of no requests doesn't show 0 requests over 2 seconds:\n%s", buf.String())
+	}
+
+	if strings.Contains(buf.String(), "NaN") {
+		t.Errorf("report of no requests contains NaN:\n%s", buf.String())
+	}
+}
+
+func TestReportNoResponse(t *testing.T) {
+	factory := &parser.NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := factory.New()
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for _, status := range []string{"000", "000", "200"} {
+		line := `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 504 0 "-" "curl/7.68.0" 120 60.001 [default-api-80] [] 10.1.0.5:8080 0 60.000 ` + status + ` req1`
+		res, err := p.Parse(line)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m.AddLine(res, line)
+	}
+
+	group := m.Analyze().Groups[0]
+
+	if len(group.ResponseCodes) != 2 {
+		t.Fatalf("got %d response codes, want 2", len(group.ResponseCodes))
+	}
+
+	if code := group.ResponseCodes[0]; code.Label != "no response" || code.Count != 2 {
+		t.Errorf("first response code = %s -- %d, want no response -- 2", code.Label, code.Count)
+	}
+
+	if code := group.ResponseCodes[1]; code.Label != "200" {
+		t.Errorf("second response code = %s, want 200", code.Label)
+	}
+
+	if group.TimedOut.Count != 2 {
+		t.Errorf("%d timed out, want 2", group.TimedOut.Count)
+	}
+}
+
+func TestMaxReportGroups(t *testing.T) {
+	tests := []struct {
+		name           string
+		max            int
+		sortKey        SortKey
+		sortDescending bool
+		wantGroups     []string
+		wantOmitted    int
+		wantNoteLine   string
+	}{
+		{"uncapped", 0, SortByName, false, []string{"/a", "/b", "/c", "/d"}, 0, ""},
+		{"capped", 2, SortByName, false, []string{"/b", "/d"}, 2, "Showing the 2 busiest groups, 2 more omitted"},
+		{"capped by name descending", 2, SortByName, true, []string{"/d", "/b"}, 2, "Showing the 2 busiest groups, 2 more omitted"},
+		{"capped by count ascending", 2, SortByCount, false, []string{"/a", "/c"}, 2, "Showing the first 2 groups by count, 2 more omitted"},
+		{"capped by count descending", 3, SortByCount, true, []string{"/b", "/d", "/a"}, 1, "Showing the first 3 groups by count, 1 more omitted"},
+		{"over the number of groups", 10, SortByName, false, []string{"/a", "/b", "/c", "/d"}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.MaxReportGroups = tt.max
+			m.SortKey = tt.sortKey
+			m.SortDescending = tt.sortDescending
+
+			addPaths(m, "/a", "/b", "/b", "/b", "/c", "/d", "/d")
+
+			report := m.Analyze()
+			keys := make([]string, 0, len(report.Groups))
+
+			for _, group := range report.Groups {
+				keys = append(keys, group.Key)
+			}
+
+			if !reflect.DeepEqual(keys, tt.wantGroups) {
+				t.Errorf("groups = %v, want %v", keys, tt.wantGroups)
+			}
+
+			if report.OmittedGroups != tt.wantOmitted {
+				t.Errorf("omitted %d groups, want %d", report.OmittedGroups, tt.wantOmitted)
+			}
+
+			var buf bytes.Buffer
+
+			if err := m.WriteReport(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if hasNote := strings.Contains(buf.String(), "more omitted"); hasNote != (tt.wantNoteLine != "") {
+				t.Errorf("report has an omitted note %t, want %t:\n%s", hasNote, tt.wantNoteLine != "", buf.String())
+			}
+
+			if tt.wantNoteLine != "" && !strings.Contains(buf.String(), tt.wantNoteLine) {
+				t.Errorf("report doesn't contain %q:\n%s", tt.wantNoteLine, buf.String())
+			}
+		})
+	}
+}
+
+func TestReportMinRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		minRequests int
+		wantListed  bool
+	}{
+		{"default", DefaultMinRequests, false},
+		{"one request isn't more than one", 1, false},
+		{"include zero", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.MinRequests = tt.minRequests
+			m.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: "/single"},
+				RequestTime:    60,
+				Status:         504,
+				UpstreamStatus: 504,
+				TimedOut:       true,
+			}, "")
+
+			var buf bytes.Buffer
+
+			if err := m.WriteReport(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+
+			for _, section := range []string{"/single:\n", "/single: 1 / 1 (100.00%)"} {
+				if got := strings.Contains(out, section); got != tt.wantListed {
+					t.Errorf("report lists %q: %t, want %t\n%s", section, got, tt.wantListed, out)
+				}
+			}
+		})
+	}
+}
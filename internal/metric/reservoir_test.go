@@ -0,0 +1,112 @@
+package metric
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestReservoirPercentiles(t *testing.T) {
+	exact := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	sampled := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	sampled.Reservoir = 2000
+
+	// a long tailed latency distribution
+	r := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 100000; i++ {
+		result := &parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:    r.ExpFloat64() / 10,
+			UpstreamStatus: 200,
+		}
+
+		exact.AddLine(result, "")
+		sampled.AddLine(result, "")
+	}
+
+	if n := len(sampled.latencyData["/a"].Latencies); n != sampled.Reservoir {
+		t.Errorf("kept %d latencies, want %d", n, sampled.Reservoir)
+	}
+
+	want := exact.Analyze().Groups[0]
+	got := sampled.Analyze().Groups[0]
+
+	// counts and means don't depend on the sample
+	if got.LatencyCount != want.LatencyCount || math.Abs(got.MeanLatency-want.MeanLatency) > 1e-9 {
+		t.Errorf("count %d mean %g, want count %d mean %g", got.LatencyCount, got.MeanLatency, want.LatencyCount, want.MeanLatency)
+	}
+
+	percentiles := []struct {
+		name      string
+		got, want float64
+	}{
+		{"p95", got.P95Latency, want.P95Latency},
+		{"p99", got.P99Latency, want.P99Latency},
+	}
+
+	for _, p := range percentiles {
+		if math.Abs(p.got-p.want)/p.want > 0.1 {
+			t.Errorf("%s = %g, want %g within 10%%", p.name, p.got, p.want)
+		}
+	}
+}
+
+func TestReservoirSmallGroups(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Reservoir = 10
+
+	for i := 1; i <= 5; i++ {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:    float64(i) / 10,
+			UpstreamStatus: 200,
+		}, "")
+	}
+
+	// groups within the reservoir keep every latency
+	if n := len(m.latencyData["/a"].Latencies); n != 5 {
+		t.Errorf("kept %d latencies, want 5", n)
+	}
+}
+
+func TestReservoirMergeEqualShards(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Reservoir = 1000
+
+	// the first shard only has latencies of 0.1s, the second of 0.3s
+	for _, latency := range []float64{0.1, 0.3} {
+		s := m.Shard()
+
+		for i := 0; i < 10000; i++ {
+			s.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: "/a"},
+				RequestTime:    latency,
+				UpstreamStatus: 200,
+			}, "")
+		}
+
+		m.Merge(s)
+	}
+
+	latencies := m.latencyData["/a"].Latencies
+
+	if len(latencies) != m.Reservoir {
+		t.Fatalf("kept %d latencies, want %d", len(latencies), m.Reservoir)
+	}
+
+	first := 0
+
+	for _, latency := range latencies {
+		if latency.latency == 0.1 {
+			first++
+		}
+	}
+
+	// equal shards should each make up about half of the sample
+	if first < 400 || first > 600 {
+		t.Errorf("kept %d latencies of the first shard, want about 500", first)
+	}
+}
@@ -0,0 +1,56 @@
+package metric
+
+import (
+	"sort"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// RoutingFailuresReport holds the requests ingress-nginx couldn't route to an upstream
+type RoutingFailuresReport struct {
+	Total  uint
+	Groups []*RoutingFailureCount
+}
+
+type RoutingFailureCount struct {
+	Group string
+	Count uint
+}
+
+func (m *MetricCollector) addRoutingFailure(group string, result *parser.NginxResult) {
+	if !result.RoutingFailure {
+		return
+	}
+
+	if m.routingFailureData == nil {
+		m.routingFailureData = make(map[string]uint)
+	}
+
+	m.routingFailureData[group]++
+}
+
+// routingFailures returns the routing failures, or nil if there are none
+func (m *MetricCollector) routingFailures() *RoutingFailuresReport {
+	if len(m.routingFailureData) == 0 {
+		return nil
+	}
+
+	report := &RoutingFailuresReport{
+		Groups: make([]*RoutingFailureCount, 0, len(m.routingFailureData)),
+	}
+
+	for group, count := range m.routingFailureData {
+		report.Total += count
+		report.Groups = append(report.Groups, &RoutingFailureCount{group, count})
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Count == report.Groups[j].Count {
+			return report.Groups[i].Group < report.Groups[j].Group
+		}
+
+		return report.Groups[i].Count > report.Groups[j].Count
+	})
+
+	return report
+}
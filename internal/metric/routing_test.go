@@ -0,0 +1,70 @@
+package metric
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestRoutingFailures(t *testing.T) {
+	requests := []struct {
+		path           string
+		routingFailure bool
+	}{
+		{"/api", false},
+		{"/wp-login.php", true},
+		{"/wp-login.php", true},
+		{"/.env", true},
+		{"/api", false},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    0.01,
+			UpstreamStatus: 404,
+			RoutingFailure: req.routingFailure,
+		}, "")
+	}
+
+	want := &RoutingFailuresReport{
+		Total: 3,
+		Groups: []*RoutingFailureCount{
+			{"/wp-login.php", 2},
+			{"/.env", 1},
+		},
+	}
+
+	if got := m.Analyze().RoutingFailures; !reflect.DeepEqual(got, want) {
+		t.Errorf("routing failures = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "routed to the default backend: 3\n/wp-login.php: 2\n/.env: 1\n") {
+		t.Errorf("routing failures missing from the report:\n%s", buf.String())
+	}
+}
+
+func TestRoutingFailuresNone(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/api"},
+		RequestTime:    0.01,
+		UpstreamStatus: 200,
+	}, "")
+
+	if got := m.Analyze().RoutingFailures; got != nil {
+		t.Errorf("routing failures = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,60 @@
+package metric
+
+import "github.com/abelanger5/nginx-ingress-parser/internal/parser"
+
+// SizeMetric accumulates the response sizes of a group's requests
+type SizeMetric struct {
+	count         int
+	bodyBytes     int64
+	upstreamBytes int64
+}
+
+func (s *SizeMetric) add(result *parser.NginxResult) {
+	if len(result.UpstreamResponseLengths) == 0 {
+		return
+	}
+
+	s.count++
+	s.bodyBytes += result.BodyBytesSent
+
+	// the client is sent the response of the last upstream attempt
+	s.upstreamBytes += result.UpstreamResponseLengths[len(result.UpstreamResponseLengths)-1]
+}
+
+func (s *SizeMetric) merge(other *SizeMetric) {
+	s.count += other.count
+	s.bodyBytes += other.bodyBytes
+	s.upstreamBytes += other.upstreamBytes
+}
+
+// ResponseSizes holds the mean response sizes of a group, in bytes
+type ResponseSizes struct {
+	MeanBodyBytes     float64
+	MeanUpstreamBytes float64
+}
+
+func (s *SizeMetric) sizes() *ResponseSizes {
+	if s.count == 0 {
+		return nil
+	}
+
+	return &ResponseSizes{
+		MeanBodyBytes:     float64(s.bodyBytes) / float64(s.count),
+		MeanUpstreamBytes: float64(s.upstreamBytes) / float64(s.count),
+	}
+}
+
+func (m *MetricCollector) addSize(group string, result *parser.NginxResult) {
+	if m.sizeData == nil {
+		m.sizeData = make(map[string]*SizeMetric)
+	}
+
+	size, exists := m.sizeData[group]
+
+	if !exists {
+		size = &SizeMetric{}
+		m.sizeData[group] = size
+	}
+
+	size.add(result)
+}
@@ -0,0 +1,83 @@
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestResponseSizes(t *testing.T) {
+	requests := []struct {
+		bodyBytes       int64
+		upstreamLengths []int64
+	}{
+		{512, []int64{512}},
+		// a retried request is sent the response of the last attempt
+		{100, []int64{0, 300}},
+		// requests without an upstream response are left out
+		{0, nil},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.ResponseSizes = true
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			Request:                 &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:             0.1,
+			BodyBytesSent:           req.bodyBytes,
+			UpstreamResponseLengths: req.upstreamLengths,
+			UpstreamStatus:          200,
+			UpstreamAddr:            "10.0.0.1:80",
+		}, "")
+	}
+
+	sizes := m.Analyze().Groups[0].Sizes
+
+	if sizes == nil {
+		t.Fatal("no response sizes for the group")
+	}
+
+	if sizes.MeanBodyBytes != 306 || sizes.MeanUpstreamBytes != 406 {
+		t.Errorf("sizes = %+v, want client 306B and upstream 406B", *sizes)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "/a: upstream 406.0B client 306.0B") {
+		t.Errorf("response sizes missing from the report:\n%s", buf.String())
+	}
+}
+
+func TestResponseSizesHidden(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:                 &parser.Request{Method: "GET", Path: "/a"},
+		RequestTime:             0.1,
+		BodyBytesSent:           512,
+		UpstreamResponseLengths: []int64{512},
+		UpstreamStatus:          200,
+		UpstreamAddr:            "10.0.0.1:80",
+	}, "")
+
+	if sizes := m.Analyze().Groups[0].Sizes; sizes != nil {
+		t.Errorf("sizes = %+v without ResponseSizes", *sizes)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "RESPONSE SIZES") {
+		t.Errorf("response sizes section shown without ResponseSizes:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,31 @@
+package metric
+
+import "sort"
+
+// SLATier is the percentage of a group's requests under a latency threshold
+type SLATier struct {
+	Threshold float64
+	Percent   float64
+}
+
+// slaTiers returns the percentage of the latencies under each of the SLATiers
+func (m *MetricCollector) slaTiers(latencies []*LatencyMetric) []*SLATier {
+	if len(m.SLATiers) == 0 || len(latencies) == 0 {
+		return nil
+	}
+
+	sorted := sortedLatencies(latencies)
+	tiers := make([]*SLATier, len(m.SLATiers))
+
+	for i, threshold := range m.SLATiers {
+		// the index of the first latency at or above the threshold
+		under := sort.SearchFloat64s(sorted, threshold)
+
+		tiers[i] = &SLATier{
+			Threshold: threshold,
+			Percent:   100 * float64(under) / float64(len(sorted)),
+		}
+	}
+
+	return tiers
+}
@@ -0,0 +1,85 @@
+package metric
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestSLATiers(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.SLATiers = []float64{0.1, 0.3, 1}
+
+	// 2 requests under 100ms, 5 under 300ms, 9 under 1s and 1 over
+	latencies := []float64{0.05, 0.099, 0.1, 0.2, 0.25, 0.3, 0.5, 0.8, 0.999, 2}
+
+	for _, latency := range latencies {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			RequestTime:    latency,
+			UpstreamStatus: 200,
+			UpstreamAddr:   "10.0.0.1:80",
+		}, "")
+	}
+
+	report := m.Analyze()
+
+	if !report.SLATiers {
+		t.Error("SLATiers not set on the report")
+	}
+
+	got := make([]SLATier, 0)
+
+	for _, tier := range report.Groups[0].SLATiers {
+		got = append(got, *tier)
+	}
+
+	want := []SLATier{
+		{Threshold: 0.1, Percent: 20},
+		{Threshold: 0.3, Percent: 50},
+		{Threshold: 1, Percent: 90},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SLA tiers = %v, want %v", got, want)
+	}
+
+	var b strings.Builder
+
+	if err := m.WriteReport(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), "/a: <0.1s 20.00% <0.3s 50.00% <1s 90.00%") {
+		t.Errorf("SLA tiers missing from the report:\n%s", b.String())
+	}
+}
+
+func TestSLATiersHidden(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/a"},
+		RequestTime:    0.05,
+		UpstreamStatus: 200,
+		UpstreamAddr:   "10.0.0.1:80",
+	}, "")
+
+	report := m.Analyze()
+
+	if report.Groups[0].SLATiers != nil {
+		t.Errorf("SLA tiers = %v without --sla-tiers", report.Groups[0].SLATiers)
+	}
+
+	var b strings.Builder
+
+	if err := m.WriteReport(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(b.String(), "LATENCY SLA TIERS") {
+		t.Errorf("SLA tiers section shown without --sla-tiers:\n%s", b.String())
+	}
+}
@@ -0,0 +1,96 @@
+package metric
+
+import (
+	"sort"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// SlowClientConfig is the signature of slow client (slowloris style) requests
+type SlowClientConfig struct {
+	MinRequestTime float64
+	MaxBytes       int64
+}
+
+var DefaultSlowClientConfig = SlowClientConfig{
+	MinRequestTime: 10,
+	MaxBytes:       1024,
+}
+
+func (c *SlowClientConfig) matches(result *parser.NginxResult) bool {
+	return result.RequestTime >= c.MinRequestTime &&
+		result.RequestLength <= c.MaxBytes &&
+		result.BodyBytesSent <= c.MaxBytes
+}
+
+type slowClientKey struct {
+	group    string
+	clientIP string
+}
+
+type SlowClientCount struct {
+	Group    string
+	ClientIP string
+	Count    uint
+}
+
+// SlowClientsReport holds the groups and clients with slow client requests
+type SlowClientsReport struct {
+	Config  SlowClientConfig
+	Clients []*SlowClientCount
+}
+
+func (m *MetricCollector) addSlowClient(group string, result *parser.NginxResult) {
+	if m.SlowClients == nil || !m.SlowClients.matches(result) {
+		return
+	}
+
+	if m.slowClientData == nil {
+		m.slowClientData = make(map[slowClientKey]uint)
+	}
+
+	m.slowClientData[slowClientKey{group, result.ClientIP}]++
+}
+
+// mergeSlowClientGroup moves the slow client counts of group from to group to
+func (m *MetricCollector) mergeSlowClientGroup(from, to string) {
+	for key, count := range m.slowClientData {
+		if key.group != from {
+			continue
+		}
+
+		m.slowClientData[slowClientKey{to, key.clientIP}] += count
+		delete(m.slowClientData, key)
+	}
+}
+
+func (m *MetricCollector) slowClientsReport() *SlowClientsReport {
+	if m.SlowClients == nil {
+		return nil
+	}
+
+	report := &SlowClientsReport{
+		Config:  *m.SlowClients,
+		Clients: make([]*SlowClientCount, 0, len(m.slowClientData)),
+	}
+
+	for key, count := range m.slowClientData {
+		report.Clients = append(report.Clients, &SlowClientCount{key.group, key.clientIP, count})
+	}
+
+	sort.Slice(report.Clients, func(i, j int) bool {
+		a, b := report.Clients[i], report.Clients[j]
+
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+
+		return a.ClientIP < b.ClientIP
+	})
+
+	return report
+}
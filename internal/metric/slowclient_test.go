@@ -0,0 +1,174 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestSlowClientSignature(t *testing.T) {
+	config := SlowClientConfig{MinRequestTime: 10, MaxBytes: 1024}
+
+	tests := []struct {
+		name          string
+		requestTime   float64
+		requestLength int64
+		bodyBytesSent int64
+		want          bool
+	}{
+		{"slowloris", 30, 200, 0, true},
+		{"at the thresholds", 10, 1024, 1024, true},
+		{"fast", 0.2, 200, 0, false},
+		{"slow upload", 30, 50000, 0, false},
+		{"slow download", 30, 200, 50000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.SlowClients = &config
+
+			m.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: "/a"},
+				ClientIP:       "10.0.0.1",
+				RequestTime:    tt.requestTime,
+				RequestLength:  tt.requestLength,
+				BodyBytesSent:  tt.bodyBytesSent,
+				Status:         200,
+				UpstreamStatus: 200,
+				UpstreamAddr:   "10.0.0.1:80",
+			}, "")
+
+			report := m.Analyze().SlowClients
+
+			if got := len(report.Clients) == 1; got != tt.want {
+				t.Errorf("reported = %v, want %v", got, tt.want)
+			}
+
+			if report.Config != config {
+				t.Errorf("config = %+v, want %+v", report.Config, config)
+			}
+		})
+	}
+}
+
+func TestSlowClientsOrder(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.SlowClients = &DefaultSlowClientConfig
+
+	for _, ip := range []string{"10.0.0.2", "10.0.0.1", "10.0.0.2"} {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/a"},
+			ClientIP:       ip,
+			RequestTime:    30,
+			Status:         200,
+			UpstreamStatus: 200,
+			UpstreamAddr:   "10.0.0.1:80",
+		}, "")
+	}
+
+	clients := m.Analyze().SlowClients.Clients
+
+	want := []SlowClientCount{
+		{"/a", "10.0.0.2", 2},
+		{"/a", "10.0.0.1", 1},
+	}
+
+	if len(clients) != len(want) {
+		t.Fatalf("got %d slow clients, want %d", len(clients), len(want))
+	}
+
+	for i := range want {
+		if *clients[i] != want[i] {
+			t.Errorf("slow client %d = %+v, want %+v", i, *clients[i], want[i])
+		}
+	}
+}
+
+func TestSlowClientsDisabled(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/a"},
+		RequestTime:    30,
+		Status:         200,
+		UpstreamStatus: 200,
+		UpstreamAddr:   "10.0.0.1:80",
+	}, "")
+
+	if report := m.Analyze().SlowClients; report != nil {
+		t.Errorf("SlowClients = %+v, want nil", report)
+	}
+}
+
+func TestSlowClients(t *testing.T) {
+	type request struct {
+		path     string
+		clientIP string
+	}
+
+	tests := []struct {
+		name     string
+		cluster  bool
+		requests []request
+		want     []SlowClientCount
+	}{
+		{
+			name: "per path",
+			requests: []request{
+				{"/a/1", "10.0.0.1"},
+				{"/a/2", "10.0.0.1"},
+				{"/a/2", "10.0.0.2"},
+			},
+			want: []SlowClientCount{
+				{"/a/1", "10.0.0.1", 1},
+				{"/a/2", "10.0.0.1", 1},
+				{"/a/2", "10.0.0.2", 1},
+			},
+		},
+		{
+			name:    "clustered",
+			cluster: true,
+			requests: []request{
+				{"/a/1", "10.0.0.1"},
+				{"/a/2", "10.0.0.1"},
+				{"/a/2", "10.0.0.2"},
+			},
+			want: []SlowClientCount{
+				{"/a/*", "10.0.0.1", 2},
+				{"/a/*", "10.0.0.2", 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.SlowClients = &DefaultSlowClientConfig
+			m.ClusterPaths = tt.cluster
+
+			for _, req := range tt.requests {
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: req.path},
+					ClientIP:       req.clientIP,
+					RequestTime:    30,
+					Status:         200,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, "")
+			}
+
+			clients := m.Analyze().SlowClients.Clients
+
+			if len(clients) != len(tt.want) {
+				t.Fatalf("got %d slow clients, want %d", len(clients), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				if *clients[i] != want {
+					t.Errorf("slow client %d = %+v, want %+v", i, *clients[i], want)
+				}
+			}
+		})
+	}
+}
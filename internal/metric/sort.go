@@ -0,0 +1,58 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortKey is what the groups of the report are sorted by
+type SortKey string
+
+const (
+	SortByName        SortKey = "name"
+	SortByCount       SortKey = "count"
+	SortByErrorRate   SortKey = "error_rate"
+	SortByTimeoutRate SortKey = "timeout_rate"
+	SortByP95         SortKey = "p95"
+)
+
+var sortKeyValues = map[SortKey]func(group *GroupReport) float64{
+	SortByCount:       func(group *GroupReport) float64 { return float64(group.TimedOut.Total) },
+	SortByErrorRate:   func(group *GroupReport) float64 { return group.ErrorRate },
+	SortByTimeoutRate: func(group *GroupReport) float64 { return group.TimeoutRate },
+	SortByP95:         func(group *GroupReport) float64 { return group.P95Latency },
+}
+
+// ParseSortKey parses a sort key, returning an error for unknown keys
+func ParseSortKey(str string) (SortKey, error) {
+	key := SortKey(str)
+
+	if _, exists := sortKeyValues[key]; !exists && key != SortByName {
+		return "", fmt.Errorf("unknown sort key %s", str)
+	}
+
+	return key, nil
+}
+
+// sortGroups sorts groups already sorted by name by the key
+func sortGroups(groups []*GroupReport, key SortKey, descending bool) {
+	if key == SortByName || key == "" {
+		if descending {
+			for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+				groups[i], groups[j] = groups[j], groups[i]
+			}
+		}
+
+		return
+	}
+
+	value := sortKeyValues[key]
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if descending {
+			return value(groups[i]) > value(groups[j])
+		}
+
+		return value(groups[i]) < value(groups[j])
+	})
+}
@@ -0,0 +1,115 @@
+package metric
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// addSortFixture adds groups that each sort key orders differently
+func addSortFixture(m *MetricCollector) {
+	requests := []struct {
+		path     string
+		latency  float64
+		status   int64
+		timedOut bool
+	}{
+		// most requests, lowest p95
+		{"/a", 0.1, 200, false},
+		{"/a", 0.1, 200, false},
+		{"/a", 0.1, 200, false},
+		{"/a", 0.1, 502, false},
+		// highest error rate
+		{"/b", 0.5, 500, false},
+		{"/b", 0.5, 200, false},
+		// highest timeout rate and p95
+		{"/c", 0, 504, true},
+		{"/c", 2, 200, false},
+		{"/c", 0, 504, true},
+	}
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    req.latency,
+			UpstreamStatus: req.status,
+			UpstreamAddr:   "10.0.0.1:80",
+			TimedOut:       req.timedOut,
+		}, "")
+	}
+}
+
+func TestSortGroups(t *testing.T) {
+	tests := []struct {
+		key        SortKey
+		descending bool
+		want       []string
+	}{
+		{"", false, []string{"/a", "/b", "/c"}},
+		{SortByName, false, []string{"/a", "/b", "/c"}},
+		{SortByName, true, []string{"/c", "/b", "/a"}},
+		{SortByCount, true, []string{"/a", "/c", "/b"}},
+		{SortByCount, false, []string{"/b", "/c", "/a"}},
+		{SortByErrorRate, true, []string{"/c", "/b", "/a"}},
+		{SortByTimeoutRate, true, []string{"/c", "/a", "/b"}},
+		{SortByP95, true, []string{"/c", "/b", "/a"}},
+		{SortByP95, false, []string{"/a", "/b", "/c"}},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.key)
+
+		if tt.descending {
+			name += " desc"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.SortKey = tt.key
+			m.SortDescending = tt.descending
+			addSortFixture(m)
+
+			got := make([]string, 0)
+
+			for _, group := range m.Analyze().Groups {
+				got = append(got, group.Key)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortGroupsSummaryCSV(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.SortKey = SortByCount
+	m.SortDescending = true
+	addSortFixture(m)
+
+	var buf bytes.Buffer
+
+	if err := m.WriteSummaryCSV(&buf, []string{"count"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "group,count\n/a,4\n/c,3\n/b,2\n"; buf.String() != want {
+		t.Errorf("summary CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	for _, key := range []string{"name", "count", "error_rate", "timeout_rate", "p95"} {
+		if _, err := ParseSortKey(key); err != nil {
+			t.Errorf("ParseSortKey(%q) error = %v", key, err)
+		}
+	}
+
+	if _, err := ParseSortKey("latency"); err == nil || !strings.Contains(err.Error(), "unknown sort key latency") {
+		t.Errorf("ParseSortKey(\"latency\") error = %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package metric
+
+import "strings"
+
+const sparklineBuckets = 10
+
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// histogram counts latencies into numBuckets equal-width buckets
+func histogram(latencies []*LatencyMetric, numBuckets int) []uint {
+	counts := make([]uint, numBuckets)
+
+	var maxLatency float64
+
+	for _, latency := range latencies {
+		if latency.latency > maxLatency {
+			maxLatency = latency.latency
+		}
+	}
+
+	for _, latency := range latencies {
+		i := 0
+
+		if maxLatency > 0 && latency.latency > 0 {
+			i = int(latency.latency / maxLatency * float64(numBuckets))
+		}
+
+		// the largest latency falls on the upper bound of the last bucket
+		if i >= numBuckets {
+			i = numBuckets - 1
+		}
+
+		counts[i]++
+	}
+
+	return counts
+}
+
+// sparkline renders the counts as unicode block characters
+func sparkline(counts []uint) string {
+	var maxCount uint
+
+	for _, count := range counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	var sb strings.Builder
+
+	for _, count := range counts {
+		if count == 0 {
+			sb.WriteRune(' ')
+			continue
+		}
+
+		level := int(count * uint(len(sparklineBlocks)-1) / maxCount)
+		sb.WriteRune(sparklineBlocks[level])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	latencies := make([]*LatencyMetric, 0)
+
+	for _, latency := range []float64{0, 0.15, 0.15, 0.15, 0.15, 0.55, 1} {
+		latencies = append(latencies, &LatencyMetric{latency: latency})
+	}
+
+	// the largest latency lands in the last bucket rather than past it
+	want := []uint{1, 4, 0, 0, 0, 1, 0, 0, 0, 1}
+
+	if got := histogram(latencies, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("histogram = %v, want %v", got, want)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []uint
+		want   string
+	}{
+		{"known distribution", []uint{1, 4, 0, 0, 0, 1, 0, 0, 0, 1}, "▂█   ▂   ▂"},
+		{"rising", []uint{1, 2, 3, 4, 5, 6, 7, 8}, "▁▂▃▄▅▆▇█"},
+		{"empty", []uint{0, 0, 0}, "   "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparkline(tt.counts); got != tt.want {
+				t.Errorf("sparkline = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package metric
+
+import (
+	"fmt"
+	"net"
+)
+
+// DefaultSubnetPrefixIPv4 and DefaultSubnetPrefixIPv6 are the default subnet prefixes
+const (
+	DefaultSubnetPrefixIPv4 = 24
+	DefaultSubnetPrefixIPv6 = 64
+)
+
+// groupIP returns the group key of an address, masked to the subnet prefix if set
+func (m *MetricCollector) groupIP(addr string) string {
+	if addr == "" {
+		return groupNone
+	}
+
+	if m.SubnetPrefixIPv4 <= 0 && m.SubnetPrefixIPv6 <= 0 {
+		return addr
+	}
+
+	host := addr
+
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+
+	if ip == nil {
+		return addr
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return maskIP(ip4, m.SubnetPrefixIPv4, 32, addr)
+	}
+
+	return maskIP(ip, m.SubnetPrefixIPv6, 128, addr)
+}
+
+// maskIP returns the subnet of the IP in CIDR notation, or addr without a prefix
+func maskIP(ip net.IP, prefix, bits int, addr string) string {
+	if prefix <= 0 {
+		return addr
+	}
+
+	return fmt.Sprintf("%s/%d", ip.Mask(net.CIDRMask(prefix, bits)), prefix)
+}
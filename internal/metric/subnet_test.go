@@ -0,0 +1,71 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestGroupIP(t *testing.T) {
+	tests := []struct {
+		name string
+		ipv4 int
+		ipv6 int
+		addr string
+		want string
+	}{
+		{name: "unmasked", addr: "10.1.2.3", want: "10.1.2.3"},
+		{name: "ipv4 /24", ipv4: 24, ipv6: 64, addr: "10.1.2.3", want: "10.1.2.0/24"},
+		{name: "ipv4 /16", ipv4: 16, ipv6: 64, addr: "10.1.2.3", want: "10.1.0.0/16"},
+		{name: "ipv4 /32", ipv4: 32, ipv6: 64, addr: "10.1.2.3", want: "10.1.2.3/32"},
+		{name: "ipv4 with a port", ipv4: 24, ipv6: 64, addr: "10.1.2.3:8080", want: "10.1.2.0/24"},
+		{name: "ipv6 /64", ipv4: 24, ipv6: 64, addr: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1:2::/64"},
+		{name: "ipv6 /48", ipv4: 24, ipv6: 48, addr: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1::/48"},
+		{name: "ipv6 with a port", ipv4: 24, ipv6: 64, addr: "[2001:db8:1:2::1]:443", want: "2001:db8:1:2::/64"},
+		{name: "ipv4-mapped ipv6", ipv4: 24, ipv6: 64, addr: "::ffff:10.1.2.3", want: "10.1.2.0/24"},
+		{name: "only ipv6 masked", ipv6: 64, addr: "10.1.2.3", want: "10.1.2.3"},
+		{name: "not an ip", ipv4: 24, ipv6: 64, addr: "upstream.local:80", want: "upstream.local:80"},
+		{name: "empty", ipv4: 24, ipv6: 64, addr: "", want: groupNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindClientIP, MetricKindLatency)
+			m.SubnetPrefixIPv4 = tt.ipv4
+			m.SubnetPrefixIPv6 = tt.ipv6
+
+			if got := m.groupIP(tt.addr); got != tt.want {
+				t.Errorf("groupIP(%q) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSubnetGroups(t *testing.T) {
+	m := NewMetricCollector(GroupKindClientIP, MetricKindLatency)
+	m.SubnetPrefixIPv4 = DefaultSubnetPrefixIPv4
+	m.SubnetPrefixIPv6 = DefaultSubnetPrefixIPv6
+
+	for _, client := range []string{"10.0.0.1", "10.0.0.200", "10.0.1.1", "2001:db8::1", "2001:db8::ffff"} {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			ClientIP:       client,
+			RequestTime:    0.1,
+			Status:         200,
+			UpstreamStatus: 200,
+		}, "")
+	}
+
+	got := make(map[string]int)
+
+	for _, group := range m.Analyze().Groups {
+		got[group.Key] = group.LatencyCount
+	}
+
+	want := map[string]int{"10.0.0.0/24": 2, "10.0.1.0/24": 1, "2001:db8::/64": 2}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,209 @@
+package metric
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+type TalkerCount struct {
+	ClientIP string
+	Count    uint
+}
+
+// talkerCounter counts requests per client, with the space-saving algorithm if capped
+type talkerCounter struct {
+	capacity int
+	entries  map[string]*talkerEntry
+	// least is a min-heap of the entries by count
+	least talkerHeap
+}
+
+type talkerEntry struct {
+	clientIP string
+	count    uint
+	index    int
+}
+
+// talkerHeap is a min-heap of talker entries by count, with ties in client IP order
+type talkerHeap []*talkerEntry
+
+func (h talkerHeap) Len() int { return len(h) }
+
+func (h talkerHeap) Less(i, j int) bool {
+	if h[i].count == h[j].count {
+		return h[i].clientIP < h[j].clientIP
+	}
+
+	return h[i].count < h[j].count
+}
+
+func (h talkerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *talkerHeap) Push(x any) {
+	entry := x.(*talkerEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *talkerHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+func newTalkerCounter(capacity int) *talkerCounter {
+	return &talkerCounter{
+		capacity: capacity,
+		entries:  make(map[string]*talkerEntry),
+	}
+}
+
+func (t *talkerCounter) add(clientIP string) {
+	if entry, exists := t.entries[clientIP]; exists {
+		entry.count++
+		heap.Fix(&t.least, entry.index)
+		return
+	}
+
+	if t.capacity <= 0 || len(t.entries) < t.capacity {
+		t.insert(clientIP, 1)
+		return
+	}
+
+	entry := t.least[0]
+	delete(t.entries, entry.clientIP)
+
+	entry.clientIP = clientIP
+	entry.count++
+	t.entries[clientIP] = entry
+	heap.Fix(&t.least, 0)
+}
+
+func (t *talkerCounter) insert(clientIP string, count uint) {
+	entry := &talkerEntry{clientIP: clientIP, count: count}
+	t.entries[clientIP] = entry
+	heap.Push(&t.least, entry)
+}
+
+// counts returns the number of requests of every tracked client
+func (t *talkerCounter) counts() map[string]uint {
+	res := make(map[string]uint, len(t.entries))
+
+	for ip, entry := range t.entries {
+		res[ip] = entry.count
+	}
+
+	return res
+}
+
+// merge adds the counts of other, keeping at most capacity clients
+func (t *talkerCounter) merge(other *talkerCounter) {
+	for ip, entry := range other.entries {
+		if existing, exists := t.entries[ip]; exists {
+			existing.count += entry.count
+			heap.Fix(&t.least, existing.index)
+		} else {
+			t.insert(ip, entry.count)
+		}
+	}
+
+	if t.capacity > 0 {
+		t.trim(t.capacity)
+	}
+}
+
+// trim bounds the counter to capacity clients, keeping the most frequent ones
+func (t *talkerCounter) trim(capacity int) {
+	t.capacity = capacity
+
+	if len(t.entries) <= capacity {
+		return
+	}
+
+	top := t.top(capacity)
+
+	t.entries = make(map[string]*talkerEntry, capacity)
+	t.least = nil
+
+	for _, talker := range top {
+		t.insert(talker.ClientIP, talker.Count)
+	}
+}
+
+// top returns the n clients with the most requests, most requests first
+func (t *talkerCounter) top(n int) []*TalkerCount {
+	res := make([]*TalkerCount, 0, len(t.entries))
+
+	for ip, entry := range t.entries {
+		res = append(res, &TalkerCount{ip, entry.count})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count == res[j].Count {
+			return res[i].ClientIP < res[j].ClientIP
+		}
+
+		return res[i].Count > res[j].Count
+	})
+
+	if len(res) > n {
+		res = res[:n]
+	}
+
+	return res
+}
+
+// TalkersReport holds the clients with the most requests, 4XX and 5XX responses
+type TalkersReport struct {
+	All          []*TalkerCount
+	ClientErrors []*TalkerCount
+	ServerErrors []*TalkerCount
+}
+
+func (m *MetricCollector) addTalker(result *parser.NginxResult) {
+	clientIP := result.ClientIP
+
+	if m.Talkers <= 0 || clientIP == "" {
+		return
+	}
+
+	if m.talkersData == nil {
+		m.talkersData = map[string]*talkerCounter{
+			"all": newTalkerCounter(m.TalkersCapacity),
+			"4xx": newTalkerCounter(m.TalkersCapacity),
+			"5xx": newTalkerCounter(m.TalkersCapacity),
+		}
+	}
+
+	m.talkersData["all"].add(clientIP)
+
+	if result.IsClientError() {
+		m.talkersData["4xx"].add(clientIP)
+	} else if result.IsError() {
+		m.talkersData["5xx"].add(clientIP)
+	}
+}
+
+func (m *MetricCollector) talkersReport() *TalkersReport {
+	if m.Talkers <= 0 {
+		return nil
+	}
+
+	if m.talkersData == nil {
+		return &TalkersReport{}
+	}
+
+	return &TalkersReport{
+		All:          m.talkersData["all"].top(m.Talkers),
+		ClientErrors: m.talkersData["4xx"].top(m.Talkers),
+		ServerErrors: m.talkersData["5xx"].top(m.Talkers),
+	}
+}
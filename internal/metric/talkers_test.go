@@ -0,0 +1,141 @@
+package metric
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// addClients adds a request with the status for each client IP to the collector
+func addClients(m *MetricCollector, status int64, clientIPs ...string) {
+	for _, clientIP := range clientIPs {
+		m.AddLine(&parser.NginxResult{
+			ClientIP:       clientIP,
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			RequestTime:    0.1,
+			UpstreamStatus: status,
+		}, "")
+	}
+}
+
+func TestTalkers(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Talkers = 2
+
+	addClients(m, 200, "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.3", "10.0.0.1")
+	addClients(m, 404, "10.0.0.2", "10.0.0.2", "10.0.0.4")
+	addClients(m, 502, "10.0.0.3")
+
+	want := &TalkersReport{
+		All: []*TalkerCount{
+			{"10.0.0.1", 3},
+			{"10.0.0.2", 3},
+		},
+		ClientErrors: []*TalkerCount{
+			{"10.0.0.2", 2},
+			{"10.0.0.4", 1},
+		},
+		ServerErrors: []*TalkerCount{
+			{"10.0.0.3", 1},
+		},
+	}
+
+	if got := m.Analyze().Talkers; !reflect.DeepEqual(got, want) {
+		t.Errorf("talkers = %+v, want %+v", got, want)
+	}
+}
+
+func TestTalkersTimedOut(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+	m.Talkers = 2
+
+	// a timed out request is a server error whatever the status nginx logged
+	for _, status := range []int64{200, 404, parser.StatusNoResponse} {
+		m.AddLine(&parser.NginxResult{
+			ClientIP:       "10.0.0.1",
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			UpstreamStatus: status,
+			TimedOut:       true,
+		}, "")
+	}
+
+	talkers := m.Analyze().Talkers
+
+	if len(talkers.ClientErrors) != 0 {
+		t.Errorf("client errors = %+v, want none", talkers.ClientErrors)
+	}
+
+	want := []*TalkerCount{{"10.0.0.1", 3}}
+
+	if !reflect.DeepEqual(talkers.ServerErrors, want) {
+		t.Errorf("server errors = %+v, want %+v", talkers.ServerErrors, want)
+	}
+}
+
+func TestTalkersDisabled(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	addClients(m, 200, "10.0.0.1")
+
+	if got := m.Analyze().Talkers; got != nil {
+		t.Errorf("talkers = %+v without Talkers set", got)
+	}
+}
+
+func TestTalkerCounterCapacity(t *testing.T) {
+	counter := newTalkerCounter(2)
+
+	for _, clientIP := range []string{"a", "a", "a", "b", "c", "a", "d"} {
+		counter.add(clientIP)
+	}
+
+	if len(counter.entries) != 2 {
+		t.Errorf("tracked %d clients, over the capacity of 2", len(counter.entries))
+	}
+
+	// c replaced b and d replaced c, inheriting their counts as upper bounds
+	want := []*TalkerCount{
+		{"a", 4},
+		{"d", 3},
+	}
+
+	if got := counter.top(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("top = %+v, want %+v", got, want)
+	}
+}
+
+func TestTalkerCounterMatchesScan(t *testing.T) {
+	counter := newTalkerCounter(5)
+	counts := make(map[string]uint)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		clientIP := fmt.Sprintf("10.0.0.%d", r.Intn(20))
+		counter.add(clientIP)
+
+		// the space-saving algorithm, scanning for the least frequent client
+		if _, exists := counts[clientIP]; exists || len(counts) < 5 {
+			counts[clientIP]++
+			continue
+		}
+
+		var minClientIP string
+		var minCount uint
+
+		for ip, count := range counts {
+			if minClientIP == "" || count < minCount || (count == minCount && ip < minClientIP) {
+				minClientIP, minCount = ip, count
+			}
+		}
+
+		delete(counts, minClientIP)
+		counts[clientIP] = minCount + 1
+	}
+
+	if got := counter.counts(); !reflect.DeepEqual(got, counts) {
+		t.Errorf("counts = %v, want %v", got, counts)
+	}
+}
@@ -0,0 +1,32 @@
+package metric
+
+import "sort"
+
+// topErrors returns the TopErrors groups with the most 5XX responses
+func (m *MetricCollector) topErrors(groups []*GroupReport) []*GroupReport {
+	if m.TopErrors <= 0 {
+		return nil
+	}
+
+	res := make([]*GroupReport, 0, len(groups))
+
+	for _, group := range groups {
+		if group.ServerErrors > 0 {
+			res = append(res, group)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].ServerErrors == res[j].ServerErrors {
+			return res[i].Key < res[j].Key
+		}
+
+		return res[i].ServerErrors > res[j].ServerErrors
+	})
+
+	if len(res) > m.TopErrors {
+		res = res[:m.TopErrors]
+	}
+
+	return res
+}
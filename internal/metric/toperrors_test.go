@@ -0,0 +1,72 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// addErrorCounts adds ok results with a 200 status and errors with a 502 status to the group
+func addErrorCounts(m *MetricCollector, group string, ok, errors int) {
+	for i := 0; i < ok+errors; i++ {
+		status := int64(200)
+
+		if i >= ok {
+			status = 502
+		}
+
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: group},
+			RequestTime:    0.1,
+			Status:         status,
+			UpstreamStatus: status,
+		}, "")
+	}
+}
+
+func TestTopErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		topErrors       int
+		maxReportGroups int
+		want            []string
+	}{
+		{name: "disabled"},
+		{name: "top 2", topErrors: 2, want: []string{"/busy", "/broken"}},
+		{name: "all", topErrors: 10, want: []string{"/busy", "/broken", "/flaky", "/quiet"}},
+		{name: "groups left out of the report", topErrors: 10, maxReportGroups: 1, want: []string{"/busy", "/broken", "/flaky", "/quiet"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.TopErrors = tt.topErrors
+			m.MaxReportGroups = tt.maxReportGroups
+
+			// ordered by 5XX count whatever the error rate, ties by key
+			addErrorCounts(m, "/busy", 94, 6)
+			addErrorCounts(m, "/broken", 0, 5)
+			addErrorCounts(m, "/flaky", 7, 3)
+			addErrorCounts(m, "/quiet", 47, 3)
+			addErrorCounts(m, "/healthy", 200, 0)
+
+			report := m.Analyze()
+			var got []string
+
+			for _, group := range report.TopErrors {
+				got = append(got, group.Key)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("top errors = %q, want %q", got, tt.want)
+			}
+
+			for _, group := range report.TopErrors {
+				if group.Key == "/broken" && (group.ServerErrors != 5 || group.ErrorRate != 1) {
+					t.Errorf("/broken has %d 5XX responses at rate %g, want 5 at rate 1", group.ServerErrors, group.ErrorRate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package metric
+
+import "time"
+
+// DefaultTrendFlatThreshold is the default slope, in seconds per minute, reported as flat
+const DefaultTrendFlatThreshold = 0.001
+
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// trendSums holds the least squares sums of latencies over minutes since origin
+type trendSums struct {
+	origin time.Time
+	n      float64
+	sumX   float64
+	sumY   float64
+	sumXY  float64
+	sumXX  float64
+}
+
+func (s *trendSums) add(t time.Time, latency float64) {
+	if t.IsZero() {
+		return
+	}
+
+	if s.origin.IsZero() {
+		s.origin = t
+	}
+
+	x := t.Sub(s.origin).Minutes()
+
+	s.n++
+	s.sumX += x
+	s.sumY += latency
+	s.sumXY += x * latency
+	s.sumXX += x * x
+}
+
+// merge adds the sums of other, shifting its x values to the origin of s
+func (s *trendSums) merge(other *trendSums) {
+	if other.n == 0 {
+		return
+	}
+
+	if s.n == 0 {
+		*s = *other
+		return
+	}
+
+	d := other.origin.Sub(s.origin).Minutes()
+
+	s.sumXX += other.sumXX + 2*d*other.sumX + other.n*d*d
+	s.sumXY += other.sumXY + d*other.sumY
+	s.sumX += other.sumX + other.n*d
+	s.sumY += other.sumY
+	s.n += other.n
+}
+
+// slope returns the fitted slope in seconds per minute, or false if there's no trend
+func (s *trendSums) slope() (float64, bool) {
+	if s.n < 2 {
+		return 0, false
+	}
+
+	denominator := s.n*s.sumXX - s.sumX*s.sumX
+
+	// shifted sums of a single point in time only cancel out up to rounding
+	if denominator <= s.n*s.n*1e-9 {
+		return 0, false
+	}
+
+	return (s.n*s.sumXY - s.sumX*s.sumY) / denominator, true
+}
+
+func (m *MetricCollector) trendDirection(slope float64) string {
+	threshold := m.TrendFlatThreshold
+
+	switch {
+	case slope > threshold:
+		return TrendUp
+	case slope < -threshold:
+		return TrendDown
+	}
+
+	return TrendFlat
+}
@@ -0,0 +1,143 @@
+package metric
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestLatencyTrend(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		latencies     []float64
+		wantDirection string
+		wantSlope     float64
+	}{
+		{"increasing", []float64{0.1, 0.2, 0.3, 0.4, 0.5}, TrendUp, 0.1},
+		{"decreasing", []float64{0.5, 0.4, 0.3, 0.2, 0.1}, TrendDown, -0.1},
+		{"flat", []float64{0.2, 0.2, 0.2, 0.2, 0.2}, TrendFlat, 0},
+		{"within the flat threshold", []float64{0.2, 0.2005, 0.2, 0.2005, 0.2}, TrendFlat, 0},
+		{"single request", []float64{0.2}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Trends = true
+
+			// one request a minute, so the slope is the latency change per request
+			for i, latency := range tt.latencies {
+				m.AddLine(&parser.NginxResult{
+					TimeLocal:      start.Add(time.Duration(i) * time.Minute),
+					Request:        &parser.Request{Method: "GET", Path: "/"},
+					RequestTime:    latency,
+					UpstreamStatus: 200,
+				}, "")
+			}
+
+			group := m.Analyze().Groups[0]
+
+			if group.TrendDirection != tt.wantDirection {
+				t.Errorf("direction = %q, want %q", group.TrendDirection, tt.wantDirection)
+			}
+
+			if math.Abs(group.LatencyTrend-tt.wantSlope) > 1e-3 {
+				t.Errorf("slope = %g, want %g", group.LatencyTrend, tt.wantSlope)
+			}
+		})
+	}
+}
+
+func TestLatencyTrendSameTime(t *testing.T) {
+	at := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	var trend trendSums
+
+	trend.add(at, 0.1)
+	trend.add(at, 0.5)
+
+	if _, ok := trend.slope(); ok {
+		t.Error("expected no trend for latencies at a single point in time")
+	}
+}
+
+func TestLatencyTrendSampled(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		shards        int
+		latency       func(i int) float64
+		wantDirection string
+		wantSlope     float64
+	}{
+		// alternating latencies, so a sample of them can fit any slope
+		{"flat", 1, func(i int) float64 { return 0.1 + 0.4*float64(i%2) }, TrendFlat, 0},
+		{"flat merged shards", 4, func(i int) float64 { return 0.1 + 0.4*float64(i%2) }, TrendFlat, 0},
+		{"increasing merged shards", 4, func(i int) float64 { return 0.01 * float64(i) }, TrendUp, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Trends = true
+			m.Reservoir = 10
+
+			for shard := 0; shard < tt.shards; shard++ {
+				s := m.Shard()
+
+				// one request a minute
+				for i := shard * 1000 / tt.shards; i < (shard+1)*1000/tt.shards; i++ {
+					s.AddLine(&parser.NginxResult{
+						TimeLocal:      start.Add(time.Duration(i) * time.Minute),
+						Request:        &parser.Request{Method: "GET", Path: "/"},
+						RequestTime:    tt.latency(i),
+						UpstreamStatus: 200,
+					}, "")
+				}
+
+				m.Merge(s)
+			}
+
+			group := m.Analyze().Groups[0]
+
+			if group.TrendDirection != tt.wantDirection {
+				t.Errorf("direction = %q, want %q", group.TrendDirection, tt.wantDirection)
+			}
+
+			if math.Abs(group.LatencyTrend-tt.wantSlope) > 1e-5 {
+				t.Errorf("slope = %g, want %g", group.LatencyTrend, tt.wantSlope)
+			}
+		})
+	}
+}
+
+func TestLatencyTrendHidden(t *testing.T) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for i := 0; i < 3; i++ {
+		m.AddLine(&parser.NginxResult{
+			TimeLocal:      start.Add(time.Duration(i) * time.Minute),
+			Request:        &parser.Request{Method: "GET", Path: "/"},
+			RequestTime:    float64(i),
+			UpstreamStatus: 200,
+		}, "")
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "LATENCY TRENDS") {
+		t.Errorf("report shows latency trends without Trends set:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+package metric
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// upstreamNone is the upstream of results without an upstream address
+const upstreamNone = "(none)"
+
+// UpstreamTimeouts is the number of timed out requests of an upstream address
+type UpstreamTimeouts struct {
+	Addr     string
+	TimedOut TimedOutMetric
+	// Share is the upstream's percentage of every timeout, Percent of its own requests
+	Share   float64
+	Percent float64
+}
+
+// lastUpstreamAddr returns the address of the last upstream attempt
+func lastUpstreamAddr(addrs string) string {
+	// nginx separates upstream servers by commas, and internal redirects by colons
+	for _, sep := range []string{", ", " : "} {
+		if i := strings.LastIndex(addrs, sep); i >= 0 {
+			addrs = addrs[i+len(sep):]
+		}
+	}
+
+	if addr := strings.TrimSpace(addrs); addr != "" {
+		return addr
+	}
+
+	return upstreamNone
+}
+
+func (m *MetricCollector) addUpstreamTimeout(result *parser.NginxResult) {
+	if m.upstreamTimeoutData == nil {
+		m.upstreamTimeoutData = make(map[string]TimedOutMetric)
+	}
+
+	addr := lastUpstreamAddr(result.UpstreamAddr)
+	timedOut := m.upstreamTimeoutData[addr]
+	timedOut.Total++
+
+	if result.TimedOut {
+		timedOut.Count++
+	}
+
+	m.upstreamTimeoutData[addr] = timedOut
+}
+
+// upstreamTimeouts returns the upstreams with timeouts, most timeouts first
+func (m *MetricCollector) upstreamTimeouts() []*UpstreamTimeouts {
+	res := make([]*UpstreamTimeouts, 0)
+	total := 0
+
+	for _, timedOut := range m.upstreamTimeoutData {
+		total += timedOut.Count
+	}
+
+	for addr, timedOut := range m.upstreamTimeoutData {
+		if timedOut.Count == 0 {
+			continue
+		}
+
+		res = append(res, &UpstreamTimeouts{
+			Addr:     addr,
+			TimedOut: timedOut,
+			Share:    100 * float64(timedOut.Count) / float64(total),
+			Percent:  100 * float64(timedOut.Count) / float64(timedOut.Total),
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].TimedOut.Count == res[j].TimedOut.Count {
+			return res[i].Addr < res[j].Addr
+		}
+
+		return res[i].TimedOut.Count > res[j].TimedOut.Count
+	})
+
+	return res
+}
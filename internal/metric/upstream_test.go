@@ -0,0 +1,108 @@
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestLastUpstreamAddr(t *testing.T) {
+	tests := []struct {
+		addrs string
+		want  string
+	}{
+		{"10.1.0.5:8080", "10.1.0.5:8080"},
+		{"10.1.0.5:8080, 10.1.0.6:8080", "10.1.0.6:8080"},
+		{"10.1.0.5:8080 : 10.1.0.7:8080", "10.1.0.7:8080"},
+		{"10.1.0.5:8080, 10.1.0.6:8080 : 10.1.0.7:8080", "10.1.0.7:8080"},
+		{"", upstreamNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addrs, func(t *testing.T) {
+			if got := lastUpstreamAddr(tt.addrs); got != tt.want {
+				t.Errorf("lastUpstreamAddr(%q) = %q, want %q", tt.addrs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamTimeouts(t *testing.T) {
+	requests := []struct {
+		path     string
+		addr     string
+		timedOut bool
+	}{
+		// the timeouts concentrate on one pod, across paths
+		{"/a", "10.1.0.5:8080", true},
+		{"/b", "10.1.0.5:8080", true},
+		{"/c", "10.1.0.5:8080", true},
+		{"/a", "10.1.0.5:8080", false},
+		{"/a", "10.1.0.6:8080", false},
+		{"/b", "10.1.0.6:8080", false},
+		// retried on another pod, which timed out too
+		{"/a", "10.1.0.6:8080, 10.1.0.7:8080", true},
+	}
+
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	for _, req := range requests {
+		m.AddLine(&parser.NginxResult{
+			Request:        &parser.Request{Method: "GET", Path: req.path},
+			RequestTime:    0.1,
+			UpstreamStatus: 200,
+			UpstreamAddr:   req.addr,
+			TimedOut:       req.timedOut,
+		}, "")
+	}
+
+	want := []UpstreamTimeouts{
+		{"10.1.0.5:8080", TimedOutMetric{Count: 3, Total: 4}, 75, 75},
+		{"10.1.0.7:8080", TimedOutMetric{Count: 1, Total: 1}, 25, 100},
+	}
+
+	got := m.Analyze().UpstreamTimeouts
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d upstreams, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("upstream %d = %+v, want %+v", i, *got[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "10.1.0.5:8080: 3 (75.00% of time outs, 75.00% of its 4 requests)") {
+		t.Errorf("upstream timeouts missing from the report:\n%s", buf.String())
+	}
+}
+
+func TestUpstreamTimeoutsHidden(t *testing.T) {
+	m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+
+	m.AddLine(&parser.NginxResult{
+		Request:        &parser.Request{Method: "GET", Path: "/a"},
+		RequestTime:    0.1,
+		UpstreamStatus: 200,
+		UpstreamAddr:   "10.1.0.5:8080",
+	}, "")
+
+	var buf bytes.Buffer
+
+	if err := m.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "TIME OUTS BY UPSTREAM") {
+		t.Errorf("upstream timeouts section shown without timeouts:\n%s", buf.String())
+	}
+}
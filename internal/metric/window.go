@@ -0,0 +1,123 @@
+package metric
+
+import (
+	"sort"
+	"time"
+)
+
+// latencyWindow holds the latencies of a group within the latest Window, oldest first
+type latencyWindow struct {
+	latencies []*LatencyMetric
+}
+
+func (w *latencyWindow) add(latency *LatencyMetric) {
+	w.latencies = append(w.latencies, latency)
+}
+
+// evict drops the latencies logged before cutoff
+func (w *latencyWindow) evict(cutoff time.Time) {
+	i := 0
+
+	for i < len(w.latencies) && w.latencies[i].time.Before(cutoff) {
+		i++
+	}
+
+	if i == 0 {
+		return
+	}
+
+	// copy the remaining latencies so the evicted ones can be garbage collected
+	if i > len(w.latencies)/2 {
+		w.latencies = append([]*LatencyMetric(nil), w.latencies[i:]...)
+	} else {
+		w.latencies = w.latencies[i:]
+	}
+}
+
+func (w *latencyWindow) sortByTime() {
+	sort.SliceStable(w.latencies, func(i, j int) bool {
+		return w.latencies[i].time.Before(w.latencies[j].time)
+	})
+}
+
+// WindowPercentiles are the latency percentiles of a group over the recent window
+type WindowPercentiles struct {
+	Count int
+	P95   float64
+	P99   float64
+}
+
+func (m *MetricCollector) addToWindow(group string, latency *LatencyMetric) {
+	if m.Window <= 0 {
+		return
+	}
+
+	if m.windowData == nil {
+		m.windowData = make(map[string]*latencyWindow)
+	}
+
+	if latency.time.After(m.latest) {
+		m.latest = latency.time
+	}
+
+	window, exists := m.windowData[group]
+
+	if !exists {
+		window = &latencyWindow{}
+		m.windowData[group] = window
+	}
+
+	window.add(latency)
+	window.evict(m.latest.Add(-m.Window))
+}
+
+// windowPercentiles returns the percentiles of the group over the latest window, or nil
+func (m *MetricCollector) windowPercentiles(group string) *WindowPercentiles {
+	window, exists := m.windowData[group]
+
+	if !exists {
+		return nil
+	}
+
+	// groups that stopped getting requests haven't evicted their old latencies yet
+	window.evict(m.latest.Add(-m.Window))
+
+	if len(window.latencies) == 0 {
+		return nil
+	}
+
+	sorted := sortedLatencies(window.latencies)
+
+	return &WindowPercentiles{
+		Count: len(sorted),
+		P95:   m.percentile(sorted, 95),
+		P99:   m.percentile(sorted, 99),
+	}
+}
+
+// mergeWindows merges the windows of other, keeping them in time order
+func (m *MetricCollector) mergeWindows(other *MetricCollector) {
+	if other.latest.After(m.latest) {
+		m.latest = other.latest
+	}
+
+	for group, window := range other.windowData {
+		if m.windowData == nil {
+			m.windowData = make(map[string]*latencyWindow)
+		}
+
+		toWindow, exists := m.windowData[group]
+
+		if !exists {
+			toWindow = &latencyWindow{}
+			m.windowData[group] = toWindow
+		}
+
+		toWindow.latencies = append(toWindow.latencies, window.latencies...)
+		toWindow.sortByTime()
+	}
+
+	for _, window := range m.windowData {
+		window.evict(m.latest.Add(-m.Window))
+	}
+}
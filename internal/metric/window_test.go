@@ -0,0 +1,142 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// windowStep adds count results of a group logged at the offset from the start of the log
+type windowStep struct {
+	at      time.Duration
+	group   string
+	latency float64
+	count   int
+}
+
+func addWindowSteps(m *MetricCollector, steps []windowStep) {
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	for _, step := range steps {
+		for i := 0; i < step.count; i++ {
+			m.AddLine(&parser.NginxResult{
+				Request:        &parser.Request{Method: "GET", Path: step.group},
+				TimeLocal:      start.Add(step.at),
+				RequestTime:    step.latency,
+				Status:         200,
+				UpstreamStatus: 200,
+			}, "")
+		}
+	}
+}
+
+func TestWindowEviction(t *testing.T) {
+	type want struct {
+		// count is the number of latencies in the window, which is 0 if the group has none
+		count     int
+		windowP99 float64
+		allP99    float64
+	}
+
+	tests := []struct {
+		name   string
+		steps  []windowStep
+		shards [][]windowStep
+		want   map[string]want
+	}{
+		{
+			name: "within the window",
+			steps: []windowStep{
+				{at: 0, group: "/a", latency: 2, count: 10},
+				{at: 4 * time.Minute, group: "/a", latency: 2, count: 10},
+			},
+			want: map[string]want{"/a": {20, 2, 2}},
+		},
+		{
+			name: "old latencies evicted",
+			steps: []windowStep{
+				{at: 0, group: "/a", latency: 5, count: 100},
+				{at: 6 * time.Minute, group: "/a", latency: 0.1, count: 10},
+			},
+			want: map[string]want{"/a": {10, 0.1, 5}},
+		},
+		{
+			name: "cutoff kept",
+			steps: []windowStep{
+				{at: 0, group: "/a", latency: 5, count: 10},
+				{at: 5 * time.Minute, group: "/a", latency: 5, count: 10},
+			},
+			want: map[string]want{"/a": {20, 5, 5}},
+		},
+		{
+			name: "idle group evicted",
+			steps: []windowStep{
+				{at: 0, group: "/a", latency: 1, count: 10},
+				{at: 10 * time.Minute, group: "/b", latency: 0.5, count: 10},
+			},
+			want: map[string]want{"/a": {0, 0, 1}, "/b": {10, 0.5, 0.5}},
+		},
+		{
+			name: "merged shards evicted",
+			shards: [][]windowStep{
+				{{at: 0, group: "/a", latency: 5, count: 100}},
+				{{at: 6 * time.Minute, group: "/a", latency: 0.1, count: 10}},
+			},
+			want: map[string]want{"/a": {10, 0.1, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.Window = 5 * time.Minute
+
+			addWindowSteps(m, tt.steps)
+
+			for _, steps := range tt.shards {
+				shard := m.Shard()
+				addWindowSteps(shard, steps)
+				m.Merge(shard)
+			}
+
+			groups := make(map[string]*GroupReport)
+
+			for _, g := range m.Analyze().Groups {
+				groups[g.Key] = g
+			}
+
+			for group, want := range tt.want {
+				g, exists := groups[group]
+
+				if !exists {
+					t.Fatalf("no group %s", group)
+				}
+
+				if g.P99Latency != want.allP99 {
+					t.Errorf("%s all-time p99 = %g, want %g", group, g.P99Latency, want.allP99)
+				}
+
+				if got := len(m.windowData[group].latencies); got != want.count {
+					t.Errorf("%s window holds %d latencies, want %d", group, got, want.count)
+				}
+
+				if want.count == 0 {
+					if g.WindowPercentiles != nil {
+						t.Errorf("%s window percentiles = %+v, want none", group, g.WindowPercentiles)
+					}
+
+					continue
+				}
+
+				if g.WindowPercentiles == nil {
+					t.Fatalf("%s has no window percentiles", group)
+				}
+
+				if g.WindowPercentiles.Count != want.count || g.WindowPercentiles.P99 != want.windowP99 {
+					t.Errorf("%s window = %+v, want %d latencies with p99 %g", group, g.WindowPercentiles, want.count, want.windowP99)
+				}
+			}
+		})
+	}
+}
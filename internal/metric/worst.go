@@ -0,0 +1,114 @@
+package metric
+
+import (
+	"sort"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// WorstRequest is the request with the highest latency of a group
+type WorstRequest struct {
+	Group   string
+	Latency float64
+	Line    string
+}
+
+func worstLineKey(group string) string {
+	return "worst:" + group
+}
+
+func (m *MetricCollector) trackWorst(group string, result *parser.NginxResult, rawLine string) {
+	if !m.WorstRequests || !result.HasLatency() {
+		return
+	}
+
+	if m.worstData == nil {
+		m.worstData = make(map[string]float64)
+	}
+
+	if latency, exists := m.worstData[group]; exists && latency >= result.RequestTime {
+		return
+	}
+
+	m.worstData[group] = result.RequestTime
+	m.sampleLine(worstLineKey(group), m.RedactLine(rawLine))
+}
+
+// worstRequests returns the worst request of every group, sorted by group
+func (m *MetricCollector) worstRequests(groups []*GroupReport) []*WorstRequest {
+	if !m.WorstRequests {
+		return nil
+	}
+
+	res := make([]*WorstRequest, 0, len(groups))
+
+	for _, group := range groups {
+		latency, exists := m.worstData[group.Key]
+
+		if !exists {
+			continue
+		}
+
+		line, _ := m.sampledLine(worstLineKey(group.Key))
+		res = append(res, &WorstRequest{group.Key, latency, line})
+	}
+
+	return res
+}
+
+// mergeWorst keeps the worse of the worst requests of each group of m and other
+func (m *MetricCollector) mergeWorst(other *MetricCollector) {
+	for _, group := range sortedFloatKeys(other.worstData) {
+		latency := other.worstData[group]
+
+		if current, exists := m.worstData[group]; exists && current >= latency {
+			continue
+		}
+
+		if m.worstData == nil {
+			m.worstData = make(map[string]float64)
+		}
+
+		m.worstData[group] = latency
+
+		line, _ := other.sampledLine(worstLineKey(group))
+		m.sampleLine(worstLineKey(group), line)
+	}
+}
+
+// mergeWorstGroup keeps the worse of the worst requests of groups from and to under to
+func (m *MetricCollector) mergeWorstGroup(from, to string) {
+	latency, exists := m.worstData[from]
+
+	if !exists {
+		return
+	}
+
+	if current, exists := m.worstData[to]; !exists || latency > current {
+		m.worstData[to] = latency
+
+		if line, exists := m.sampledLine(worstLineKey(from)); exists {
+			m.sampleLine(worstLineKey(to), line)
+		} else if m.lineSamples != nil {
+			m.lineSamples.remove(worstLineKey(to))
+		}
+	}
+
+	delete(m.worstData, from)
+
+	if m.lineSamples != nil {
+		m.lineSamples.remove(worstLineKey(from))
+	}
+}
+
+func sortedFloatKeys(data map[string]float64) []string {
+	keys := make([]string, 0, len(data))
+
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
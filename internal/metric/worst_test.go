@@ -0,0 +1,93 @@
+package metric
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestWorstRequests(t *testing.T) {
+	type request struct {
+		path    string
+		query   string
+		latency float64
+	}
+
+	tests := []struct {
+		name       string
+		cluster    bool
+		redact     bool
+		requests   []request
+		wantGroups []string
+		wantLines  []string
+	}{
+		{
+			name: "per path",
+			requests: []request{
+				{"/a/1", "id=1", 0.2},
+				{"/a/2", "id=2", 0.5},
+			},
+			wantGroups: []string{"/a/1", "/a/2"},
+			wantLines:  []string{"GET /a/1?id=1 0.2", "GET /a/2?id=2 0.5"},
+		},
+		{
+			name:    "clustered",
+			cluster: true,
+			requests: []request{
+				{"/a/1", "id=1", 0.2},
+				{"/a/2", "id=2", 0.5},
+				{"/a/3", "id=3", 0.1},
+			},
+			wantGroups: []string{"/a/*"},
+			wantLines:  []string{"GET /a/2?id=2 0.5"},
+		},
+		{
+			name:   "redacted",
+			redact: true,
+			requests: []request{
+				{"/a", "id=2&page=1", 0.5},
+			},
+			wantGroups: []string{"/a"},
+			wantLines:  []string{"GET /a?id=REDACTED&page=1 0.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetricCollector(GroupKindPath, MetricKindLatency)
+			m.WorstRequests = true
+			m.ClusterPaths = tt.cluster
+			m.RedactQueryValues = tt.redact
+			m.KeepQueryValues = []string{"page"}
+
+			for _, req := range tt.requests {
+				line := "GET " + req.path + "?" + req.query + " " + strconv.FormatFloat(req.latency, 'f', -1, 64)
+
+				m.AddLine(&parser.NginxResult{
+					Request:        &parser.Request{Method: "GET", Path: req.path, Query: req.query},
+					RequestTime:    req.latency,
+					Status:         200,
+					UpstreamStatus: 200,
+					UpstreamAddr:   "10.0.0.1:80",
+				}, line)
+			}
+
+			worst := m.Analyze().WorstRequests
+
+			if len(worst) != len(tt.wantGroups) {
+				t.Fatalf("got %d worst requests, want %d", len(worst), len(tt.wantGroups))
+			}
+
+			for i, group := range tt.wantGroups {
+				if worst[i].Group != group {
+					t.Errorf("worst request %d group = %q, want %q", i, worst[i].Group, group)
+				}
+
+				if worst[i].Line != tt.wantLines[i] {
+					t.Errorf("worst request %d line = %q, want %q", i, worst[i].Line, tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
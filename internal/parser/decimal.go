@@ -0,0 +1,18 @@
+package parser
+
+import "regexp"
+
+// numericValuePattern matches numeric values like "0,123" or "0,001, 0,002 : 0,003"
+var numericValuePattern = regexp.MustCompile(`^[0-9,.: -]+(ms|s)?$`)
+
+// decimalCommaPattern matches a comma between two digits
+var decimalCommaPattern = regexp.MustCompile(`([0-9]),([0-9])`)
+
+// normalizeDecimalCommas replaces the decimal commas of numeric fields with dots
+func normalizeDecimalCommas(fields map[string]string) {
+	for k, v := range fields {
+		if numericValuePattern.MatchString(v) {
+			fields[k] = decimalCommaPattern.ReplaceAllString(v, "$1.$2")
+		}
+	}
+}
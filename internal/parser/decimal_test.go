@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeDecimalCommas(t *testing.T) {
+	fields := map[string]string{
+		"request_time":           "0,123",
+		"upstream_response_time": "0,001, 0,002 : 0,003",
+		"upstream_header_time":   "12,5ms",
+		"http_user_agent":        "Mozilla/5.0 (X11; Linux x86_64, rv:1,2)",
+		"body_bytes_sent":        "512",
+	}
+
+	normalizeDecimalCommas(fields)
+
+	want := map[string]string{
+		"request_time":           "0.123",
+		"upstream_response_time": "0.001, 0.002 : 0.003",
+		"upstream_header_time":   "12.5ms",
+		"http_user_agent":        "Mozilla/5.0 (X11; Linux x86_64, rv:1,2)",
+		"body_bytes_sent":        "512",
+	}
+
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ConversionError is returned when a field of a line has a value of the wrong type
+type ConversionError struct {
+	Field string
+	msg   string
+}
+
+func (e *ConversionError) Error() string {
+	return e.msg
+}
+
+// FieldFailureCount is the number of lines dropped because a field failed to convert
+type FieldFailureCount struct {
+	Field string
+	Count uint
+}
+
+// fieldFailures tallies conversion failures by field, safe for concurrent use
+type fieldFailures struct {
+	mu     sync.Mutex
+	counts map[string]uint
+}
+
+func (f *fieldFailures) record(err error) {
+	if f == nil {
+		return
+	}
+
+	var convErr *ConversionError
+
+	if !errors.As(err, &convErr) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.counts == nil {
+		f.counts = make(map[string]uint)
+	}
+
+	f.counts[convErr.Field]++
+}
+
+// FieldFailures returns the conversion failures of the factory's parsers
+func (pf *NginxParserFactory) FieldFailures() []*FieldFailureCount {
+	pf.failures.mu.Lock()
+	defer pf.failures.mu.Unlock()
+
+	res := make([]*FieldFailureCount, 0, len(pf.failures.counts))
+
+	for field, count := range pf.failures.counts {
+		res = append(res, &FieldFailureCount{field, count})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count == res[j].Count {
+			return res[i].Field < res[j].Field
+		}
+
+		return res[i].Count > res[j].Count
+	})
+
+	return res
+}
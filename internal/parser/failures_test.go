@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldFailures(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{
+		"log_format": `$remote_addr [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 0.300 10.1.0.5:8080 200`,
+		`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 slow 10.1.0.5:8080 200`,
+		`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 fast 10.1.0.5:8080 200`,
+		`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 1.2.3 10.1.0.5:8080 200`,
+		`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 0.300 10.1.0.5:8080 OK`,
+		// lines matching no format aren't conversion failures
+		`not a log line`,
+	}
+
+	// the tally is shared by the parsers of the factory
+	parsers := []*NginxParser{factory.New(), factory.New()}
+
+	for i, line := range lines {
+		parsers[i%2].Parse(line)
+	}
+
+	want := []*FieldFailureCount{
+		{"request_time", 3},
+		{"upstream_status", 1},
+	}
+
+	if got := factory.FieldFailures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("field failures = %v, want %v", got, want)
+	}
+}
+
+func TestFieldFailuresNone(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := factory.New().Parse(testAccessLine); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := factory.FieldFailures(); len(got) != 0 {
+		t.Errorf("field failures = %v, want none", got)
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FormatFileExt is the extension of the format definition files in a format directory
+const FormatFileExt = ".format"
+
+// FormatDefinition is an access log format loaded from a format directory
+type FormatDefinition struct {
+	Format     string
+	TimeLayout string
+}
+
+// LoadFormat loads the format definition in the file <name>.format of dir
+func LoadFormat(dir, name string) (*FormatDefinition, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid format name %s", name)
+	}
+
+	path := filepath.Join(dir, name+FormatFileExt)
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	def := &FormatDefinition{
+		TimeLayout: nginxIngressTimeFormat,
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key: value", path, lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "format":
+			def.Format = value
+		case "time_layout":
+			def.TimeLayout = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown key %s", path, lineNum, key)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if def.Format == "" {
+		return nil, fmt.Errorf("%s: missing format", path)
+	}
+
+	return def, nil
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFormatFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name+FormatFileExt), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFormatFile(t, dir, "short", `# a short format, with ISO 8601 times
+format: $remote_addr [$time_local] "$request" $status $body_bytes_sent $request_time $upstream_addr $upstream_response_time $upstream_status
+
+time_layout: 2006-01-02T15:04:05Z07:00
+`)
+
+	def, err := LoadFormat(dir, "short")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if def.TimeLayout != time.RFC3339 {
+		t.Errorf("TimeLayout = %q, want %q", def.TimeLayout, time.RFC3339)
+	}
+
+	p := newTestParser(t, map[string]interface{}{
+		"log_format":  def.Format,
+		"time_layout": def.TimeLayout,
+	})
+
+	res, err := p.Parse(`10.0.0.1 [2026-10-14T10:00:00+02:00] "GET /api HTTP/1.1" 200 512 0.300 10.1.0.5:8080 0.250 200`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC); !res.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %v, want %v", res.TimeLocal, want)
+	}
+
+	if res.Request.Path != "/api" || res.RequestTime != 0.3 || res.UpstreamStatus != 200 {
+		t.Errorf("got %+v", res)
+	}
+}
+
+func TestLoadFormatDefaultTimeLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeFormatFile(t, dir, "ingress", "format: "+nginxIngressLogFormat+"\n")
+
+	def, err := LoadFormat(dir, "ingress")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if def.Format != nginxIngressLogFormat || def.TimeLayout != nginxIngressTimeFormat {
+		t.Errorf("got %+v", def)
+	}
+}
+
+func TestLoadFormatInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFormatFile(t, dir, "no-format", "time_layout: 2006-01-02\n")
+	writeFormatFile(t, dir, "no-colon", "format $remote_addr\n")
+	writeFormatFile(t, dir, "unknown-key", "format: $remote_addr\nlayout: 2006-01-02\n")
+
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"no-format", "missing format"},
+		{"no-colon", "expected key: value"},
+		{"unknown-key", "unknown key layout"},
+		{"missing", "no such file or directory"},
+		{"../escape", "invalid format name"},
+		{"", "invalid format name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFormat(dir, tt.name)
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFormat() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
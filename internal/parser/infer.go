@@ -0,0 +1,271 @@
+package parser
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tokenQuoting int
+
+const (
+	tokenBare tokenQuoting = iota
+	tokenBracketed
+	tokenQuoted
+)
+
+type token struct {
+	quoting tokenQuoting
+	value   string
+}
+
+type tokenKind int
+
+const (
+	kindDash tokenKind = iota
+	kindAddr
+	kindTime
+	kindRequest
+	kindInt
+	kindFloat
+	kindReqID
+	kindString
+)
+
+var (
+	requestRegexp = regexp.MustCompile(`^[A-Z]+ \S+ HTTP/`)
+	reqIDRegexp   = regexp.MustCompile(`^[0-9a-f]{16,}$`)
+)
+
+// InferFormat guesses a gonx log format from sample lines
+func InferFormat(lines []string) (string, error) {
+	samples := make(map[int][][]*token)
+	commonLen := 0
+
+	for _, line := range lines {
+		tokens := tokenize(line)
+
+		if len(tokens) == 0 {
+			continue
+		}
+
+		samples[len(tokens)] = append(samples[len(tokens)], tokens)
+
+		if len(samples[len(tokens)]) > len(samples[commonLen]) {
+			commonLen = len(tokens)
+		}
+	}
+
+	if commonLen == 0 {
+		return "", fmt.Errorf("no sample lines to infer a format from")
+	}
+
+	lineTokens := samples[commonLen]
+	kinds := make([]tokenKind, commonLen)
+
+	for i := range kinds {
+		kinds[i] = positionKind(lineTokens, i)
+	}
+
+	names := newFieldNames()
+	fields := make([]string, commonLen)
+
+	for i, kind := range kinds {
+		var prev, next tokenKind = -1, -1
+		prevName := ""
+
+		if i > 0 {
+			prev = kinds[i-1]
+			prevName = fields[i-1]
+		}
+
+		if i < len(kinds)-1 {
+			next = kinds[i+1]
+		}
+
+		name := ""
+
+		switch kind {
+		case kindAddr:
+			name = names.next("addr")
+		case kindTime:
+			name = names.next("time")
+		case kindRequest:
+			name = names.next("request")
+		case kindReqID:
+			name = names.next("req_id")
+		case kindFloat:
+			name = names.next("float")
+		case kindInt:
+			// statuses follow the request line and the upstream response time
+			if prev == kindRequest || prevName == "$upstream_response_time" {
+				name = names.next("status")
+			} else {
+				name = names.next("int")
+			}
+		case kindDash, kindString:
+			switch {
+			case prevName == "$remote_addr" && kind == kindDash:
+				name = "-"
+			case next == kindTime:
+				name = names.next("user")
+			case lineTokens[0][i].quoting == tokenQuoted:
+				name = names.next("quoted")
+			case lineTokens[0][i].quoting == tokenBracketed:
+				name = names.next("bracketed")
+			}
+		}
+
+		if name == "" {
+			name = fmt.Sprintf("$field_%d", i)
+		}
+
+		fields[i] = name
+	}
+
+	for i, field := range fields {
+		switch lineTokens[0][i].quoting {
+		case tokenBracketed:
+			fields[i] = "[" + field + "]"
+		case tokenQuoted:
+			fields[i] = `"` + field + `"`
+		}
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// fieldNames hands out the variable names of each kind of token
+type fieldNames map[string][]string
+
+func newFieldNames() fieldNames {
+	return fieldNames{
+		"addr":      {"$remote_addr", "$upstream_addr"},
+		"user":      {"$remote_user"},
+		"time":      {"$time_local"},
+		"request":   {"$request"},
+		"status":    {"$status", "$upstream_status"},
+		"int":       {"$body_bytes_sent", "$request_length", "$upstream_response_length"},
+		"float":     {"$request_time", "$upstream_response_time"},
+		"quoted":    {"$http_referer", "$http_user_agent"},
+		"bracketed": {"$proxy_upstream_name", "$proxy_alternative_upstream_name"},
+		"req_id":    {"$req_id"},
+	}
+}
+
+func (f fieldNames) next(kind string) string {
+	names := f[kind]
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	f[kind] = names[1:]
+
+	return names[0]
+}
+
+// positionKind returns the most common kind of the tokens at position i
+func positionKind(lineTokens [][]*token, i int) tokenKind {
+	counts := make(map[tokenKind]int)
+	best := kindDash
+
+	for _, tokens := range lineTokens {
+		kind := classify(tokens[i])
+
+		if kind == kindDash {
+			continue
+		}
+
+		counts[kind]++
+
+		if counts[kind] > counts[best] {
+			best = kind
+		}
+	}
+
+	return best
+}
+
+func classify(t *token) tokenKind {
+	value := t.value
+
+	switch {
+	case value == "-" || value == "":
+		return kindDash
+	case t.quoting == tokenBracketed:
+		if _, err := time.Parse(nginxIngressTimeFormat, value); err == nil {
+			return kindTime
+		}
+	case t.quoting == tokenQuoted:
+		if requestRegexp.MatchString(value) {
+			return kindRequest
+		}
+	case isAddr(value):
+		return kindAddr
+	case reqIDRegexp.MatchString(value):
+		return kindReqID
+	default:
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return kindInt
+		}
+
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return kindFloat
+		}
+	}
+
+	return kindString
+}
+
+func isAddr(value string) bool {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	return net.ParseIP(value) != nil
+}
+
+// tokenize splits a line into tokens, keeping bracketed and quoted values together
+func tokenize(line string) []*token {
+	tokens := make([]*token, 0)
+
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+		case '[':
+			end := strings.IndexByte(line[i+1:], ']')
+
+			if end < 0 {
+				end = len(line) - i - 1
+			}
+
+			tokens = append(tokens, &token{tokenBracketed, line[i+1 : i+1+end]})
+			i += end + 2
+		case '"':
+			end := i + 1
+
+			for end < len(line) && (line[end] != '"' || line[end-1] == '\\') {
+				end++
+			}
+
+			tokens = append(tokens, &token{tokenQuoted, line[i+1 : end]})
+			i = end + 1
+		default:
+			end := strings.IndexByte(line[i:], ' ')
+
+			if end < 0 {
+				end = len(line) - i
+			}
+
+			tokens = append(tokens, &token{tokenBare, line[i : i+end]})
+			i += end
+		}
+	}
+
+	return tokens
+}
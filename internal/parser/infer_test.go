@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const nginxCombinedLogFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+func TestInferFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ingress",
+			lines: []string{
+				`10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] 10.1.0.5:8080 512 0.250 200 4f0c2a1e9b7d3c5a8e6f1d2b3a4c5e6f`,
+				`10.0.0.2 - bob [14/Oct/2026:10:00:01 +0000] "POST /api/orders HTTP/2.0" 502 0 "https://example.com/" "Mozilla/5.0" 340 1.250 [default-orders-80] [default-orders-canary-80] 10.1.0.6:8080 0 1.248 502 9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d`,
+			},
+			want: nginxIngressLogFormat,
+		},
+		{
+			name: "ingress with dashes in some lines",
+			lines: []string{
+				`10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 504 0 "-" "curl/7.68.0" 120 5.000 [default-api-80] [] - - - - 4f0c2a1e9b7d3c5a8e6f1d2b3a4c5e6f`,
+				`10.0.0.2 - - [14/Oct/2026:10:00:01 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] 10.1.0.5:8080 512 0.250 200 9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d`,
+			},
+			want: nginxIngressLogFormat,
+		},
+		{
+			name: "combined",
+			lines: []string{
+				`10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0"`,
+				`10.0.0.2 - bob [14/Oct/2026:10:00:01 +0000] "POST /login HTTP/1.1" 302 0 "https://example.com/" "Mozilla/5.0"`,
+			},
+			want: nginxCombinedLogFormat,
+		},
+		{
+			name: "most common line length",
+			lines: []string{
+				`10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0"`,
+				`a truncated line`,
+				`10.0.0.2 - bob [14/Oct/2026:10:00:01 +0000] "POST /login HTTP/1.1" 302 0 "https://example.com/" "Mozilla/5.0"`,
+			},
+			want: nginxCombinedLogFormat,
+		},
+		{
+			name:    "no lines",
+			lines:   []string{"", ""},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InferFormat(tt.lines)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("InferFormat() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			// the parser needs $request_time, which the combined format lacks
+			if !strings.Contains(got, "$request_time") {
+				return
+			}
+
+			// the inferred format must parse the lines it was inferred from
+			p := newTestParser(t, map[string]interface{}{"log_format": got})
+
+			if _, err := p.Parse(tt.lines[len(tt.lines)-1]); err != nil {
+				t.Errorf("inferred format doesn't parse its sample: %v", err)
+			}
+		})
+	}
+}
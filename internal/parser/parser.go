@@ -16,43 +16,333 @@ type Parser interface {
 }
 
 const nginxIngressLogFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" $request_length $request_time [$proxy_upstream_name] [$proxy_alternative_upstream_name] $upstream_addr $upstream_response_length $upstream_response_time $upstream_status $req_id`
+const nginxIngressXFFLogFormat = nginxIngressLogFormat + ` "$http_x_forwarded_for" $host`
 const nginxIngressErrorFormat = `$time_date $time_hms [$status] $code: $id $message, client: $upstream_addr, server: $proxy_upstream_name, request: "$request", upstream: "$upstream_full", host: "$host"`
-const nginxIngressTimeFormat = `2/Jan/2006:15:04:05 +0000`
+const nginxIngressErrorReferrerFormat = nginxIngressErrorFormat + `, referrer: "$http_referer"`
+const nginxIngressErrorNoUpstreamFormat = `$time_date $time_hms [$status] $code: $id $message, client: $upstream_addr, server: $proxy_upstream_name, request: "$request", host: "$host"`
+const nginxIngressErrorNoUpstreamReferrerFormat = nginxIngressErrorNoUpstreamFormat + `, referrer: "$http_referer"`
+const nginxIngressTimeFormat = `2/Jan/2006:15:04:05 -0700`
+
+// FormatPresets are the access log formats selectable with the format_preset option
+var FormatPresets = map[string]string{
+	"ingress":     nginxIngressLogFormat,
+	"ingress-xff": nginxIngressXFFLogFormat,
+}
+
+// ErrorFormatPresets are the error log formats selectable with the error_formats option
+var ErrorFormatPresets = map[string]string{
+	"ingress":                               nginxIngressErrorFormat,
+	"ingress-referrer":                      nginxIngressErrorReferrerFormat,
+	"ingress-no-upstream":                   nginxIngressErrorNoUpstreamFormat,
+	"ingress-no-upstream-referrer":          nginxIngressErrorNoUpstreamReferrerFormat,
+	"ingress-unquoted":                      unquotedRequest(nginxIngressErrorFormat),
+	"ingress-referrer-unquoted":             unquotedRequest(nginxIngressErrorReferrerFormat),
+	"ingress-no-upstream-unquoted":          unquotedRequest(nginxIngressErrorNoUpstreamFormat),
+	"ingress-no-upstream-referrer-unquoted": unquotedRequest(nginxIngressErrorNoUpstreamReferrerFormat),
+}
+
+// DefaultErrorFormats are the error log format presets tried in order by default
+var DefaultErrorFormats = []string{
+	"ingress", "ingress-referrer", "ingress-no-upstream", "ingress-no-upstream-referrer",
+	"ingress-unquoted", "ingress-referrer-unquoted", "ingress-no-upstream-unquoted", "ingress-no-upstream-referrer-unquoted",
+}
+
+// unquotedRequest returns the error format without quotes around the request line
+func unquotedRequest(format string) string {
+	return strings.Replace(format, `request: "$request"`, `request: $request`, 1)
+}
+
+// DefaultBackendUpstream is the upstream name of ingress-nginx's default backend
+const DefaultBackendUpstream = "upstream-default-backend"
+
+// StatusNoResponse is the upstream status nginx logs as 000
+const StatusNoResponse int64 = 0
 
 type NginxParserFactory struct {
-	parserName   string
-	logFormat    string
-	errLogFormat string
+	parserName    string
+	logFormat     string
+	errLogFormats []string
+	timeLayout    string
+	clientIPField string
+
+	noUpstreamFallback bool
+	collapseTargets    bool
+	decimalComma       bool
+	sanitizeUTF8       bool
+	latencyField       string
+
+	failures fieldFailures
 }
 
+// Init configures the factory. Supported options are:
+//
+//	format_preset: the access log format in FormatPresets, ingress by default
+//	log_format: a custom gonx access log format
+//	err_log_format: a custom gonx error log format, not combined with error_formats
+//	time_layout: the Go time layout of $time_local
+//	client_ip_field: the field the client IP is read from, remote_addr by default
+//	no_upstream_fallback: mark lines without an upstream_addr as timed out
+//	collapse_absolute_targets: reduce absolute-form request targets to their path
+//	decimal_comma: parse numeric fields logged with comma decimals
+//	latency_field: request_time (the default) or upstream_response_time
+//	sanitize_utf8: replace invalid UTF-8 in fields with U+FFFD
+//	error_formats: the error log formats in ErrorFormatPresets to try in order
 func (pf *NginxParserFactory) Init(options map[string]interface{}) error {
 	pf.logFormat = nginxIngressLogFormat
-	pf.errLogFormat = nginxIngressErrorFormat
+	pf.errLogFormats = make([]string, 0, len(DefaultErrorFormats))
+
+	for _, name := range DefaultErrorFormats {
+		pf.errLogFormats = append(pf.errLogFormats, ErrorFormatPresets[name])
+	}
+
+	pf.timeLayout = nginxIngressTimeFormat
+	pf.clientIPField = "remote_addr"
+	pf.latencyField = "request_time"
+
+	if preset, exists := options["format_preset"]; exists {
+		name, ok := preset.(string)
+
+		if !ok {
+			return fmt.Errorf("option format_preset must be a string")
+		}
+
+		if pf.logFormat, ok = FormatPresets[name]; !ok {
+			return fmt.Errorf("unknown format preset %s", name)
+		}
+	}
+
+	if logFormat, exists := options["log_format"]; exists {
+		str, ok := logFormat.(string)
+
+		if !ok || str == "" {
+			return fmt.Errorf("option log_format must be a non-empty string")
+		}
+
+		if err := compileFormat(str); err != nil {
+			return fmt.Errorf("option log_format: %w", err)
+		}
+
+		pf.logFormat = str
+	}
+
+	if timeLayout, exists := options["time_layout"]; exists {
+		str, ok := timeLayout.(string)
+
+		if !ok || str == "" {
+			return fmt.Errorf("option time_layout must be a non-empty string")
+		}
+
+		pf.timeLayout = str
+	}
+
+	if clientIPField, exists := options["client_ip_field"]; exists {
+		str, ok := clientIPField.(string)
+
+		if !ok || str == "" {
+			return fmt.Errorf("option client_ip_field must be a non-empty string")
+		}
+
+		pf.clientIPField = str
+	}
+
+	if errorFormats, exists := options["error_formats"]; exists {
+		names, ok := errorFormats.([]string)
+
+		if !ok || len(names) == 0 {
+			return fmt.Errorf("option error_formats must be a non-empty []string")
+		}
+
+		pf.errLogFormats = make([]string, 0, len(names))
+
+		for _, name := range names {
+			format, ok := ErrorFormatPresets[name]
+
+			if !ok {
+				return fmt.Errorf("unknown error format preset %s", name)
+			}
+
+			pf.errLogFormats = append(pf.errLogFormats, format)
+		}
+	}
+
+	if errLogFormat, exists := options["err_log_format"]; exists {
+		str, ok := errLogFormat.(string)
+
+		if !ok || str == "" {
+			return fmt.Errorf("option err_log_format must be a non-empty string")
+		}
+
+		if err := compileFormat(str); err != nil {
+			return fmt.Errorf("option err_log_format: %w", err)
+		}
+
+		if _, exists := options["error_formats"]; exists {
+			return fmt.Errorf("option err_log_format can't be combined with error_formats")
+		}
+
+		pf.errLogFormats = []string{str}
+	}
+
+	if collapseTargets, exists := options["collapse_absolute_targets"]; exists {
+		b, ok := collapseTargets.(bool)
+
+		if !ok {
+			return fmt.Errorf("option collapse_absolute_targets must be a bool")
+		}
+
+		pf.collapseTargets = b
+	}
+
+	if noUpstreamFallback, exists := options["no_upstream_fallback"]; exists {
+		b, ok := noUpstreamFallback.(bool)
+
+		if !ok {
+			return fmt.Errorf("option no_upstream_fallback must be a bool")
+		}
+
+		pf.noUpstreamFallback = b
+	}
+
+	if decimalComma, exists := options["decimal_comma"]; exists {
+		b, ok := decimalComma.(bool)
+
+		if !ok {
+			return fmt.Errorf("option decimal_comma must be a bool")
+		}
+
+		pf.decimalComma = b
+	}
+
+	if latencyField, exists := options["latency_field"]; exists {
+		str, ok := latencyField.(string)
+
+		if !ok || (str != "request_time" && str != "upstream_response_time") {
+			return fmt.Errorf("option latency_field must be request_time or upstream_response_time")
+		}
+
+		pf.latencyField = str
+	}
+
+	if sanitizeUTF8, exists := options["sanitize_utf8"]; exists {
+		b, ok := sanitizeUTF8.(bool)
+
+		if !ok {
+			return fmt.Errorf("option sanitize_utf8 must be a bool")
+		}
+
+		pf.sanitizeUTF8 = b
+	}
 
 	return nil
 }
 
 func (pf *NginxParserFactory) New() *NginxParser {
+	errParsers := make([]*gonx.Parser, len(pf.errLogFormats))
+
+	for i, format := range pf.errLogFormats {
+		errParsers[i] = gonx.NewParser(format)
+	}
+
 	return &NginxParser{
-		gonxParser:    gonx.NewParser(pf.logFormat),
-		gonxErrParser: gonx.NewParser(pf.errLogFormat),
+		hasUpstreamName:  strings.Contains(pf.logFormat, "$proxy_upstream_name"),
+		hasRemoteUser:    strings.HasPrefix(pf.logFormat, remoteUserPrefix),
+		gonxParser:       gonx.NewParser(pf.logFormat),
+		gonxErrParsers:   errParsers,
+		gonxStreamParser: gonx.NewParser(nginxIngressStreamLogFormat),
+		timeLayout:       pf.timeLayout,
+		clientIPField:    pf.clientIPField,
+
+		noUpstreamFallback: pf.noUpstreamFallback,
+		collapseTargets:    pf.collapseTargets,
+		decimalComma:       pf.decimalComma,
+		sanitizeUTF8:       pf.sanitizeUTF8,
+		latencyField:       pf.latencyField,
+		failures:           &pf.failures,
 	}
 }
 
 type NginxParser struct {
+	// hasUpstreamName is set if the access log format logs $proxy_upstream_name
+	hasUpstreamName bool
+	// hasRemoteUser is set if the access log format begins with remoteUserPrefix
+	hasRemoteUser bool
 	gonxParser    *gonx.Parser
-	gonxErrParser *gonx.Parser
+	// gonxErrParsers are tried in order on lines that aren't access log lines
+	gonxErrParsers []*gonx.Parser
+	// gonxStreamParser parses the lines of L4 stream sessions
+	gonxStreamParser *gonx.Parser
+	timeLayout       string
+	clientIPField    string
+
+	noUpstreamFallback bool
+	collapseTargets    bool
+	decimalComma       bool
+	sanitizeUTF8       bool
+	latencyField       string
+
+	// failures tallies the conversion failures of the lines dropped by the parser
+	failures *fieldFailures
 }
 
 type NginxResult struct {
-	RemoteAddr     string
-	RemoteUser     string
-	UpstreamAddr   string
-	TimeLocal      time.Time
-	Request        *Request
-	RequestTime    float64
+	RemoteAddr string
+	RemoteUser string
+	// ClientIP is the address of the client, RemoteAddr unless read from another field
+	ClientIP     string
+	UpstreamAddr string
+	// TimeLocal is zero for lines without a time_local, including error log lines
+	TimeLocal   time.Time
+	Request     *Request
+	RequestTime float64
+	// Status is the status returned to the client, and is 0 for error log lines
+	Status         int64
 	UpstreamStatus int64
 	TimedOut       bool
+	// LatencyMissing is set when the line logged no upstream_response_time latency
+	LatencyMissing bool
+	ReqID          string
+	// BodyBytesSent and RequestLength are 0 when they aren't logged
+	BodyBytesSent int64
+	RequestLength int64
+	// XForwardedFor and Host are only set by formats that log them
+	XForwardedFor string
+	Host          string
+	// ProxyUpstreamName is the upstream the request was routed to
+	ProxyUpstreamName string
+	RoutingFailure    bool
+	// Referer is the Referer header of the request, and is empty if nginx logged it as "-"
+	Referer string
+	// upstream timings hold one value per upstream attempt, in seconds
+	UpstreamConnectTimes  []float64
+	UpstreamHeaderTimes   []float64
+	UpstreamResponseTimes []float64
+	// UpstreamResponseLengths holds the response size of each upstream attempt
+	UpstreamResponseLengths []int64
+	// ErrorMessage is the message of an error log line, and is empty for access log lines
+	ErrorMessage string
+	// Stream is set for the sessions of L4 TCP and UDP services
+	Stream   bool
+	Protocol string
+}
+
+// IsError reports whether the upstream returned a 5XX status or the request timed out
+func (r *NginxResult) IsError() bool {
+	return r.TimedOut || r.UpstreamStatus >= 500
+}
+
+// HasLatency reports whether RequestTime is the latency of the request
+func (r *NginxResult) HasLatency() bool {
+	return !r.TimedOut && !r.LatencyMissing
+}
+
+// IsClientError reports whether the upstream returned a 4XX status
+func (r *NginxResult) IsClientError() bool {
+	return !r.TimedOut && r.UpstreamStatus >= 400 && r.UpstreamStatus < 500
+}
+
+// IsSuccess reports whether the upstream returned a 1XX, 2XX or 3XX status
+func (r *NginxResult) IsSuccess() bool {
+	return !r.TimedOut && r.UpstreamStatus >= 100 && r.UpstreamStatus < 400
 }
 
 type Request struct {
@@ -62,61 +352,190 @@ type Request struct {
 }
 
 func (p *NginxParser) Parse(line string) (*NginxResult, error) {
+	res, _, err := p.ParseWithFields(line)
+
+	return res, err
+}
+
+// ParseWithFields parses the line like Parse, also returning the typed field map
+func (p *NginxParser) ParseWithFields(line string) (*NginxResult, map[string]interface{}, error) {
+	if p.sanitizeUTF8 {
+		line = strings.ToValidUTF8(line, "\uFFFD")
+	}
+
 	gonxEvent, err := p.gonxParser.ParseString(line)
 
+	if err != nil && p.hasRemoteUser {
+		if entry, ok := p.parseWithRemoteUser(line); ok {
+			gonxEvent, err = entry, nil
+		}
+	}
+
 	if err != nil {
+		if gonxEventStream, err := p.gonxStreamParser.ParseString(line); err == nil {
+			fields := typeifyParsedLine(gonxEventStream.Fields)
+
+			res, err := p.parsedStreamLineToResult(fields)
+
+			if err != nil {
+				p.failures.record(err)
+				return nil, nil, err
+			}
+
+			return res, fields, nil
+		}
+
 		// attempt to parse to error line
-		gonxEventErr, err := p.gonxErrParser.ParseString(line)
+		gonxEventErr, err := p.parseErrLine(line)
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		res, err := parsedErrLineToResult(typeifyParsedLine(gonxEventErr.Fields))
+		fields := typeifyParsedLine(gonxEventErr.Fields)
+
+		res, err := p.parsedErrLineToResult(fields)
 
 		if err != nil {
-			return nil, err
+			p.failures.record(err)
+			return nil, nil, err
 		}
 
-		return res, nil
+		return res, fields, nil
+	}
+
+	if p.decimalComma {
+		normalizeDecimalCommas(gonxEvent.Fields)
 	}
 
-	res, err := parsedLineToResult(typeifyParsedLine(gonxEvent.Fields))
+	fields := typeifyParsedLine(gonxEvent.Fields)
+
+	res, err := p.parsedLineToResult(fields)
 
 	if err != nil {
-		return nil, err
+		p.failures.record(err)
+		return nil, nil, err
 	}
 
-	return res, nil
+	return res, fields, nil
 }
 
-func parsedLineToResult(line map[string]interface{}) (*NginxResult, error) {
+// parseErrLine parses the line with the first error log format that matches it
+func (p *NginxParser) parseErrLine(line string) (*gonx.Entry, error) {
+	var err error
+
+	for _, errParser := range p.gonxErrParsers {
+		var entry *gonx.Entry
+
+		if entry, err = errParser.ParseString(line); err == nil {
+			return entry, nil
+		}
+	}
+
+	return nil, err
+}
+
+func (p *NginxParser) parsedLineToResult(line map[string]interface{}) (*NginxResult, error) {
 	res := &NginxResult{}
 	var err error
 
+	// these aren't needed for the metrics, so don't drop the line if they're missing
+	res.RemoteAddr, _ = toString(line, "remote_addr")
+	res.RemoteUser, _ = toString(line, "remote_user")
+
+	res.ClientIP = res.RemoteAddr
+
+	if clientIP, err := toString(line, p.clientIPField); err == nil {
+		res.ClientIP = firstAddr(clientIP)
+	}
+
+	// request IDs are hex, so they may have been typeified into a number
+	res.ReqID, _ = toFormattedString(line, "req_id")
+
+	res.Status, _ = toInt64(line, "status")
+
+	if p.hasUpstreamName {
+		// "-" values are dropped from the line, so a missing name is an empty one
+		res.ProxyUpstreamName, _ = toFormattedString(line, "proxy_upstream_name")
+		res.RoutingFailure = res.ProxyUpstreamName == "" || res.ProxyUpstreamName == DefaultBackendUpstream
+	}
+
+	if res.BodyBytesSent, err = toByteCount(line, "body_bytes_sent"); err != nil {
+		return nil, err
+	}
+
+	if res.RequestLength, err = toByteCount(line, "request_length"); err != nil {
+		return nil, err
+	}
+
+	res.XForwardedFor, _ = toString(line, "http_x_forwarded_for")
+	res.Host, _ = toString(line, "host")
+	res.Referer, _ = toString(line, "http_referer")
+
+	missingUpstream := false
+
 	if res.UpstreamAddr, err = toString(line, "upstream_addr"); err != nil {
-		res.UpstreamAddr = "0.0.0.0"
+		if p.noUpstreamFallback {
+			res.UpstreamAddr = ""
+			missingUpstream = true
+		} else {
+			res.UpstreamAddr = "0.0.0.0"
+		}
 		// return nil, err
 	}
 
-	if res.RequestTime, err = toFloat64(line, "request_time"); err != nil {
+	if res.RequestTime, err = toSeconds(line, "request_time"); err != nil {
 		return nil, err
 	}
 
-	reqTimeLocalStr, err := toString(line, "time_local")
+	// formats without a time_local, or lines logging it as "-", leave TimeLocal zero
+	if _, exists := line["time_local"]; exists {
+		reqTimeLocalStr, err := toString(line, "time_local")
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		if res.TimeLocal, err = time.Parse(p.timeLayout, reqTimeLocalStr); err != nil {
+			return nil, &ConversionError{"time_local", err.Error()}
+		}
 	}
 
-	res.TimeLocal, err = time.Parse(nginxIngressTimeFormat, reqTimeLocalStr)
+	res.UpstreamConnectTimes = toFloat64List(line, "upstream_connect_time")
+	res.UpstreamHeaderTimes = toFloat64List(line, "upstream_header_time")
+	res.UpstreamResponseTimes = toFloat64List(line, "upstream_response_time")
+
+	if p.latencyField == "upstream_response_time" {
+		// keep the line for its status, but leave it out of the latencies
+		if len(res.UpstreamResponseTimes) == 0 {
+			res.RequestTime = 0
+			res.LatencyMissing = true
+		} else {
+			// the client was sent the response of the last upstream attempt
+			res.RequestTime = res.UpstreamResponseTimes[len(res.UpstreamResponseTimes)-1]
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	for _, length := range toFloat64List(line, "upstream_response_length") {
+		res.UpstreamResponseLengths = append(res.UpstreamResponseLengths, int64(length))
 	}
 
 	if res.UpstreamStatus, err = toInt64(line, "upstream_status"); err != nil {
-		return nil, err
+		if !missingUpstream {
+			return nil, err
+		}
+
+		// the request never reached an upstream, so use the status nginx returned
+		res.UpstreamStatus = res.Status
+
+		if res.UpstreamStatus == 0 {
+			res.UpstreamStatus = 502
+		}
+	}
+
+	// a request without a response from the upstream or nginx timed out
+	if missingUpstream || res.UpstreamStatus == StatusNoResponse {
+		res.TimedOut = true
 	}
 
 	reqStr, err := toString(line, "request")
@@ -125,7 +544,7 @@ func parsedLineToResult(line map[string]interface{}) (*NginxResult, error) {
 		return nil, err
 	}
 
-	res.Request, err = requestStringToReq(reqStr)
+	res.Request, err = requestStringToReq(reqStr, p.collapseTargets)
 
 	if err != nil {
 		return nil, err
@@ -134,7 +553,7 @@ func parsedLineToResult(line map[string]interface{}) (*NginxResult, error) {
 	return res, nil
 }
 
-func parsedErrLineToResult(line map[string]interface{}) (*NginxResult, error) {
+func (p *NginxParser) parsedErrLineToResult(line map[string]interface{}) (*NginxResult, error) {
 	res := &NginxResult{
 		UpstreamStatus: 504,
 		TimedOut:       true,
@@ -147,13 +566,27 @@ func parsedErrLineToResult(line map[string]interface{}) (*NginxResult, error) {
 		// return nil, err
 	}
 
+	// the upstream clause holds the address of the upstream the error happened with
+	if upstream, err := toString(line, "upstream_full"); err == nil {
+		if upstreamURL, err := url.Parse(upstream); err == nil && upstreamURL.Host != "" {
+			res.UpstreamAddr = upstreamURL.Host
+		}
+	}
+
+	if res.ErrorMessage, err = toString(line, "message"); err != nil {
+		return nil, err
+	}
+
+	res.Host, _ = toString(line, "host")
+	res.Referer, _ = toString(line, "http_referer")
+
 	reqStr, err := toString(line, "request")
 
 	if err != nil {
 		return nil, err
 	}
 
-	res.Request, err = requestStringToReq(reqStr)
+	res.Request, err = requestStringToReq(reqStr, p.collapseTargets)
 
 	if err != nil {
 		return nil, err
@@ -172,12 +605,23 @@ func toString(parsedLine map[string]interface{}, field string) (string, error) {
 	str, ok := strInt.(string)
 
 	if !ok {
-		return "", fmt.Errorf("field %s could not be converted to string", field)
+		return "", &ConversionError{field, fmt.Sprintf("field %s could not be converted to string", field)}
 	}
 
 	return str, nil
 }
 
+// toFormattedString returns the field as a string, formatting numbers
+func toFormattedString(parsedLine map[string]interface{}, field string) (string, error) {
+	strInt, exists := parsedLine[field]
+
+	if !exists {
+		return "", fmt.Errorf("field %s does not exist", field)
+	}
+
+	return fmt.Sprint(strInt), nil
+}
+
 func toFloat64(parsedLine map[string]interface{}, field string) (float64, error) {
 	strInt, exists := parsedLine[field]
 
@@ -188,12 +632,80 @@ func toFloat64(parsedLine map[string]interface{}, field string) (float64, error)
 	res, ok := strInt.(float64)
 
 	if !ok {
-		return 0, fmt.Errorf("field %s could not be converted to float64", field)
+		return 0, &ConversionError{field, fmt.Sprintf("field %s could not be converted to float64", field)}
 	}
 
 	return res, nil
 }
 
+// toSeconds returns a duration field in seconds, with an optional "s" or "ms" suffix
+func toSeconds(parsedLine map[string]interface{}, field string) (float64, error) {
+	value, exists := parsedLine[field]
+
+	if !exists {
+		return 0, fmt.Errorf("field %s does not exist", field)
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		scale := 1.0
+
+		switch {
+		case strings.HasSuffix(v, "ms"):
+			v, scale = strings.TrimSuffix(v, "ms"), 0.001
+		case strings.HasSuffix(v, "s"):
+			v = strings.TrimSuffix(v, "s")
+		}
+
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f * scale, nil
+		}
+	}
+
+	return 0, &ConversionError{field, fmt.Sprintf("field %s could not be converted to seconds", field)}
+}
+
+// toFloat64List returns the values of a field logged once per upstream attempt
+func toFloat64List(parsedLine map[string]interface{}, field string) []float64 {
+	value, exists := parsedLine[field]
+
+	if !exists {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return []float64{v}
+	case int64:
+		return []float64{float64(v)}
+	case string:
+		var res []float64
+
+		for _, str := range strings.FieldsFunc(v, isUpstreamSeparator) {
+			f, err := strconv.ParseFloat(str, 64)
+
+			if err != nil {
+				continue
+			}
+
+			res = append(res, f)
+		}
+
+		return res
+	}
+
+	return nil
+}
+
+// isUpstreamSeparator reports whether r separates upstream values
+func isUpstreamSeparator(r rune) bool {
+	return r == ',' || r == ':' || r == ' '
+}
+
 func toInt64(parsedLine map[string]interface{}, field string) (int64, error) {
 	strInt, exists := parsedLine[field]
 
@@ -204,20 +716,37 @@ func toInt64(parsedLine map[string]interface{}, field string) (int64, error) {
 	res, ok := strInt.(int64)
 
 	if !ok {
-		return 0, fmt.Errorf("field %s could not be converted to uint, is %s", field, reflect.TypeOf(strInt))
+		return 0, &ConversionError{field, fmt.Sprintf("field %s could not be converted to uint, is %s", field, reflect.TypeOf(strInt))}
 	}
 
 	return res, nil
 }
 
-func requestStringToReq(str string) (*Request, error) {
+// toByteCount reads a byte count field, 0 if it's missing
+func toByteCount(parsedLine map[string]interface{}, field string) (int64, error) {
+	if _, exists := parsedLine[field]; !exists {
+		return 0, nil
+	}
+
+	return toInt64(parsedLine, field)
+}
+
+// requestStringToReq splits a request line into its method, target and protocol
+func requestStringToReq(str string, collapseTargets bool) (*Request, error) {
 	strArr := strings.Split(str, " ")
 
-	if len(strArr) != 3 {
+	if len(strArr) < 3 {
 		return nil, fmt.Errorf("incorrect format for %s", str)
 	}
 
-	urlRes, err := url.Parse(fmt.Sprintf("http://localhost%s", strArr[1]))
+	target := strings.Join(strArr[1:len(strArr)-1], " ")
+
+	if collapseTargets {
+		target = collapseTarget(strArr[0], target)
+	}
+
+	// url.Parse rejects raw spaces in some parts of a URL, so escape them
+	urlRes, err := url.Parse(fmt.Sprintf("http://localhost%s", strings.ReplaceAll(target, " ", "%20")))
 
 	if err != nil {
 		return nil, err
@@ -230,6 +759,46 @@ func requestStringToReq(str string) (*Request, error) {
 	}, nil
 }
 
+// collapseTarget reduces an absolute-form or authority-form target to its path
+func collapseTarget(method, target string) string {
+	if method == "CONNECT" {
+		return "/"
+	}
+
+	if strings.HasPrefix(target, "/") || !strings.Contains(target, "://") {
+		return target
+	}
+
+	targetURL, err := url.Parse(target)
+
+	if err != nil {
+		return target
+	}
+
+	path := targetURL.EscapedPath()
+
+	if path == "" {
+		path = "/"
+	}
+
+	if targetURL.RawQuery != "" {
+		path += "?" + targetURL.RawQuery
+	}
+
+	return path
+}
+
+// firstAddr returns the first address in an address chain like X-Forwarded-For
+func firstAddr(chain string) string {
+	for _, addr := range strings.Split(chain, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			return addr
+		}
+	}
+
+	return chain
+}
+
 // typeifyParsedLine attempts to cast numbers in the event to floats or ints
 func typeifyParsedLine(pl map[string]string) map[string]interface{} {
 	// try to convert numbers, if possible
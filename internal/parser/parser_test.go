@@ -0,0 +1,1112 @@
+package parser
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessLine = `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] 10.1.0.5:8080 512 0.250 200 req1`
+	// nginx logs - for the upstream fields of a request no upstream answered
+	testNoUpstreamTimeLine = `10.0.0.1 - - [14/Oct/2026:10:00:01 +0000] "GET /api HTTP/1.1" 504 0 "-" "curl/7.68.0" 120 5.000 [default-api-80] [] 10.1.0.5:8080 0 - 504 req2`
+)
+
+func newTestParser(t *testing.T, options map[string]interface{}) *NginxParser {
+	t.Helper()
+
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(options); err != nil {
+		t.Fatal(err)
+	}
+
+	return factory.New()
+}
+
+func TestLatencyField(t *testing.T) {
+	tests := []struct {
+		name        string
+		field       string
+		line        string
+		wantLatency float64
+		wantMissing bool
+	}{
+		{"request_time", "request_time", testAccessLine, 0.3, false},
+		{"upstream_response_time", "upstream_response_time", testAccessLine, 0.25, false},
+		{"request_time without upstream time", "request_time", testNoUpstreamTimeLine, 5, false},
+		{"upstream_response_time without upstream time", "upstream_response_time", testNoUpstreamTimeLine, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, map[string]interface{}{"latency_field": tt.field})
+			res, err := p.Parse(tt.line)
+
+			if err != nil {
+				t.Fatalf("line dropped: %v", err)
+			}
+
+			if res.RequestTime != tt.wantLatency {
+				t.Errorf("RequestTime = %g, want %g", res.RequestTime, tt.wantLatency)
+			}
+
+			if res.LatencyMissing != tt.wantMissing {
+				t.Errorf("LatencyMissing = %t, want %t", res.LatencyMissing, tt.wantMissing)
+			}
+
+			if res.UpstreamStatus == 0 {
+				t.Errorf("UpstreamStatus wasn't parsed")
+			}
+		})
+	}
+}
+
+func TestLatencyFieldInvalid(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{"latency_field": "bytes_sent"}); err == nil {
+		t.Error("expected an error for an unknown latency field")
+	}
+}
+
+func TestParseWithFields(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{})
+
+	res, fields, err := p.ParseWithFields(testAccessLine)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Request.Method != "GET" || res.Request.Path != "/api" {
+		t.Errorf("request = %s %s, want GET /api", res.Request.Method, res.Request.Path)
+	}
+
+	if res.RequestTime != 0.3 {
+		t.Errorf("RequestTime = %g, want 0.3", res.RequestTime)
+	}
+
+	// NginxResult doesn't model the user agent or the request id
+	if agent := fields["http_user_agent"]; agent != "curl/7.68.0" {
+		t.Errorf("http_user_agent = %v, want curl/7.68.0", agent)
+	}
+
+	if id := fields["req_id"]; id != "req1" {
+		t.Errorf("req_id = %v, want req1", id)
+	}
+
+	// the map holds the typed values
+	if length, ok := fields["request_length"].(int64); !ok || length != 120 {
+		t.Errorf("request_length = %#v, want int64 120", fields["request_length"])
+	}
+}
+
+func TestParseWithFieldsInvalid(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{})
+
+	res, fields, err := p.ParseWithFields("not a log line")
+
+	if err == nil {
+		t.Fatal("expected an error for an unparseable line")
+	}
+
+	if res != nil || fields != nil {
+		t.Errorf("got result %v and fields %v with an error", res, fields)
+	}
+}
+
+func TestParseTimeLocalOffset(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{})
+
+	res, err := p.Parse(`10.0.0.1 - - [14/Oct/2026:10:00:00 -0400] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] 10.1.0.5:8080 512 0.250 200 req1`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC); !res.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %s, want %s", res.TimeLocal, want)
+	}
+
+	if _, offset := res.TimeLocal.Zone(); offset != -4*60*60 {
+		t.Errorf("TimeLocal offset = %ds, want -14400s", offset)
+	}
+}
+
+func TestClientIPField(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		xff   string
+		want  string
+	}{
+		{"remote_addr by default", "", "203.0.113.7", "10.0.0.1"},
+		{"single hop", "http_x_forwarded_for", "203.0.113.7", "203.0.113.7"},
+		{"multi hop", "http_x_forwarded_for", "203.0.113.7, 198.51.100.2, 10.0.0.1", "203.0.113.7"},
+		{"multi hop without spaces", "http_x_forwarded_for", "2001:db8::1,198.51.100.2", "2001:db8::1"},
+		{"leading empty hop", "http_x_forwarded_for", " , 203.0.113.7", "203.0.113.7"},
+		{"missing", "http_x_forwarded_for", "-", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := map[string]interface{}{}
+
+			if tt.field != "" {
+				options["client_ip_field"] = tt.field
+			}
+
+			factory := &NginxParserFactory{}
+
+			if err := factory.Init(options); err != nil {
+				t.Fatal(err)
+			}
+
+			// the default format with the X-Forwarded-For header appended
+			factory.logFormat += ` "$http_x_forwarded_for"`
+
+			res, err := factory.New().Parse(testAccessLine + ` "` + tt.xff + `"`)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.ClientIP != tt.want {
+				t.Errorf("ClientIP = %q, want %q", res.ClientIP, tt.want)
+			}
+
+			if res.RemoteAddr != "10.0.0.1" {
+				t.Errorf("RemoteAddr = %q, want 10.0.0.1", res.RemoteAddr)
+			}
+		})
+	}
+}
+
+func TestClientIPFieldInvalid(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{"client_ip_field": ""}); err == nil {
+		t.Error("expected an error for an empty client_ip_field")
+	}
+}
+
+func TestUpstreamTimings(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default format with the remaining upstream timings appended
+	factory.logFormat += ` $upstream_connect_time $upstream_header_time`
+
+	tests := []struct {
+		name         string
+		line         string
+		wantConnect  []float64
+		wantHeader   []float64
+		wantResponse []float64
+	}{
+		{
+			name:         "single upstream",
+			line:         testAccessLine + ` 0.010 0.200`,
+			wantConnect:  []float64{0.01},
+			wantHeader:   []float64{0.2},
+			wantResponse: []float64{0.25},
+		},
+		{
+			name:         "no upstream",
+			line:         `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/7.68.0" 120 0.300 [default-api-80] [] - - - 200 req1 - -`,
+			wantConnect:  nil,
+			wantHeader:   nil,
+			wantResponse: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := factory.New().Parse(tt.line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(res.UpstreamConnectTimes, tt.wantConnect) {
+				t.Errorf("UpstreamConnectTimes = %v, want %v", res.UpstreamConnectTimes, tt.wantConnect)
+			}
+
+			if !reflect.DeepEqual(res.UpstreamHeaderTimes, tt.wantHeader) {
+				t.Errorf("UpstreamHeaderTimes = %v, want %v", res.UpstreamHeaderTimes, tt.wantHeader)
+			}
+
+			if !reflect.DeepEqual(res.UpstreamResponseTimes, tt.wantResponse) {
+				t.Errorf("UpstreamResponseTimes = %v, want %v", res.UpstreamResponseTimes, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestUpstreamTimingsMissingFields(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{})
+
+	res, err := p.Parse(testAccessLine)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default format doesn't log the connect and header times
+	if res.UpstreamConnectTimes != nil || res.UpstreamHeaderTimes != nil {
+		t.Errorf("got connect times %v and header times %v for fields missing from the format",
+			res.UpstreamConnectTimes, res.UpstreamHeaderTimes)
+	}
+}
+
+func TestToFloat64List(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []float64
+	}{
+		{"float", 0.25, []float64{0.25}},
+		{"integer", int64(1), []float64{1}},
+		{"retried upstreams", "0.100, 0.150", []float64{0.1, 0.15}},
+		{"internal redirect", "0.100 : 0.150, 0.200", []float64{0.1, 0.15, 0.2}},
+		{"missing values", "- : 0.150", []float64{0.15}},
+		{"no values", "-", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toFloat64List(map[string]interface{}{"upstream_response_time": tt.value}, "upstream_response_time")
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toFloat64List(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if got := toFloat64List(map[string]interface{}{}, "upstream_response_time"); got != nil {
+		t.Errorf("toFloat64List of a missing field = %v, want nil", got)
+	}
+}
+
+func TestNoUpstreamFallback(t *testing.T) {
+	const (
+		// the ingress controller logs a 503 without an upstream when no endpoint is ready
+		noUpstream = `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 503 0 "-" "curl/7.68.0" 120 0.000 [default-api-80] [] - - - - req1`
+		// the upstream status is logged, but not the address
+		noUpstreamAddr = `10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 503 0 "-" "curl/7.68.0" 120 0.000 [default-api-80] [] - 0 - 503 req1`
+	)
+
+	tests := []struct {
+		name             string
+		fallback         bool
+		line             string
+		wantErr          bool
+		wantUpstreamAddr string
+		wantStatus       int64
+		wantTimedOut     bool
+	}{
+		{"off", false, noUpstreamAddr, false, "0.0.0.0", 503, false},
+		{"off without an upstream status", false, noUpstream, true, "", 0, false},
+		{"on", true, noUpstreamAddr, false, "", 503, true},
+		{"on without an upstream status", true, noUpstream, false, "", 503, true},
+		{"on with an upstream", true, testAccessLine, false, "10.1.0.5:8080", 200, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, map[string]interface{}{"no_upstream_fallback": tt.fallback})
+
+			res, err := p.Parse(tt.line)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected the line to be dropped, got %+v", res)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.UpstreamAddr != tt.wantUpstreamAddr {
+				t.Errorf("UpstreamAddr = %q, want %q", res.UpstreamAddr, tt.wantUpstreamAddr)
+			}
+
+			if res.UpstreamStatus != tt.wantStatus {
+				t.Errorf("UpstreamStatus = %d, want %d", res.UpstreamStatus, tt.wantStatus)
+			}
+
+			if res.TimedOut != tt.wantTimedOut {
+				t.Errorf("TimedOut = %t, want %t", res.TimedOut, tt.wantTimedOut)
+			}
+		})
+	}
+}
+
+func TestNoUpstreamFallbackInvalid(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{"no_upstream_fallback": "yes"}); err == nil {
+		t.Error("expected an error for a non-bool no_upstream_fallback")
+	}
+}
+
+func TestUpstreamStatusNoResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		wantStatus   int64
+		wantTimedOut bool
+	}{
+		{"000", "000", StatusNoResponse, true},
+		{"0", "0", StatusNoResponse, true},
+		{"response", "504", 504, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, map[string]interface{}{})
+
+			res, err := p.Parse(`10.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 504 0 "-" "curl/7.68.0" 120 60.001 [default-api-80] [] 10.1.0.5:8080 0 60.000 ` + tt.status + ` req1`)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.UpstreamStatus != tt.wantStatus {
+				t.Errorf("UpstreamStatus = %d, want %d", res.UpstreamStatus, tt.wantStatus)
+			}
+
+			if res.TimedOut != tt.wantTimedOut {
+				t.Errorf("TimedOut = %t, want %t", res.TimedOut, tt.wantTimedOut)
+			}
+		})
+	}
+}
+
+func TestFormatPresetIngressXFF(t *testing.T) {
+	tests := []struct {
+		name              string
+		line              string
+		wantXForwardedFor string
+		wantHost          string
+	}{
+		{"forwarded", testAccessLine + ` "203.0.113.7, 10.0.0.1" api.example.com`, "203.0.113.7, 10.0.0.1", "api.example.com"},
+		{"not forwarded", testAccessLine + ` "-" api.example.com`, "", "api.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, map[string]interface{}{"format_preset": "ingress-xff"})
+
+			res, err := p.Parse(tt.line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.XForwardedFor != tt.wantXForwardedFor {
+				t.Errorf("XForwardedFor = %q, want %q", res.XForwardedFor, tt.wantXForwardedFor)
+			}
+
+			if res.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", res.Host, tt.wantHost)
+			}
+
+			if res.Request.Path != "/api" || res.UpstreamStatus != 200 {
+				t.Errorf("request = %s with upstream status %d, want /api with 200", res.Request.Path, res.UpstreamStatus)
+			}
+		})
+	}
+}
+
+func TestFormatPresetDefault(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{"format_preset": "ingress"})
+
+	res, err := p.Parse(testAccessLine)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.XForwardedFor != "" || res.Host != "" {
+		t.Errorf("got XForwardedFor %q and Host %q from a format without them", res.XForwardedFor, res.Host)
+	}
+
+	// the default format doesn't match lines of the xff variant
+	if _, err := p.Parse(testAccessLine + ` "203.0.113.7" api.example.com`); err == nil {
+		t.Error("expected an error parsing an ingress-xff line with the ingress preset")
+	}
+}
+
+func TestFormatPresetInvalid(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{"format_preset": "apache"}); err == nil {
+		t.Error("expected an error for an unknown format preset")
+	}
+}
+
+func TestBodyBytesSent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int64
+	}{
+		{"bytes", testAccessLine, 512},
+		{"dash", strings.Replace(testAccessLine, `200 512 "-"`, `200 - "-"`, 1), 0},
+	}
+
+	p := newTestParser(t, map[string]interface{}{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := p.Parse(tt.line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.BodyBytesSent != tt.want {
+				t.Errorf("BodyBytesSent = %d, want %d", res.BodyBytesSent, tt.want)
+			}
+
+			if res.RequestTime != 0.3 {
+				t.Errorf("RequestTime = %g, want 0.3", res.RequestTime)
+			}
+		})
+	}
+}
+
+func TestUpstreamResponseLengths(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// quote the lengths, so the lengths of retried requests are a single field
+	factory.logFormat = strings.Replace(factory.logFormat, "$upstream_response_length", `"$upstream_response_length"`, 1)
+
+	tests := []struct {
+		name    string
+		lengths string
+		want    []int64
+	}{
+		{"single", "512", []int64{512}},
+		{"retried", "0, 512", []int64{0, 512}},
+		{"retried in another upstream group", "0 : 512", []int64{0, 512}},
+		{"no upstream", "-", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := strings.Replace(testAccessLine, "10.1.0.5:8080 512", `10.1.0.5:8080 "`+tt.lengths+`"`, 1)
+
+			res, err := factory.New().Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(res.UpstreamResponseLengths, tt.want) {
+				t.Errorf("UpstreamResponseLengths = %v, want %v", res.UpstreamResponseLengths, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorFormats(t *testing.T) {
+	const (
+		// logged by older ingress-nginx versions once an upstream was picked
+		withUpstream = `2026/10/14 10:00:00 [error] 31#31: *1234 upstream timed out (110: Operation timed out) while reading response header from upstream, client: 10.0.0.1, server: api.example.com, request: "GET /api HTTP/1.1", upstream: "http://10.1.0.5:8080/api", host: "api.example.com"`
+		// newer versions append the referrer of requests sent with one
+		withReferrer = withUpstream + `, referrer: "https://example.com/"`
+		// the upstream clause is left out when no upstream was picked
+		noUpstream         = `2026/10/14 10:00:00 [error] 31#31: *1235 no live upstreams while connecting to upstream, client: 10.0.0.1, server: api.example.com, request: "GET /api HTTP/1.1", host: "api.example.com"`
+		noUpstreamReferrer = noUpstream + `, referrer: "https://example.com/"`
+	)
+
+	tests := []struct {
+		name        string
+		formats     []string
+		line        string
+		wantErr     bool
+		wantMessage string
+		// the error formats read the upstream address from the client clause, unless the
+		// line has an upstream clause
+		wantUpstreamAddr string
+	}{
+		{"upstream", nil, withUpstream, false, "upstream timed out (110: Operation timed out) while reading response header from upstream", "10.1.0.5:8080"},
+		{"referrer", nil, withReferrer, false, "upstream timed out (110: Operation timed out) while reading response header from upstream", "10.1.0.5:8080"},
+		{"no upstream", nil, noUpstream, false, "no live upstreams while connecting to upstream", "10.0.0.1"},
+		{"no upstream with referrer", nil, noUpstreamReferrer, false, "no live upstreams while connecting to upstream", "10.0.0.1"},
+		{"selected format", []string{"ingress"}, withUpstream, false, "upstream timed out (110: Operation timed out) while reading response header from upstream", "10.1.0.5:8080"},
+		{"unselected format", []string{"ingress"}, noUpstream, true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := map[string]interface{}{}
+
+			if tt.formats != nil {
+				options["error_formats"] = tt.formats
+			}
+
+			res, err := newTestParser(t, options).Parse(tt.line)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected the line to be dropped, got %+v", res)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.ErrorMessage != tt.wantMessage {
+				t.Errorf("ErrorMessage = %q, want %q", res.ErrorMessage, tt.wantMessage)
+			}
+
+			if res.Request.Path != "/api" || res.Host != "api.example.com" {
+				t.Errorf("request = %s on %s, want /api on api.example.com", res.Request.Path, res.Host)
+			}
+
+			if !res.TimedOut {
+				t.Error("error line not counted as a timeout")
+			}
+
+			if res.UpstreamAddr != tt.wantUpstreamAddr {
+				t.Errorf("UpstreamAddr = %q, want %q", res.UpstreamAddr, tt.wantUpstreamAddr)
+			}
+		})
+	}
+}
+
+func TestErrorFormatsInvalid(t *testing.T) {
+	for _, formats := range []interface{}{[]string{"ingress", "nginx"}, []string{}, "ingress"} {
+		factory := &NginxParserFactory{}
+
+		if err := factory.Init(map[string]interface{}{"error_formats": formats}); err == nil {
+			t.Errorf("expected an error for error formats %v", formats)
+		}
+	}
+}
+
+func TestFormatOptionsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		wantErr string
+	}{
+		{"empty log format", map[string]interface{}{"log_format": ""}, "option log_format must be a non-empty string"},
+		{"log format not a string", map[string]interface{}{"log_format": 1}, "option log_format must be a non-empty string"},
+		// a field followed by a trailing backslash doesn't compile
+		{"invalid log format", map[string]interface{}{"log_format": `$remote_addr $status\`}, "option log_format: format does not compile"},
+		{"empty err log format", map[string]interface{}{"err_log_format": ""}, "option err_log_format must be a non-empty string"},
+		{"invalid err log format", map[string]interface{}{"err_log_format": `$time_local $message\`}, "option err_log_format: format does not compile"},
+		{
+			"err log format with error formats",
+			map[string]interface{}{"err_log_format": "$time_local $message", "error_formats": []string{"ingress"}},
+			"option err_log_format can't be combined with error_formats",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := &NginxParserFactory{}
+			err := factory.Init(tt.options)
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Init() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResultStatusClasses(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int64
+		timedOut        bool
+		wantError       bool
+		wantClientError bool
+		wantSuccess     bool
+	}{
+		{"2XX", 200, false, false, false, true},
+		{"3XX", 304, false, false, false, true},
+		{"4XX", 404, false, false, true, false},
+		{"5XX", 502, false, true, false, false},
+		// the parser marks requests without a response as timed out
+		{"no response", StatusNoResponse, true, true, false, false},
+		{"timed out with a 2XX", 200, true, true, false, false},
+		{"timed out with a 4XX", 499, true, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &NginxResult{UpstreamStatus: tt.status, TimedOut: tt.timedOut}
+
+			if got := res.IsError(); got != tt.wantError {
+				t.Errorf("IsError() = %t, want %t", got, tt.wantError)
+			}
+
+			if got := res.IsClientError(); got != tt.wantClientError {
+				t.Errorf("IsClientError() = %t, want %t", got, tt.wantClientError)
+			}
+
+			if got := res.IsSuccess(); got != tt.wantSuccess {
+				t.Errorf("IsSuccess() = %t, want %t", got, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReferer(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"external", strings.Replace(testAccessLine, `512 "-"`, `512 "https://www.example.com/pricing"`, 1), "https://www.example.com/pricing"},
+		{"missing", testAccessLine, ""},
+	}
+
+	p := newTestParser(t, map[string]interface{}{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := p.Parse(tt.line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Referer != tt.want {
+				t.Errorf("Referer = %q, want %q", res.Referer, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestStringToReq(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   string
+		wantErr   bool
+		wantPath  string
+		wantQuery string
+	}{
+		{"plain", "GET /a/b?x=1 HTTP/1.1", false, "/a/b", "x=1"},
+		{"spaces in the path", "GET /a b c HTTP/1.1", false, "/a b c", ""},
+		{"spaces in the query", "GET /search?q=a b HTTP/1.1", false, "/search", "q=a%20b"},
+		{"missing protocol", "GET /a", true, "", ""},
+		{"method only", "GET", true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := requestStringToReq(tt.request, false)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", req)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != "GET" || req.Path != tt.wantPath || req.Query != tt.wantQuery {
+				t.Errorf("request = %s %q ? %q, want GET %q ? %q", req.Method, req.Path, req.Query, tt.wantPath, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestParseRequestWithSpaces(t *testing.T) {
+	line := strings.Replace(testAccessLine, `"GET /api HTTP/1.1"`, `"GET /a b c HTTP/1.1"`, 1)
+
+	res, err := newTestParser(t, map[string]interface{}{}).Parse(line)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Request.Path != "/a b c" {
+		t.Errorf("Path = %q, want %q", res.Request.Path, "/a b c")
+	}
+}
+
+func TestCollapseTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   string
+		collapse  bool
+		wantPath  string
+		wantQuery string
+	}{
+		{"connect", "CONNECT api.example.com:443 HTTP/1.1", true, "/", ""},
+		{"absolute uri", "GET https://api.example.com:443/a/b?x=1 HTTP/1.1", true, "/a/b", "x=1"},
+		{"absolute uri without a path", "GET http://api.example.com HTTP/1.1", true, "/", ""},
+		{"origin form", "GET /a/b HTTP/1.1", true, "/a/b", ""},
+		// without collapsing, the host and port end up in the path
+		{"absolute uri not collapsed", "GET https://api.example.com:443/a/b HTTP/1.1", false, "//api.example.com:443/a/b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := strings.Replace(testAccessLine, `"GET /api HTTP/1.1"`, `"`+tt.request+`"`, 1)
+
+			res, err := newTestParser(t, map[string]interface{}{"collapse_absolute_targets": tt.collapse}).Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Request.Path != tt.wantPath || res.Request.Query != tt.wantQuery {
+				t.Errorf("request = %q ? %q, want %q ? %q", res.Request.Path, res.Request.Query, tt.wantPath, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestRoutingFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		upstreamName string
+		want         bool
+	}{
+		{"routed", "default-api-80", false},
+		{"default backend", DefaultBackendUpstream, true},
+		{"empty", "", true},
+		{"dash", "-", true},
+	}
+
+	p := newTestParser(t, map[string]interface{}{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := strings.Replace(testAccessLine, "[default-api-80]", "["+tt.upstreamName+"]", 1)
+
+			res, err := p.Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.RoutingFailure != tt.want {
+				t.Errorf("RoutingFailure = %t, want %t for upstream name %q", res.RoutingFailure, tt.want, tt.upstreamName)
+			}
+		})
+	}
+}
+
+func TestRoutingFailureWithoutUpstreamName(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{
+		"log_format": `$remote_addr [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+	})
+
+	res, err := p.Parse(`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 0.300 10.1.0.5:8080 200`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RoutingFailure {
+		t.Error("routing failure reported for a format without upstream names")
+	}
+}
+
+func TestRequestTimeUnits(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{
+		"log_format": `$remote_addr [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+	})
+
+	tests := []struct {
+		requestTime string
+		want        float64
+	}{
+		{"0.123", 0.123},
+		{"0.123s", 0.123},
+		{"123ms", 0.123},
+		{"2", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.requestTime, func(t *testing.T) {
+			res, err := p.Parse(`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 ` + tt.requestTime + ` 10.1.0.5:8080 200`)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if math.Abs(res.RequestTime-tt.want) > 1e-9 {
+				t.Errorf("request time = %v, want %v", res.RequestTime, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTimeUnitsInvalid(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{
+		"log_format": `$remote_addr [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+	})
+
+	if _, err := p.Parse(`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 123us 10.1.0.5:8080 200`); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+}
+
+func TestRemoteUserWithSpaces(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		want string
+	}{
+		{"no user", "-", ""},
+		{"plain", "alice", "alice"},
+		{"space", "alice smith", "alice smith"},
+		{"spaces and brackets", "svc [ci] bot", "svc [ci] bot"},
+	}
+
+	p := newTestParser(t, map[string]interface{}{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := strings.Replace(testAccessLine, "10.0.0.1 - - ", "10.0.0.1 - "+tt.user+" ", 1)
+			res, err := p.Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.RemoteUser != tt.want {
+				t.Errorf("RemoteUser = %q, want %q", res.RemoteUser, tt.want)
+			}
+
+			if res.Request.Path != "/api" || res.RequestTime != 0.3 || res.UpstreamStatus != 200 {
+				t.Errorf("fields after the remote user misaligned: %s %g %d", res.Request.Path, res.RequestTime, res.UpstreamStatus)
+			}
+		})
+	}
+}
+
+func TestRemoteUserWithSpacesOtherFormat(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{
+		"log_format": `$remote_addr $remote_user [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+	})
+
+	// only formats beginning like the combined format are recovered
+	if _, err := p.Parse(`10.0.0.1 alice smith [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 0.300 10.1.0.5:8080 200`); err == nil {
+		t.Error("expected an error for a remote user with spaces in another format")
+	}
+}
+
+func TestDecimalComma(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{"decimal_comma": true})
+
+	line := strings.Replace(testAccessLine, "120 0.300 ", "120 0,300 ", 1)
+	line = strings.Replace(line, "512 0.250 200", "512 0,250 200", 1)
+
+	res, err := p.Parse(line)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RequestTime != 0.3 {
+		t.Errorf("RequestTime = %g, want 0.3", res.RequestTime)
+	}
+
+	if !reflect.DeepEqual(res.UpstreamResponseTimes, []float64{0.25}) {
+		t.Errorf("UpstreamResponseTimes = %v, want [0.25]", res.UpstreamResponseTimes)
+	}
+
+	// without the option, comma decimals don't parse
+	if _, err := newTestParser(t, map[string]interface{}{}).Parse(line); err == nil {
+		t.Error("expected an error for comma decimals without decimal_comma")
+	}
+}
+
+func TestDecimalCommaUnits(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{
+		"log_format":    `$remote_addr [$time_local] "$request" $status $request_time $upstream_addr $upstream_status`,
+		"decimal_comma": true,
+	})
+
+	tests := []struct {
+		requestTime string
+		want        float64
+	}{
+		{"0,123", 0.123},
+		{"0,123s", 0.123},
+		{"123,5ms", 0.1235},
+		{"0.123", 0.123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.requestTime, func(t *testing.T) {
+			res, err := p.Parse(`10.0.0.1 [14/Oct/2026:10:00:00 +0000] "GET /api HTTP/1.1" 200 ` + tt.requestTime + ` 10.1.0.5:8080 200`)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if math.Abs(res.RequestTime-tt.want) > 1e-9 {
+				t.Errorf("request time = %v, want %v", res.RequestTime, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalCommaInvalid(t *testing.T) {
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{"decimal_comma": "yes"}); err == nil {
+		t.Error("expected an error for a non-bool decimal_comma")
+	}
+}
+
+func TestSanitizeUTF8(t *testing.T) {
+	line := strings.Replace(testAccessLine, `"curl/7.68.0"`, "\"curl/\xff\xfe7.68.0\"", 1)
+
+	tests := []struct {
+		name      string
+		sanitize  bool
+		wantAgent string
+	}{
+		{"off", false, "curl/\xff\xfe7.68.0"},
+		{"on", true, "curl/�7.68.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(t, map[string]interface{}{"sanitize_utf8": tt.sanitize})
+
+			_, fields, err := p.ParseWithFields(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if agent := fields["http_user_agent"]; agent != tt.wantAgent {
+				t.Errorf("http_user_agent = %q, want %q", agent, tt.wantAgent)
+			}
+		})
+	}
+}
+
+func TestParseStreamLine(t *testing.T) {
+	p := newTestParser(t, map[string]interface{}{})
+
+	res, err := p.Parse(`[10.0.0.9] [14/Oct/2026:10:00:00 +0000] TCP 200 1024 256 1.500`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &NginxResult{
+		RemoteAddr:     "10.0.0.9",
+		ClientIP:       "10.0.0.9",
+		TimeLocal:      time.Date(2026, 10, 14, 10, 0, 0, 0, time.FixedZone("", 0)),
+		Stream:         true,
+		Protocol:       "TCP",
+		Status:         200,
+		UpstreamStatus: 200,
+		BodyBytesSent:  1024,
+		RequestLength:  256,
+		RequestTime:    1.5,
+	}
+
+	if !res.TimeLocal.Equal(want.TimeLocal) {
+		t.Errorf("TimeLocal = %v, want %v", res.TimeLocal, want.TimeLocal)
+	}
+
+	res.TimeLocal = want.TimeLocal
+
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("result = %+v, want %+v", res, want)
+	}
+}
+
+func TestErrorLogRequestClause(t *testing.T) {
+	const (
+		prefix   = `2026/10/14 10:00:00 [error] 31#31: *12345 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 10.0.0.1, server: example.com, `
+		upstream = `, upstream: "http://10.1.0.5:8080/api/users?id=1"`
+		host     = `, host: "example.com"`
+		referrer = `, referrer: "https://example.com/home"`
+	)
+
+	tests := []struct {
+		name         string
+		request      string
+		upstream     bool
+		referrer     bool
+		wantUpstream string
+		wantReferer  string
+	}{
+		{name: "quoted", request: `"GET /api/users?id=1 HTTP/1.1"`, upstream: true, wantUpstream: "10.1.0.5:8080"},
+		{name: "unquoted", request: `GET /api/users?id=1 HTTP/1.1`, upstream: true, wantUpstream: "10.1.0.5:8080"},
+		{name: "quoted referrer", request: `"GET /api/users?id=1 HTTP/1.1"`, upstream: true, referrer: true, wantUpstream: "10.1.0.5:8080", wantReferer: "https://example.com/home"},
+		{name: "unquoted referrer", request: `GET /api/users?id=1 HTTP/1.1`, upstream: true, referrer: true, wantUpstream: "10.1.0.5:8080", wantReferer: "https://example.com/home"},
+		{name: "quoted no upstream", request: `"GET /api/users?id=1 HTTP/1.1"`, wantUpstream: "10.0.0.1"},
+		{name: "unquoted no upstream", request: `GET /api/users?id=1 HTTP/1.1`, wantUpstream: "10.0.0.1"},
+		{name: "quoted no upstream referrer", request: `"GET /api/users?id=1 HTTP/1.1"`, referrer: true, wantUpstream: "10.0.0.1", wantReferer: "https://example.com/home"},
+		{name: "unquoted no upstream referrer", request: `GET /api/users?id=1 HTTP/1.1`, referrer: true, wantUpstream: "10.0.0.1", wantReferer: "https://example.com/home"},
+	}
+
+	p := newTestParser(t, map[string]interface{}{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := prefix + "request: " + tt.request
+
+			if tt.upstream {
+				line += upstream
+			}
+
+			line += host
+
+			if tt.referrer {
+				line += referrer
+			}
+
+			res, err := p.Parse(line)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Request.Method != "GET" || res.Request.Path != "/api/users" {
+				t.Errorf("request = %s %s, want GET /api/users", res.Request.Method, res.Request.Path)
+			}
+
+			if res.UpstreamAddr != tt.wantUpstream {
+				t.Errorf("upstream = %q, want %q", res.UpstreamAddr, tt.wantUpstream)
+			}
+
+			if res.Referer != tt.wantReferer {
+				t.Errorf("referer = %q, want %q", res.Referer, tt.wantReferer)
+			}
+
+			if res.Host != "example.com" || !res.TimedOut {
+				t.Errorf("host = %q, timed out %t, want example.com and a time out", res.Host, res.TimedOut)
+			}
+		})
+	}
+}
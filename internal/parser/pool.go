@@ -0,0 +1,28 @@
+package parser
+
+import "sync"
+
+// ParserPool hands out NginxParser instances built by a factory to concurrent workers
+type ParserPool struct {
+	pool sync.Pool
+}
+
+func NewParserPool(factory *NginxParserFactory) *ParserPool {
+	return &ParserPool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				return factory.New()
+			},
+		},
+	}
+}
+
+// Get returns a parser reserved for the caller until it is passed to Put
+func (p *ParserPool) Get() *NginxParser {
+	return p.pool.Get().(*NginxParser)
+}
+
+// Put returns a parser to the pool. The caller must not use the parser afterwards.
+func (p *ParserPool) Put(parser *NginxParser) {
+	p.pool.Put(parser)
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestParserPoolConcurrent parses from many goroutines through the pool, which go test
+// -race checks for data races
+func TestParserPoolConcurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		lines   int
+	}{
+		{"one worker", 1, 200},
+		{"a few workers", 4, 200},
+		{"many workers", 32, 50},
+	}
+
+	factory := &NginxParserFactory{}
+
+	if err := factory.Init(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewParserPool(factory)
+			errs := make(chan error, tt.workers)
+
+			var wg sync.WaitGroup
+
+			for i := 0; i < tt.workers; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for j := 0; j < tt.lines; j++ {
+						p := pool.Get()
+						res, err := p.Parse(testAccessLine)
+						pool.Put(p)
+
+						if err != nil {
+							errs <- err
+							return
+						}
+
+						if res.Request.Path != "/api" || res.RequestTime != 0.3 || res.UpstreamStatus != 200 {
+							t.Errorf("parsed %s in %g with %d, want /api in 0.3 with 200", res.Request.Path, res.RequestTime, res.UpstreamStatus)
+							return
+						}
+					}
+				}()
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Errorf("line dropped: %v", err)
+			}
+		})
+	}
+}
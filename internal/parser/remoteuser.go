@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/honeycombio/gonx"
+)
+
+// remoteUserPrefix is how the combined format, and the ingress format based on it, begin
+const remoteUserPrefix = "$remote_addr - $remote_user [$time_local]"
+
+// remoteUserPattern captures the remote user of a line up to the timestamp
+var remoteUserPattern = regexp.MustCompile(`^(\S+ - )(.*?) (\[\d{2}/[A-Za-z]{3}/\d{4}:)`)
+
+// parseWithRemoteUser parses a line nginx logged with spaces in the remote user
+func (p *NginxParser) parseWithRemoteUser(line string) (*gonx.Entry, bool) {
+	match := remoteUserPattern.FindStringSubmatchIndex(line)
+
+	if match == nil {
+		return nil, false
+	}
+
+	user := line[match[4]:match[5]]
+
+	if !strings.Contains(user, " ") {
+		return nil, false
+	}
+
+	escaped := line[:match[4]] + strings.ReplaceAll(user, " ", "_") + line[match[5]:]
+	entry, err := p.gonxParser.ParseString(escaped)
+
+	if err != nil {
+		return nil, false
+	}
+
+	entry.SetField("remote_user", user)
+
+	return entry, true
+}
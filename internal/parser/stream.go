@@ -0,0 +1,49 @@
+package parser
+
+import "time"
+
+// nginxIngressStreamLogFormat is the ingress-nginx log format of L4 TCP and UDP services
+const nginxIngressStreamLogFormat = `[$remote_addr] [$time_local] $protocol $status $bytes_sent $bytes_received $session_time`
+
+// parsedStreamLineToResult converts a stream log line
+func (p *NginxParser) parsedStreamLineToResult(line map[string]interface{}) (*NginxResult, error) {
+	res := &NginxResult{Stream: true}
+	var err error
+
+	res.RemoteAddr, _ = toString(line, "remote_addr")
+	res.ClientIP = res.RemoteAddr
+
+	if res.Protocol, err = toString(line, "protocol"); err != nil {
+		return nil, err
+	}
+
+	if res.Status, err = toInt64(line, "status"); err != nil {
+		return nil, err
+	}
+
+	res.UpstreamStatus = res.Status
+
+	if res.BodyBytesSent, err = toByteCount(line, "bytes_sent"); err != nil {
+		return nil, err
+	}
+
+	if res.RequestLength, err = toByteCount(line, "bytes_received"); err != nil {
+		return nil, err
+	}
+
+	if res.RequestTime, err = toSeconds(line, "session_time"); err != nil {
+		return nil, err
+	}
+
+	timeLocal, err := toString(line, "time_local")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.TimeLocal, err = time.Parse(p.timeLayout, timeLocal); err != nil {
+		return nil, &ConversionError{"time_local", err.Error()}
+	}
+
+	return res, nil
+}
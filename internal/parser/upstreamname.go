@@ -0,0 +1,49 @@
+package parser
+
+import "strings"
+
+// UpstreamName is an ingress-nginx upstream name split into the service it routes to
+type UpstreamName struct {
+	Namespace string
+	Service   string
+	// Port is the service port, which is a number or a port name
+	Port string
+}
+
+// ParseUpstreamName splits an upstream name like <namespace>-<service>-<port>
+func ParseUpstreamName(name string, namespaces []string) (*UpstreamName, bool) {
+	if name == DefaultBackendUpstream {
+		return nil, false
+	}
+
+	portIndex := strings.LastIndex(name, "-")
+
+	if portIndex <= 0 || portIndex == len(name)-1 {
+		return nil, false
+	}
+
+	rest, port := name[:portIndex], name[portIndex+1:]
+	namespace := ""
+
+	for _, ns := range namespaces {
+		if len(ns) > len(namespace) && strings.HasPrefix(rest, ns+"-") && len(rest) > len(ns)+1 {
+			namespace = ns
+		}
+	}
+
+	if namespace == "" {
+		i := strings.Index(rest, "-")
+
+		if i <= 0 || i == len(rest)-1 {
+			return nil, false
+		}
+
+		namespace = rest[:i]
+	}
+
+	return &UpstreamName{
+		Namespace: namespace,
+		Service:   rest[len(namespace)+1:],
+		Port:      port,
+	}, true
+}
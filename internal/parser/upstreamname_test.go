@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUpstreamName(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   string
+		namespaces []string
+		want       *UpstreamName
+	}{
+		{"typical", "default-api-80", nil, &UpstreamName{"default", "api", "80"}},
+		{"named port", "shop-orders-http", nil, &UpstreamName{"shop", "orders", "http"}},
+		{"hyphenated service", "shop-order-service-8080", nil, &UpstreamName{"shop", "order-service", "8080"}},
+		{"hyphenated namespace", "team-a-order-service-8080", []string{"team-a"}, &UpstreamName{"team-a", "order-service", "8080"}},
+		{"longest namespace", "team-a-b-api-80", []string{"team-a", "team-a-b"}, &UpstreamName{"team-a-b", "api", "80"}},
+		{"namespace without service", "team-a-80", []string{"team-a"}, &UpstreamName{"team", "a", "80"}},
+		{"unknown namespace", "team-a-api-80", []string{"other-ns"}, &UpstreamName{"team", "a-api", "80"}},
+		{"default backend", DefaultBackendUpstream, nil, nil},
+		{"two segments", "api-80", nil, nil},
+		{"one segment", "api", nil, nil},
+		{"trailing hyphen", "default-api-", nil, nil},
+		{"empty", "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseUpstreamName(tt.upstream, tt.namespaces)
+
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseUpstreamName(%q) ok = %v, want %v", tt.upstream, ok, tt.want != nil)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseUpstreamName(%q) = %+v, want %+v", tt.upstream, got, tt.want)
+			}
+		})
+	}
+}
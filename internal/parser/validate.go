@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/honeycombio/gonx"
+)
+
+// formatFieldRegexp matches the fields of a format the way gonx does
+var formatFieldRegexp = regexp.MustCompile(`\$([A-Za-z0-9_]+)`)
+
+// KnownFields are the fields of the ingress formats
+var KnownFields = map[string]bool{
+	"remote_addr":                     true,
+	"remote_user":                     true,
+	"time_local":                      true,
+	"request":                         true,
+	"status":                          true,
+	"body_bytes_sent":                 true,
+	"http_referer":                    true,
+	"http_user_agent":                 true,
+	"request_length":                  true,
+	"request_time":                    true,
+	"proxy_upstream_name":             true,
+	"proxy_alternative_upstream_name": true,
+	"upstream_addr":                   true,
+	"upstream_response_length":        true,
+	"upstream_connect_time":           true,
+	"upstream_header_time":            true,
+	"upstream_response_time":          true,
+	"upstream_status":                 true,
+	"req_id":                          true,
+	"http_x_forwarded_for":            true,
+	"host":                            true,
+}
+
+// ValidateFormat lints an access log format
+func ValidateFormat(format string) []error {
+	problems := make([]error, 0)
+
+	if err := compileFormat(format); err != nil {
+		problems = append(problems, err)
+	}
+
+	matches := formatFieldRegexp.FindAllStringSubmatch(format, -1)
+
+	if len(matches) == 0 {
+		problems = append(problems, fmt.Errorf("format has no fields"))
+	}
+
+	seen := make(map[string]int)
+
+	for _, match := range matches {
+		field := match[1]
+		seen[field]++
+
+		if seen[field] > 1 {
+			if seen[field] == 2 {
+				problems = append(problems, fmt.Errorf("duplicate field $%s", field))
+			}
+
+			continue
+		}
+
+		if !KnownFields[field] {
+			problems = append(problems, fmt.Errorf("unknown field $%s", field))
+		}
+	}
+
+	return problems
+}
+
+// compileFormat returns an error if gonx can't compile the format
+func compileFormat(format string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("format does not compile: %v", r)
+		}
+	}()
+
+	gonx.NewParser(format)
+
+	return nil
+}
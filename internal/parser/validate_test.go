@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{"ingress", nginxIngressLogFormat, []string{}},
+		{"ingress xff", nginxIngressXFFLogFormat, []string{}},
+		{"typo", `$remote_addr [$time_local] "$request" $staus $request_time`, []string{"unknown field $staus"}},
+		{"duplicate", `$remote_addr $status $status $status $request_time`, []string{"duplicate field $status"}},
+		{"no fields", `static text`, []string{"format has no fields"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+
+			for _, problem := range ValidateFormat(tt.format) {
+				got = append(got, problem.Error())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
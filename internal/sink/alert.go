@@ -0,0 +1,279 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/metric"
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// AlertConfig configures when AlertSink posts alerts
+type AlertConfig struct {
+	URL string
+
+	// ErrorRate and TimeoutRate are the rates over the window to alert above
+	ErrorRate   float64
+	TimeoutRate float64
+
+	// ErrorRateExcludeStatus leaves statuses in any of the ranges out of the error rate
+	ErrorRateExcludeStatus []metric.StatusRange
+
+	// Window is how far back rates are computed
+	Window      time.Duration
+	MinRequests int
+
+	// Cooldown is the minimum time between two alerts of the same group
+	Cooldown time.Duration
+}
+
+var DefaultAlertConfig = AlertConfig{
+	ErrorRate:   0.05,
+	TimeoutRate: 0.05,
+	Window:      time.Minute,
+	MinRequests: 20,
+	Cooldown:    5 * time.Minute,
+}
+
+// Alert is the JSON payload posted to the webhook
+type Alert struct {
+	Group       string    `json:"group"`
+	Time        time.Time `json:"time"`
+	Requests    int       `json:"requests"`
+	Window      float64   `json:"window_seconds"`
+	ErrorRate   float64   `json:"error_rate"`
+	TimeoutRate float64   `json:"timeout_rate"`
+	// Exceeded holds the names of the rates above their threshold
+	Exceeded []string `json:"exceeded"`
+}
+
+// AlertSink posts an alert to a webhook when the rates of a group cross a threshold
+type AlertSink struct {
+	cfg    AlertConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*alertWindow
+	lastAlert map[string]time.Time
+	lastEvict time.Time
+	failed    int
+	lastErr   error
+
+	wg sync.WaitGroup
+}
+
+// alertWindow holds the counts of a group's recent requests in one second buckets
+type alertWindow struct {
+	buckets []*alertBucket
+}
+
+// alertBucket counts the requests of one second
+type alertBucket struct {
+	second    int64
+	total     int
+	responses int
+	errors    int
+	timeouts  int
+}
+
+func NewAlertSink(cfg AlertConfig) *AlertSink {
+	return &AlertSink{
+		cfg:       cfg,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		now:       time.Now,
+		windows:   make(map[string]*alertWindow),
+		lastAlert: make(map[string]time.Time),
+	}
+}
+
+func (s *AlertSink) Observe(group string, result *parser.NginxResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.evict(now)
+
+	window, exists := s.windows[group]
+
+	if !exists {
+		window = &alertWindow{}
+		s.windows[group] = window
+	}
+
+	window.add(now, result, s.excludedFromErrorRate(result))
+	window.prune(now.Add(-s.cfg.Window))
+
+	alert := s.check(group, window, now)
+
+	if alert == nil {
+		return
+	}
+
+	s.lastAlert[group] = now
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.post(alert); err != nil {
+			s.mu.Lock()
+			s.failed++
+			s.lastErr = err
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// evict drops the idle windows and the cooldowns that are over
+func (s *AlertSink) evict(now time.Time) {
+	if now.Sub(s.lastEvict) < s.cfg.Window {
+		return
+	}
+
+	s.lastEvict = now
+
+	for group, window := range s.windows {
+		window.prune(now.Add(-s.cfg.Window))
+
+		if len(window.buckets) == 0 {
+			delete(s.windows, group)
+		}
+	}
+
+	for group, last := range s.lastAlert {
+		if now.Sub(last) >= s.cfg.Cooldown {
+			delete(s.lastAlert, group)
+		}
+	}
+}
+
+func (s *AlertSink) excludedFromErrorRate(result *parser.NginxResult) bool {
+	for _, r := range s.cfg.ErrorRateExcludeStatus {
+		if r.Contains(result.UpstreamStatus) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *alertWindow) add(now time.Time, result *parser.NginxResult, excluded bool) {
+	second := now.Unix()
+
+	if len(w.buckets) == 0 || w.buckets[len(w.buckets)-1].second != second {
+		w.buckets = append(w.buckets, &alertBucket{second: second})
+	}
+
+	bucket := w.buckets[len(w.buckets)-1]
+	bucket.total++
+
+	if !excluded {
+		bucket.responses++
+
+		if result.IsError() {
+			bucket.errors++
+		}
+	}
+
+	if result.TimedOut {
+		bucket.timeouts++
+	}
+}
+
+// prune drops the buckets before since
+func (w *alertWindow) prune(since time.Time) {
+	i := 0
+
+	for i < len(w.buckets) && w.buckets[i].second < since.Unix() {
+		i++
+	}
+
+	w.buckets = w.buckets[i:]
+}
+
+// check returns the alert to send for the group, or nil
+func (s *AlertSink) check(group string, window *alertWindow, now time.Time) *Alert {
+	if last, exists := s.lastAlert[group]; exists && now.Sub(last) < s.cfg.Cooldown {
+		return nil
+	}
+
+	var total, responses, errors, timeouts int
+
+	for _, bucket := range window.buckets {
+		total += bucket.total
+		responses += bucket.responses
+		errors += bucket.errors
+		timeouts += bucket.timeouts
+	}
+
+	if total == 0 || total < s.cfg.MinRequests {
+		return nil
+	}
+
+	alert := &Alert{
+		Group:       group,
+		Time:        now,
+		Requests:    total,
+		Window:      s.cfg.Window.Seconds(),
+		TimeoutRate: float64(timeouts) / float64(total),
+	}
+
+	if responses > 0 {
+		alert.ErrorRate = float64(errors) / float64(responses)
+	}
+
+	if s.cfg.ErrorRate > 0 && alert.ErrorRate > s.cfg.ErrorRate {
+		alert.Exceeded = append(alert.Exceeded, "error_rate")
+	}
+
+	if s.cfg.TimeoutRate > 0 && alert.TimeoutRate > s.cfg.TimeoutRate {
+		alert.Exceeded = append(alert.Exceeded, "timeout_rate")
+	}
+
+	if len(alert.Exceeded) == 0 {
+		return nil
+	}
+
+	return alert
+}
+
+func (s *AlertSink) post(alert *Alert) error {
+	body, err := json.Marshal(alert)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.cfg.URL, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close waits for the alerts being sent, and returns an error if any of them failed
+func (s *AlertSink) Close() error {
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed > 0 {
+		return fmt.Errorf("%d webhook alerts failed, last error: %w", s.failed, s.lastErr)
+	}
+
+	return nil
+}
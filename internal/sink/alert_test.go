@@ -0,0 +1,186 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/metric"
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// alertStep observes results of a group, after moving the clock forward
+type alertStep struct {
+	after    time.Duration
+	group    string
+	ok       int
+	errors   int
+	timeouts int
+}
+
+func TestAlertSink(t *testing.T) {
+	cfg := AlertConfig{
+		ErrorRate:   0.2,
+		TimeoutRate: 0.2,
+		Window:      time.Minute,
+		MinRequests: 10,
+		Cooldown:    5 * time.Minute,
+	}
+
+	tests := []struct {
+		name          string
+		excludeStatus []metric.StatusRange
+		steps         []alertStep
+		// want are the alerts posted, as the group and the rates exceeded
+		want []string
+	}{
+		{
+			name:  "below the thresholds",
+			steps: []alertStep{{group: "/a", ok: 16, errors: 4}},
+		},
+		{
+			name:  "below the minimum requests",
+			steps: []alertStep{{group: "/a", errors: 9}},
+		},
+		{
+			name:  "error rate",
+			steps: []alertStep{{group: "/a", ok: 10, errors: 5}},
+			want:  []string{"/a error_rate"},
+		},
+		{
+			name:  "timeout rate",
+			steps: []alertStep{{group: "/a", ok: 10, timeouts: 5}},
+			want:  []string{"/a error_rate,timeout_rate"},
+		},
+		{
+			name: "cooldown",
+			steps: []alertStep{
+				{group: "/a", ok: 10, errors: 5},
+				{after: time.Minute, group: "/a", ok: 10, errors: 10},
+				{after: 4 * time.Minute, group: "/a", ok: 10, errors: 10},
+			},
+			want: []string{"/a error_rate", "/a error_rate"},
+		},
+		{
+			name: "cooldown per group",
+			steps: []alertStep{
+				{group: "/a", ok: 10, errors: 5},
+				{group: "/b", ok: 10, errors: 5},
+			},
+			want: []string{"/a error_rate", "/b error_rate"},
+		},
+		{
+			name:          "excluded errors",
+			excludeStatus: []metric.StatusRange{{Min: 502, Max: 502}},
+			steps:         []alertStep{{group: "/a", ok: 10, errors: 5}},
+		},
+		{
+			name:          "excluded responses left out of the error rate",
+			excludeStatus: []metric.StatusRange{{Min: 200, Max: 299}},
+			steps:         []alertStep{{group: "/a", ok: 30, errors: 5}},
+			want:          []string{"/a error_rate"},
+		},
+		{
+			name: "old results leave the window",
+			steps: []alertStep{
+				{group: "/a", ok: 10, errors: 2},
+				{after: 2 * time.Minute, group: "/a", ok: 8, errors: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var alert Alert
+
+				if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+					t.Errorf("invalid alert: %v", err)
+				}
+
+				mu.Lock()
+				got = append(got, alert.Group+" "+strings.Join(alert.Exceeded, ","))
+				mu.Unlock()
+			}))
+			defer server.Close()
+
+			cfg.URL = server.URL
+			cfg.ErrorRateExcludeStatus = tt.excludeStatus
+			s := NewAlertSink(cfg)
+			now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+			s.now = func() time.Time { return now }
+
+			for _, step := range tt.steps {
+				now = now.Add(step.after)
+
+				for i := 0; i < step.ok; i++ {
+					s.Observe(step.group, &parser.NginxResult{Status: 200, UpstreamStatus: 200})
+				}
+
+				for i := 0; i < step.errors; i++ {
+					s.Observe(step.group, &parser.NginxResult{Status: 502, UpstreamStatus: 502})
+				}
+
+				for i := 0; i < step.timeouts; i++ {
+					s.Observe(step.group, &parser.NginxResult{Status: 504, UpstreamStatus: 504, TimedOut: true})
+				}
+
+				// alerts are posted in the background, so wait for them to keep their order
+				s.wg.Wait()
+			}
+
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alerts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertSinkWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := DefaultAlertConfig
+	cfg.URL = server.URL
+	cfg.MinRequests = 1
+	s := NewAlertSink(cfg)
+
+	s.Observe("/a", &parser.NginxResult{Status: 502, UpstreamStatus: 502})
+
+	if err := s.Close(); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Close() = %v, want the webhook status", err)
+	}
+}
+
+func TestAlertSinkEvictsIdleGroups(t *testing.T) {
+	cfg := DefaultAlertConfig
+	cfg.URL = "http://127.0.0.1:0"
+	s := NewAlertSink(cfg)
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for _, group := range []string{"/a", "/b", "/c"} {
+		s.Observe(group, &parser.NginxResult{Status: 200, UpstreamStatus: 200})
+	}
+
+	now = now.Add(2 * cfg.Window)
+	s.Observe("/d", &parser.NginxResult{Status: 200, UpstreamStatus: 200})
+
+	if len(s.windows) != 1 || s.windows["/d"] == nil {
+		t.Errorf("kept the windows of %d groups, want only /d", len(s.windows))
+	}
+}
@@ -0,0 +1,176 @@
+package sink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+var graphiteNameReplacer = strings.NewReplacer(".", "_", "/", "_", " ", "_", "\t", "_", "\n", "_")
+
+// GraphiteSink writes the results of each group as Graphite plaintext lines
+type GraphiteSink struct {
+	w      io.Writer
+	closer io.Closer
+	prefix string
+
+	mu     sync.Mutex
+	groups map[string]*graphiteGroup
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type graphiteGroup struct {
+	count int
+	// errors counts the results with a 5XX status or that timed out
+	errors   int
+	timedOut int
+	// requestTimeSum is the sum of the request times of the results that didn't time out
+	requestTimeSum   float64
+	requestTimeCount int
+}
+
+// NewGraphiteSink writes to the Carbon endpoint at addr, or to stdout if addr is "-"
+func NewGraphiteSink(addr, prefix string, stdout io.Writer, flushInterval time.Duration) (*GraphiteSink, error) {
+	s := &GraphiteSink{
+		w:      stdout,
+		prefix: prefix,
+		groups: make(map[string]*graphiteGroup),
+		done:   make(chan struct{}),
+	}
+
+	if addr != "-" {
+		conn, err := net.Dial("tcp", addr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.w = conn
+		s.closer = conn
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.Flush()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *GraphiteSink) Observe(group string, result *parser.NginxResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, exists := s.groups[group]
+
+	if !exists {
+		g = &graphiteGroup{}
+		s.groups[group] = g
+	}
+
+	g.count++
+
+	if result.IsError() {
+		g.errors++
+	}
+
+	if result.TimedOut {
+		g.timedOut++
+	}
+
+	if result.HasLatency() {
+		g.requestTimeSum += result.RequestTime
+		g.requestTimeCount++
+	}
+}
+
+// Flush writes the metrics aggregated since the last flush, timestamped now
+func (s *GraphiteSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.groups) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(s.groups))
+
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	timestamp := time.Now().Unix()
+	w := bufio.NewWriter(s.w)
+
+	for _, key := range keys {
+		g := s.groups[key]
+		name := s.prefix + "." + GraphiteName(key)
+
+		fmt.Fprint(w, formatGraphiteLine(name+".count", float64(g.count), timestamp))
+		fmt.Fprint(w, formatGraphiteLine(name+".errors", float64(g.errors), timestamp))
+		fmt.Fprint(w, formatGraphiteLine(name+".timed_out", float64(g.timedOut), timestamp))
+
+		if g.requestTimeCount > 0 {
+			fmt.Fprint(w, formatGraphiteLine(name+".request_time", g.requestTimeSum/float64(g.requestTimeCount), timestamp))
+		}
+	}
+
+	s.groups = make(map[string]*graphiteGroup)
+
+	return w.Flush()
+}
+
+// Close stops the periodic flushes, and writes the remaining aggregated metrics
+func (s *GraphiteSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	err := s.Flush()
+
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// GraphiteName turns a group key into a single Graphite metric name node
+func GraphiteName(key string) string {
+	name := graphiteNameReplacer.Replace(strings.Trim(key, "/"))
+
+	if name == "" {
+		return "root"
+	}
+
+	return name
+}
+
+func formatGraphiteLine(name string, value float64, timestamp int64) string {
+	return fmt.Sprintf("%s %g %d\n", name, value, timestamp)
+}
@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestGraphiteName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"/api/users", "api_users"},
+		{"/", "root"},
+		{"", "root"},
+		{"/v1.2/items/", "v1_2_items"},
+		{"GET /a b", "GET__a_b"},
+		{"default-api-80", "default-api-80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := GraphiteName(tt.key); got != tt.want {
+				t.Errorf("GraphiteName(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatGraphiteLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  string
+	}{
+		{"nginx.api.count", 3, "nginx.api.count 3 1700000000\n"},
+		{"nginx.api.request_time", 0.25, "nginx.api.request_time 0.25 1700000000\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatGraphiteLine(tt.name, tt.value, 1700000000); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphiteSinkFlush(t *testing.T) {
+	var buf bytes.Buffer
+
+	s, err := NewGraphiteSink("-", "nginx", &buf, time.Hour)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Observe("/api", &parser.NginxResult{UpstreamStatus: 200, RequestTime: 0.25})
+	s.Observe("/api", &parser.NginxResult{UpstreamStatus: 502, RequestTime: 0.75})
+	s.Observe("/", &parser.NginxResult{UpstreamStatus: 504, TimedOut: true})
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"nginx.root.count 1",
+		"nginx.root.errors 1",
+		"nginx.root.timed_out 1",
+		"nginx.api.count 2",
+		"nginx.api.errors 1",
+		"nginx.api.timed_out 0",
+		"nginx.api.request_time 0.5",
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+
+		if len(fields) != 3 {
+			t.Errorf("line %q isn't \"path value timestamp\"", line)
+			continue
+		}
+
+		if got := fields[0] + " " + fields[1]; got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package sink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	openMetricsMediaType   = "application/openmetrics-text"
+)
+
+var prometheusMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+var prometheusLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusSink counts the results of each group, and serves them on /metrics
+type PrometheusSink struct {
+	prefix string
+	server *http.Server
+
+	mu     sync.Mutex
+	groups map[string]*prometheusGroup
+}
+
+type prometheusGroup struct {
+	requests uint64
+	// errors counts the results with a 5XX status or that timed out
+	errors   uint64
+	timedOut uint64
+	// requestTimeSum is the sum of the request times of the results with a latency
+	requestTimeSum   float64
+	requestTimeCount uint64
+}
+
+// NewPrometheusSink serves the metrics on addr, or only counts them if addr is empty
+func NewPrometheusSink(addr, prefix string) (*PrometheusSink, error) {
+	if !prometheusMetricName.MatchString(prefix) {
+		return nil, fmt.Errorf("invalid Prometheus metric prefix %s", prefix)
+	}
+
+	s := &PrometheusSink{
+		prefix: prefix,
+		groups: make(map[string]*prometheusGroup),
+	}
+
+	if addr == "" {
+		return s, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s)
+
+	s.server = &http.Server{Handler: mux}
+
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+func (s *PrometheusSink) Observe(group string, result *parser.NginxResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, exists := s.groups[group]
+
+	if !exists {
+		g = &prometheusGroup{}
+		s.groups[group] = g
+	}
+
+	g.requests++
+
+	if result.IsError() {
+		g.errors++
+	}
+
+	if result.TimedOut {
+		g.timedOut++
+	}
+
+	if result.HasLatency() {
+		g.requestTimeSum += result.RequestTime
+		g.requestTimeCount++
+	}
+}
+
+// ServeHTTP writes the metrics in the format negotiated with the Accept header
+func (s *PrometheusSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := acceptsOpenMetrics(r.Header.Get("Accept"))
+
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", prometheusContentType)
+	}
+
+	s.WriteMetrics(w, openMetrics)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text or OpenMetrics format
+func (s *PrometheusSink) WriteMetrics(w io.Writer, openMetrics bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.groups))
+
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(g *prometheusGroup) uint64
+	}{
+		{"requests", "Requests by group.", func(g *prometheusGroup) uint64 { return g.requests }},
+		{"errors", "Requests with a 5XX status or that timed out by group.", func(g *prometheusGroup) uint64 { return g.errors }},
+		{"timed_out", "Requests that timed out by group.", func(g *prometheusGroup) uint64 { return g.timedOut }},
+	}
+
+	for _, counter := range counters {
+		family := s.prefix + "_" + counter.name
+
+		if !openMetrics {
+			family += "_total"
+		}
+
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", family, counter.help, family)
+
+		for _, key := range keys {
+			fmt.Fprintf(bw, "%s_%s_total{group=\"%s\"} %d\n", s.prefix, counter.name, prometheusLabel(key), counter.value(s.groups[key]))
+		}
+	}
+
+	family := s.prefix + "_request_time_seconds"
+	fmt.Fprintf(bw, "# HELP %s Request times by group.\n# TYPE %s summary\n", family, family)
+
+	for _, key := range keys {
+		g := s.groups[key]
+		label := prometheusLabel(key)
+
+		fmt.Fprintf(bw, "%s_sum{group=\"%s\"} %s\n", family, label, strconv.FormatFloat(g.requestTimeSum, 'g', -1, 64))
+		fmt.Fprintf(bw, "%s_count{group=\"%s\"} %d\n", family, label, g.requestTimeCount)
+	}
+
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+
+	return bw.Flush()
+}
+
+// Close stops serving the metrics
+func (s *PrometheusSink) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Close()
+}
+
+// acceptsOpenMetrics reports whether the Accept header prefers OpenMetrics
+func acceptsOpenMetrics(accept string) bool {
+	var openMetricsQ, textQ float64
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch mediaType {
+		case openMetricsMediaType:
+			openMetricsQ = max(openMetricsQ, q)
+		case "text/plain", "text/*", "*/*":
+			textQ = max(textQ, q)
+		}
+	}
+
+	return openMetricsQ > 0 && openMetricsQ >= textQ
+}
+
+// prometheusLabel escapes the label value for both text formats
+func prometheusLabel(value string) string {
+	return prometheusLabelReplacer.Replace(value)
+}
@@ -0,0 +1,181 @@
+package sink
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+const prometheusWant = `# HELP nginx_requests_total Requests by group.
+# TYPE nginx_requests_total counter
+nginx_requests_total{group="/a"} 2
+nginx_requests_total{group="/b\"c"} 1
+# HELP nginx_errors_total Requests with a 5XX status or that timed out by group.
+# TYPE nginx_errors_total counter
+nginx_errors_total{group="/a"} 1
+nginx_errors_total{group="/b\"c"} 0
+# HELP nginx_timed_out_total Requests that timed out by group.
+# TYPE nginx_timed_out_total counter
+nginx_timed_out_total{group="/a"} 0
+nginx_timed_out_total{group="/b\"c"} 0
+# HELP nginx_request_time_seconds Request times by group.
+# TYPE nginx_request_time_seconds summary
+nginx_request_time_seconds_sum{group="/a"} 0.75
+nginx_request_time_seconds_count{group="/a"} 2
+nginx_request_time_seconds_sum{group="/b\"c"} 0.1
+nginx_request_time_seconds_count{group="/b\"c"} 1
+`
+
+const openMetricsWant = `# HELP nginx_requests Requests by group.
+# TYPE nginx_requests counter
+nginx_requests_total{group="/a"} 2
+nginx_requests_total{group="/b\"c"} 1
+# HELP nginx_errors Requests with a 5XX status or that timed out by group.
+# TYPE nginx_errors counter
+nginx_errors_total{group="/a"} 1
+nginx_errors_total{group="/b\"c"} 0
+# HELP nginx_timed_out Requests that timed out by group.
+# TYPE nginx_timed_out counter
+nginx_timed_out_total{group="/a"} 0
+nginx_timed_out_total{group="/b\"c"} 0
+# HELP nginx_request_time_seconds Request times by group.
+# TYPE nginx_request_time_seconds summary
+nginx_request_time_seconds_sum{group="/a"} 0.75
+nginx_request_time_seconds_count{group="/a"} 2
+nginx_request_time_seconds_sum{group="/b\"c"} 0.1
+nginx_request_time_seconds_count{group="/b\"c"} 1
+# EOF
+`
+
+func TestPrometheusSinkNegotiation(t *testing.T) {
+	s, err := NewPrometheusSink("", "nginx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Observe("/a", &parser.NginxResult{RequestTime: 0.25, Status: 200, UpstreamStatus: 200})
+	s.Observe("/a", &parser.NginxResult{RequestTime: 0.5, Status: 502, UpstreamStatus: 502})
+	s.Observe(`/b"c`, &parser.NginxResult{RequestTime: 0.1, Status: 200, UpstreamStatus: 200})
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "no accept header",
+			wantContentType: prometheusContentType,
+			wantBody:        prometheusWant,
+		},
+		{
+			name:            "prometheus text",
+			accept:          "text/plain; version=0.0.4",
+			wantContentType: prometheusContentType,
+			wantBody:        prometheusWant,
+		},
+		{
+			name:            "openmetrics",
+			accept:          "application/openmetrics-text; version=1.0.0",
+			wantContentType: openMetricsContentType,
+			wantBody:        openMetricsWant,
+		},
+		{
+			name:            "prometheus scraper",
+			accept:          "application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			wantContentType: openMetricsContentType,
+			wantBody:        openMetricsWant,
+		},
+		{
+			name:            "text preferred",
+			accept:          "application/openmetrics-text;q=0.5,text/plain",
+			wantContentType: prometheusContentType,
+			wantBody:        prometheusWant,
+		},
+		{
+			name:            "openmetrics refused",
+			accept:          "application/openmetrics-text;q=0",
+			wantContentType: prometheusContentType,
+			wantBody:        prometheusWant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resp.Header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			if string(body) != tt.wantBody {
+				t.Errorf("body =\n%s\nwant\n%s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPrometheusSinkInvalidPrefix(t *testing.T) {
+	for _, prefix := range []string{"", "1nginx", "nginx-ingress"} {
+		if _, err := NewPrometheusSink("", prefix); err == nil {
+			t.Errorf("NewPrometheusSink(%q) succeeded, want an error", prefix)
+		}
+	}
+}
+
+func TestPrometheusSinkLatencyMissing(t *testing.T) {
+	s, err := NewPrometheusSink("", "nginx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Observe("/a", &parser.NginxResult{RequestTime: 0.25, Status: 200, UpstreamStatus: 200})
+	s.Observe("/a", &parser.NginxResult{Status: 504, UpstreamStatus: 504, LatencyMissing: true})
+	s.Observe("/a", &parser.NginxResult{RequestTime: 60, Status: 504, UpstreamStatus: 504, TimedOut: true})
+
+	var buf strings.Builder
+
+	s.WriteMetrics(&buf, false)
+
+	for _, want := range []string{
+		`nginx_requests_total{group="/a"} 3`,
+		`nginx_timed_out_total{group="/a"} 1`,
+		`nginx_request_time_seconds_sum{group="/a"} 0.25`,
+		`nginx_request_time_seconds_count{group="/a"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("metrics are missing %q:\n%s", want, buf.String())
+		}
+	}
+}
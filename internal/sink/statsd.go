@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+// maxStatsdPacket keeps batched packets below the typical MTU, so they aren't fragmented
+const maxStatsdPacket = 1432
+
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// StatsdSink sends the request time and status of each result to a StatsD server
+type StatsdSink struct {
+	conn   net.Conn
+	prefix string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewStatsdSink(addr, prefix string, flushInterval time.Duration) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StatsdSink{
+		conn:   conn,
+		prefix: prefix,
+		done:   make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.Flush()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *StatsdSink) Observe(group string, result *parser.NginxResult) {
+	tags := formatStatsdTags(map[string]string{"group": group})
+
+	if result.HasLatency() {
+		s.write(formatStatsdTiming(s.prefix+".request_time", result.RequestTime*1000, tags))
+	}
+
+	s.write(formatStatsdCount(fmt.Sprintf("%s.response.%d", s.prefix, result.UpstreamStatus), 1, tags))
+}
+
+func (s *StatsdSink) write(metric string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf.Len() > 0 && s.buf.Len()+1+len(metric) > maxStatsdPacket {
+		s.flushLocked()
+	}
+
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+
+	s.buf.WriteString(metric)
+}
+
+// Flush sends the buffered metrics
+func (s *StatsdSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushLocked()
+}
+
+func (s *StatsdSink) flushLocked() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+
+	return err
+}
+
+// Close stops the periodic flushes, and sends the remaining buffered metrics
+func (s *StatsdSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	err := s.Flush()
+
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func formatStatsdTiming(name string, ms float64, tags string) string {
+	return fmt.Sprintf("%s:%g|ms%s", name, ms, tags)
+}
+
+func formatStatsdCount(name string, count int, tags string) string {
+	return fmt.Sprintf("%s:%d|c%s", name, count, tags)
+}
+
+// formatStatsdTags formats the tags in the DogStatsD format
+func formatStatsdTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	formatted := make([]string, 0, len(tags))
+
+	for key, value := range tags {
+		formatted = append(formatted, statsdTagReplacer.Replace(key)+":"+statsdTagReplacer.Replace(value))
+	}
+
+	// map order is random, and the tags of a metric should read the same every time
+	sort.Strings(formatted)
+
+	return "|#" + strings.Join(formatted, ",")
+}
@@ -0,0 +1,113 @@
+package sink
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+)
+
+func TestFormatStatsd(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "timing",
+			got:  formatStatsdTiming("nginx.request_time", 250, formatStatsdTags(map[string]string{"group": "/api"})),
+			want: "nginx.request_time:250|ms|#group:/api",
+		},
+		{
+			name: "count",
+			got:  formatStatsdCount("nginx.response.502", 1, formatStatsdTags(map[string]string{"group": "/api"})),
+			want: "nginx.response.502:1|c|#group:/api",
+		},
+		{
+			name: "fractional timing",
+			got:  formatStatsdTiming("nginx.request_time", 0.5, ""),
+			want: "nginx.request_time:0.5|ms",
+		},
+		{
+			name: "tags in key order",
+			got:  formatStatsdCount("nginx.response.200", 3, formatStatsdTags(map[string]string{"service": "api", "group": "/a"})),
+			want: "nginx.response.200:3|c|#group:/a,service:api",
+		},
+		{
+			name: "separators in tags",
+			got:  formatStatsdCount("nginx.response.200", 1, formatStatsdTags(map[string]string{"group": "/a|b,c#d\ne"})),
+			want: "nginx.response.200:1|c|#group:/a_b_c_d_e",
+		},
+		{
+			name: "no tags",
+			got:  formatStatsdCount("nginx.response.200", 1, formatStatsdTags(nil)),
+			want: "nginx.response.200:1|c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsdSinkBatches(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	s, err := NewStatsdSink(conn.LocalAddr().String(), "nginx", time.Hour)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const results = 100
+
+	for i := 0; i < results; i++ {
+		s.Observe("/api", &parser.NginxResult{RequestTime: 0.25, Status: 200, UpstreamStatus: 200})
+	}
+
+	s.Observe("/api", &parser.NginxResult{TimedOut: true, Status: 504, UpstreamStatus: 504})
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var metrics []string
+	buf := make([]byte, 65536)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	for len(metrics) < 2*results+1 {
+		n, _, err := conn.ReadFrom(buf)
+
+		if err != nil {
+			t.Fatalf("got %d metrics: %v", len(metrics), err)
+		}
+
+		if n > maxStatsdPacket {
+			t.Errorf("got a %d byte packet, want at most %d", n, maxStatsdPacket)
+		}
+
+		metrics = append(metrics, strings.Split(string(buf[:n]), "\n")...)
+	}
+
+	if metrics[0] != "nginx.request_time:250|ms|#group:/api" || metrics[1] != "nginx.response.200:1|c|#group:/api" {
+		t.Errorf("first metrics = %q, want the timing and the count of the first result", metrics[:2])
+	}
+
+	// the time out has no latency, only its response count
+	if last := metrics[len(metrics)-1]; last != "nginx.response.504:1|c|#group:/api" {
+		t.Errorf("last metric = %q, want the count of the time out", last)
+	}
+}
@@ -3,59 +3,1544 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math"
+	"net/http"
+	_ "net/http/pprof"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/abelanger5/nginx-ingress-parser/internal/input"
 	"github.com/abelanger5/nginx-ingress-parser/internal/metric"
 	"github.com/abelanger5/nginx-ingress-parser/internal/parser"
+	"github.com/abelanger5/nginx-ingress-parser/internal/sink"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+var (
+	zstdInput            bool
+	heatmapPath          string
+	csvPath              string
+	dumpUnparsedPath     string
+	dumpUnparsedMax      int
+	dedupeLines          int
+	verboseErrors        bool
+	verboseErrorsRate    int
+	maxRuntime           time.Duration
+	heatmapTimeBucket    time.Duration
+	heatmapLatencyBucket float64
+	displayTimezone      string
+	showSparkline        bool
+	showBreakdown        bool
+	showResponseSizes    bool
+	journald             bool
+	journaldUnit         string
+	caseInsensitivePaths bool
+	validateLatency      bool
+	saneLatency          metric.LatencyRange
+	showHealth           bool
+	health               = metric.DefaultHealthConfig
+	healthSort           string
+	pathDepth            int
+	reportOnEOF          bool
+	reportOnSigint       bool
+	roundLatency         int
+	clientIPField        string
+	templatePath         string
+	reqIDCap             int
+	excludeStatus        []string
+	errorRateExclude     []string
+	includeMethods       []string
+	includeStream        bool
+	excludeMethods       []string
+	groupBy              string
+	groupTemplate        string
+	upstreamNamespaces   []string
+	latencyBucketSize    time.Duration
+	groupIPSubnet        bool
+	subnetPrefixIPv4     int
+	subnetPrefixIPv6     int
+	noUpstreamFallback   bool
+	talkers              int
+	talkersCapacity      int
+	statsdAddr           string
+	statsdPrefix         string
+	statsdFlushInterval  time.Duration
+	graphiteAddr         string
+	slowClients          bool
+	slowClientConfig     metric.SlowClientConfig
+	graphitePrefix       string
+	graphiteInterval     time.Duration
+	prometheusAddr       string
+	prometheusPrefix     string
+	alertConfig          = sink.DefaultAlertConfig
+	maxMemoryMB          uint64
+	approximateCapacity  int
+	clusterPaths         bool
+	clusterMaxDistinct   int
+	stateFile            string
+	showTrends           bool
+	trendFlatThreshold   float64
+	maxReportGroups      int
+	minRequests          int
+	reportIncludeZero    bool
+	plugins              []string
+	manifest             bool
+	percentiles          []float64
+	percentileMethod     string
+	formatPreset         string
+	includeQuery         bool
+	queryParams          []string
+	redactQueryValues    bool
+	keepQueryValues      []string
+	warmup               time.Duration
+	logLevel             string
+	inputDir             string
+	inputFile            string
+	errorFormats         []string
+	formatDir            string
+	pprofAddr            string
+	cpuProfilePath       string
+	memProfilePath       string
+	collapseTargets      bool
+	decimalComma         bool
+	sanitizeUTF8         bool
+	latencyField         string
+	formatName           string
+	logFormat            string
+	errLogFormat         string
+	saveAggregatePath    string
+	mergeAggregatePaths  []string
+	slaTiers             []time.Duration
+	worstRequests        bool
+	outputFormat         string
+	classSummary         bool
+	textToStderr         bool
+	sortKey              string
+	sortOrder            string
+	summaryColumns       []string
+	maxLineSample        int
+	arrivalHistogram     bool
+	window               time.Duration
+	latencyByClass       bool
+	burstSize            int
+	topErrors            int
+	reservoir            int
+	splitAt              string
+	burstWindow          time.Duration
+	concurrency          int
+)
+
+// logger writes the tool's own operational logs to stderr, keeping them out of the report
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
 // wrap with cobra
 var rootCmd = &cobra.Command{
-	Use: "nginx-parser",
-	Run: func(cmd *cobra.Command, args []string) {
-		factory := &parser.NginxParserFactory{}
+	Use:               "nginx-parser [file]",
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRunE: configureLogger,
+	RunE:              run,
+	// errors are printed by Execute, and are rarely caused by bad usage
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func configureLogger(cmd *cobra.Command, args []string) error {
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid --log-level %s", logLevel)
+	}
+
+	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	return nil
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	logStartup(cmd)
+
+	factory, err := newParserFactory()
+
+	if err != nil {
+		return err
+	}
+
+	collector, err := newCollector()
+
+	if err != nil {
+		return err
+	}
+
+	if manifest {
+		collector.Manifest = runManifest(cmd)
+	}
+
+	stopProfiling, err := startProfiling()
+
+	if err != nil {
+		return err
+	}
+
+	counts := &lineCounts{}
+
+	if counts.dump, err = openUnparsedDump(); err != nil {
+		return err
+	}
+
+	if counts.errors, err = newParseErrorLog(); err != nil {
+		return err
+	}
+
+	// guards the collector, since the report can be written on an interrupt
+	var mu sync.Mutex
+
+	sinks, err := newSinks(collector)
+
+	if err != nil {
+		return err
+	}
+
+	// an interrupt in a pipeline also closes stdin, so only print the report once
+	var finishOnce sync.Once
+	var finishErr error
+
+	finish := func(report bool) error {
+		finishOnce.Do(func() {
+			// stdin may still be scanned, so only touch the collector under the lock
+			mu.Lock()
+
+			if report {
+				finishErr = writeReport(collector)
+			}
+
+			for _, sink := range sinks {
+				if err := sink.Close(); err != nil && finishErr == nil {
+					finishErr = err
+				}
+			}
+
+			mu.Unlock()
+
+			counts.errors.Close()
+
+			if err := counts.dump.Close(); err != nil && finishErr == nil {
+				finishErr = err
+			}
+
+			if err := stopProfiling(); err != nil && finishErr == nil {
+				finishErr = err
+			}
+		})
+
+		return finishErr
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		for range c {
+			logger.Info("interrupted", counts.attrs()...)
+
+			if err := finish(reportOnSigint); err != nil {
+				logger.Error("finishing run failed", "err", err)
+				os.Exit(1)
+			}
+
+			os.Exit(0)
+		}
+	}()
+
+	// stops streamed input after a bounded time, e.g. for smoke tests of tail -F pipelines
+	if maxRuntime > 0 {
+		time.AfterFunc(maxRuntime, func() {
+			logger.Info("max runtime reached", counts.attrs()...)
+
+			if err := finish(true); err != nil {
+				logger.Error("finishing run failed", "err", err)
+				os.Exit(1)
+			}
+
+			os.Exit(0)
+		})
+	}
+
+	path := inputFile
+
+	if len(args) == 1 {
+		if inputFile != "" {
+			finish(false)
+			return fmt.Errorf("the file argument can't be combined with --file")
+		}
+
+		path = args[0]
+	}
+
+	if path == "-" {
+		path = ""
+	}
+
+	if path != "" && (inputDir != "" || journald) {
+		finish(false)
+		return fmt.Errorf("a file can't be combined with --dir or --journald")
+	}
+
+	if inputDir != "" {
+		if journald || stateFile != "" {
+			finish(false)
+			return fmt.Errorf("--dir can't be combined with --journald or --since-last-run")
+		}
+
+		// each file would skip its own warmup, since they're aggregated separately
+		if warmup > 0 {
+			finish(false)
+			return fmt.Errorf("--warmup can't be combined with --dir")
+		}
+
+		if err := processDir(inputDir, factory, collector, &mu, counts); err != nil {
+			finish(false)
+			return err
+		}
+	} else {
+		reader, saveState, err := openInput(path)
+
+		if err != nil {
+			finish(false)
+			return err
+		}
+
+		defer reader.Close()
+
+		if err := scanLines(reader, factory.New(), collector, &mu, counts); err != nil {
+			finish(false)
+			return err
+		}
+
+		if err := saveState(); err != nil {
+			finish(false)
+			return err
+		}
+	}
+
+	logger.Info("input ended", counts.attrs()...)
+
+	for _, path := range mergeAggregatePaths {
+		if err := mergeAggregate(path, collector, &mu); err != nil {
+			finish(false)
+			return err
+		}
+	}
+
+	if saveAggregatePath != "" {
+		if err := saveAggregate(saveAggregatePath, collector); err != nil {
+			finish(false)
+			return err
+		}
+	}
+
+	if duplicates := atomic.LoadInt64(&counts.duplicates); duplicates > 0 {
+		logger.Info("skipped duplicate lines", "duplicates", duplicates)
+	}
+
+	if dropped := atomic.LoadInt64(&counts.dropped); dropped > 0 {
+		logger.Warn("dropped unparseable lines", "dropped", dropped)
+	}
+
+	// fields failing to convert point at a format that drifted from the configured one
+	if failures := factory.FieldFailures(); len(failures) > 0 {
+		attrs := make([]any, 0, 2*len(failures))
+
+		for _, failure := range failures {
+			attrs = append(attrs, failure.Field, failure.Count)
+		}
+
+		logger.Warn("field conversion failures", attrs...)
+	}
+
+	return finish(reportOnEOF)
+}
+
+// lineCounts atomically counts the lines read, dropped and skipped as duplicates
+type lineCounts struct {
+	lines      int64
+	dropped    int64
+	duplicates int64
+	dump       *unparsedDump
+	errors     *parseErrorLog
+}
+
+// parseErrorLog logs the parse errors of dropped lines, up to rate errors per second
+type parseErrorLog struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	rate       int
+	second     int64
+	logged     int
+	suppressed int
+}
+
+// newParseErrorLog returns the log of parse errors, or nil if --verbose-errors isn't set
+func newParseErrorLog() (*parseErrorLog, error) {
+	if !verboseErrors {
+		return nil, nil
+	}
+
+	if verboseErrorsRate < 1 {
+		return nil, fmt.Errorf("invalid --verbose-errors-rate %d", verboseErrorsRate)
+	}
+
+	return &parseErrorLog{now: time.Now, rate: verboseErrorsRate}, nil
+}
+
+// log logs the parse error of the raw text, printed as the redacted line
+func (l *parseErrorLog) log(err error, text, line string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if second := l.now().Unix(); second != l.second {
+		l.second = second
+		l.logged = 0
+	}
+
+	if l.logged >= l.rate {
+		l.suppressed++
+		return
+	}
+
+	l.logged++
+
+	var errAttr any = err
+
+	if line != text {
+		errAttr = strings.ReplaceAll(err.Error(), text, line)
+	}
+
+	attrs := []any{"err", errAttr, "line", line}
+
+	if l.suppressed > 0 {
+		attrs = append(attrs, "suppressed", l.suppressed)
+		l.suppressed = 0
+	}
+
+	logger.Warn("dropped unparseable line", attrs...)
+}
+
+// Close logs the number of errors suppressed since the last logged one
+func (l *parseErrorLog) Close() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.suppressed > 0 {
+		logger.Warn("suppressed parse errors over --verbose-errors-rate", "suppressed", l.suppressed)
+	}
+}
 
-		factory.Init(map[string]interface{}{})
-		parser := factory.New()
-		collector := metric.NewMetricCollector(metric.GroupKindPath, metric.MetricKindLatency)
+// unparsedDump writes the lines dropped as unparseable, up to max lines if it's positive
+type unparsedDump struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	file    *os.File
+	max     int
+	written int
+	skipped int
+}
+
+// openUnparsedDump opens the --dump-unparsed file, or returns nil if it isn't set
+func openUnparsedDump() (*unparsedDump, error) {
+	if dumpUnparsedPath == "" {
+		return nil, nil
+	}
+
+	dump := &unparsedDump{max: dumpUnparsedMax}
+
+	if dumpUnparsedPath == "-" {
+		dump.w = bufio.NewWriter(os.Stdout)
+		return dump, nil
+	}
+
+	f, err := os.Create(dumpUnparsedPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dump.file = f
+	dump.w = bufio.NewWriter(f)
+
+	return dump, nil
+}
+
+func (d *unparsedDump) write(line string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.max > 0 && d.written >= d.max {
+		d.skipped++
+		return
+	}
+
+	d.w.WriteString(line)
+	d.w.WriteByte('\n')
+	d.written++
+}
+
+// Close flushes the dump, and closes it unless it's stdout
+func (d *unparsedDump) Close() error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.skipped > 0 {
+		logger.Warn("unparsed lines dump reached --dump-unparsed-max", "written", d.written, "skipped", d.skipped)
+	}
+
+	err := d.w.Flush()
+
+	if d.file != nil {
+		if closeErr := d.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (c *lineCounts) attrs() []any {
+	attrs := []any{"lines", atomic.LoadInt64(&c.lines), "dropped", atomic.LoadInt64(&c.dropped)}
+
+	if duplicates := atomic.LoadInt64(&c.duplicates); duplicates > 0 {
+		attrs = append(attrs, "duplicates", duplicates)
+	}
+
+	return attrs
+}
+
+// mergeAggregate merges the aggregate saved at path into the collector
+func mergeAggregate(path string, collector *metric.MetricCollector, mu *sync.Mutex) error {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	shard := collector.Shard()
+
+	if err := shard.GobDecode(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	mu.Lock()
+	collector.Merge(shard)
+	mu.Unlock()
+
+	return nil
+}
+
+func saveAggregate(path string, collector *metric.MetricCollector) error {
+	data, err := collector.GobEncode()
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// scanLines parses every line of the reader into the collector, holding mu if it's set
+func scanLines(reader io.Reader, p *parser.NginxParser, collector *metric.MetricCollector, mu *sync.Mutex, counts *lineCounts) error {
+	scanner := bufio.NewScanner(reader)
+
+	var deduper *input.Deduper
+
+	if dedupeLines > 0 {
+		deduper = input.NewDeduper(dedupeLines)
+	}
+
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		atomic.AddInt64(&counts.lines, 1)
+
+		if deduper != nil && deduper.Duplicate(text) {
+			atomic.AddInt64(&counts.duplicates, 1)
+			continue
+		}
+
+		res, fields, err := p.ParseWithFields(text)
+
+		if err != nil {
+			atomic.AddInt64(&counts.dropped, 1)
+			line := collector.RedactLine(text)
+			counts.dump.write(line)
+			counts.errors.log(err, text, line)
+			continue
+		}
+
+		if mu != nil {
+			mu.Lock()
+		}
+
+		collector.AddLineWithFields(res, fields, text)
+
+		if mu != nil {
+			mu.Unlock()
+		}
+	}
+
+	return scanner.Err()
+}
+
+// processDir aggregates every file of the directory concurrently, merging in name order
+func processDir(dir string, factory *parser.NginxParserFactory, collector *metric.MetricCollector, mu *sync.Mutex, counts *lineCounts) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid --concurrency %d", concurrency)
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	// shards are created upfront, since they copy the collector's configuration
+	shards := make([]*metric.MetricCollector, len(paths))
+	done := make([]chan error, len(paths))
+
+	for i := range paths {
+		shards[i] = collector.Shard()
+		done[i] = make(chan error, 1)
+	}
+
+	pool := parser.NewParserPool(factory)
+	workers := make(chan struct{}, concurrency)
+
+	go func() {
+		for i, path := range paths {
+			workers <- struct{}{}
+
+			go func(i int, path string) {
+				defer func() { <-workers }()
+
+				p := pool.Get()
+				defer pool.Put(p)
+
+				done[i] <- processFile(path, p, shards[i], counts)
+			}(i, path)
+		}
+	}()
+
+	for i, path := range paths {
+		if err := <-done[i]; err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		mu.Lock()
+		collector.Merge(shards[i])
+		mu.Unlock()
+
+		shards[i] = nil
+	}
+
+	return nil
+}
+
+func processFile(path string, p *parser.NginxParser, shard *metric.MetricCollector, counts *lineCounts) error {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
 
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt)
+	defer f.Close()
+
+	zstd := zstdInput || strings.HasSuffix(path, ".zst") || strings.HasSuffix(path, ".zstd")
+	reader, err := input.NewReader(f, input.Options{Zstd: zstd, Gzip: strings.HasSuffix(path, ".gz")})
+
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	// shards are only merged into the collector once they're complete
+	return scanLines(reader, p, shard, nil, counts)
+}
+
+// startProfiling starts profiling, returning a function writing the profiles
+func startProfiling() (func() error, error) {
+	if pprofAddr != "" {
 		go func() {
-			for range c {
-				collector.GetInfo()
-				os.Exit(0)
+			if err := http.ListenAndServe(pprofAddr, nil); err != nil {
+				logger.Error("pprof server failed", "err", err)
 			}
 		}()
+	}
 
-		scanner := bufio.NewScanner(os.Stdin)
+	var cpuProfile *os.File
+
+	if cpuProfilePath != "" {
+		var err error
+
+		if cpuProfile, err = os.Create(cpuProfilePath); err != nil {
+			return nil, err
+		}
 
-		for scanner.Scan() {
-			text := scanner.Text()
-			res, err := parser.Parse(text)
+		if err := pprof.StartCPUProfile(cpuProfile); err != nil {
+			cpuProfile.Close()
+			return nil, err
+		}
+	}
 
-			if err != nil {
-				continue
+	return func() error {
+		if cpuProfile != nil {
+			pprof.StopCPUProfile()
+
+			if err := cpuProfile.Close(); err != nil {
+				return err
 			}
+		}
 
-			collector.AddLine(res, text)
+		if memProfilePath == "" {
+			return nil
 		}
 
-		if err := scanner.Err(); err != nil {
-			fmt.Println(err)
+		memProfile, err := os.Create(memProfilePath)
+
+		if err != nil {
+			return err
 		}
 
-		collector.GetInfo()
-	},
+		defer memProfile.Close()
+
+		return pprof.WriteHeapProfile(memProfile)
+	}, nil
+}
+
+// logStartup logs the flags set for the run
+func logStartup(cmd *cobra.Command) {
+	attrs := make([]any, 0)
+
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		attrs = append(attrs, flag.Name, redactedFlagValue(flag))
+	})
+
+	logger.Info("starting", attrs...)
+}
+
+// runManifest returns the effective value of every option of the command, in name order
+func runManifest(cmd *cobra.Command) []*metric.ManifestOption {
+	options := make([]*metric.ManifestOption, 0)
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+
+		options = append(options, &metric.ManifestOption{Name: flag.Name, Value: redactedFlagValue(flag), Set: flag.Changed})
+	})
+
+	return options
+}
+
+// secretFlags are the flags whose values carry credentials
+var secretFlags = map[string]bool{
+	"alert-webhook": true,
+}
+
+// redactedFlagValue returns the value of the flag with its credentials redacted
+func redactedFlagValue(flag *pflag.Flag) string {
+	value := flag.Value.String()
+
+	if value == "" || !strings.Contains(value, "://") {
+		if secretFlags[flag.Name] && value != "" {
+			return "REDACTED"
+		}
+
+		return value
+	}
+
+	u, err := url.Parse(value)
+
+	if err != nil {
+		return "REDACTED"
+	}
+
+	if secretFlags[flag.Name] {
+		return u.Scheme + "://" + u.Host + "/REDACTED"
+	}
+
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+
+	return u.String()
+}
+
+func newParserFactory() (*parser.NginxParserFactory, error) {
+	factory := &parser.NginxParserFactory{}
+	parserOpts := map[string]interface{}{}
+
+	if formatPreset != "" {
+		parserOpts["format_preset"] = formatPreset
+	}
+
+	if formatName != "" {
+		if formatPreset != "" {
+			return nil, fmt.Errorf("--format can't be combined with --format-preset")
+		}
+
+		if formatDir == "" {
+			return nil, fmt.Errorf("--format needs a --format-dir to load it from")
+		}
+
+		def, err := parser.LoadFormat(formatDir, formatName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parserOpts["log_format"] = def.Format
+		parserOpts["time_layout"] = def.TimeLayout
+	}
+
+	if logFormat != "" {
+		if formatPreset != "" || formatName != "" {
+			return nil, fmt.Errorf("--log-format can't be combined with --format-preset or --format")
+		}
+
+		parserOpts["log_format"] = logFormat
+	}
+
+	if errLogFormat != "" {
+		if len(errorFormats) > 0 {
+			return nil, fmt.Errorf("--err-log-format can't be combined with --error-formats")
+		}
+
+		parserOpts["err_log_format"] = errLogFormat
+	}
+
+	if clientIPField != "" {
+		parserOpts["client_ip_field"] = clientIPField
+	}
+
+	if noUpstreamFallback {
+		parserOpts["no_upstream_fallback"] = true
+	}
+
+	if collapseTargets {
+		parserOpts["collapse_absolute_targets"] = true
+	}
+
+	if decimalComma {
+		parserOpts["decimal_comma"] = true
+	}
+
+	if sanitizeUTF8 {
+		parserOpts["sanitize_utf8"] = true
+	}
+
+	parserOpts["latency_field"] = latencyField
+
+	if len(errorFormats) > 0 {
+		parserOpts["error_formats"] = errorFormats
+	}
+
+	if err := factory.Init(parserOpts); err != nil {
+		return nil, err
+	}
+
+	return factory, nil
+}
+
+func newCollector() (*metric.MetricCollector, error) {
+	group, groupField, err := metric.ParseGroupBy(groupBy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl *metric.GroupTemplate
+
+	if groupTemplate != "" {
+		if tmpl, err = metric.ParseGroupTemplate(groupTemplate); err != nil {
+			return nil, err
+		}
+
+		group = metric.GroupKindTemplate
+	}
+
+	collector := metric.NewMetricCollector(group, metric.MetricKindLatency)
+	collector.GroupField = groupField
+	collector.GroupTemplate = tmpl
+	collector.UpstreamNamespaces = upstreamNamespaces
+
+	if latencyBucketSize <= 0 {
+		return nil, fmt.Errorf("invalid --latency-bucket-size %s", latencyBucketSize)
+	}
+
+	collector.LatencyBucketSize = latencyBucketSize.Seconds()
+
+	if groupIPSubnet {
+		if subnetPrefixIPv4 < 1 || subnetPrefixIPv4 > 32 {
+			return nil, fmt.Errorf("invalid --subnet-prefix-ipv4 %d", subnetPrefixIPv4)
+		}
+
+		if subnetPrefixIPv6 < 1 || subnetPrefixIPv6 > 128 {
+			return nil, fmt.Errorf("invalid --subnet-prefix-ipv6 %d", subnetPrefixIPv6)
+		}
+
+		collector.SubnetPrefixIPv4 = subnetPrefixIPv4
+		collector.SubnetPrefixIPv6 = subnetPrefixIPv6
+	}
+
+	if displayTimezone != "" {
+		loc, err := time.LoadLocation(displayTimezone)
+
+		if err != nil {
+			return nil, err
+		}
+
+		collector.Location = loc
+	}
+
+	if templatePath != "" {
+		text, err := ioutil.ReadFile(templatePath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if collector.Template, err = metric.NewReportTemplate(filepath.Base(templatePath), string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	collector.Sparkline = showSparkline
+	collector.Breakdown = showBreakdown
+	collector.ResponseSizes = showResponseSizes
+	collector.CaseInsensitivePaths = caseInsensitivePaths
+	collector.PathDepth = pathDepth
+	collector.IncludeQuery = includeQuery
+	collector.QueryParams = queryParams
+	collector.RedactQueryValues = redactQueryValues
+	collector.KeepQueryValues = keepQueryValues
+	collector.ClusterPaths = clusterPaths
+	collector.ClusterMaxDistinct = clusterMaxDistinct
+	collector.RoundLatency = roundLatency
+	collector.MaxReportGroups = maxReportGroups
+	collector.Trends = showTrends
+
+	if minRequests < 0 {
+		return nil, fmt.Errorf("invalid --min-requests %d", minRequests)
+	}
+
+	collector.MinRequests = minRequests
+
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid --percentiles %g, percentiles must be in (0, 100]", p)
+		}
+	}
+
+	collector.Percentiles = percentiles
+
+	if collector.PercentileMethod, err = metric.ParsePercentileMethod(percentileMethod); err != nil {
+		return nil, err
+	}
+
+	for _, name := range plugins {
+		plugin, ok := metric.Plugins[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown plugin %s", name)
+		}
+
+		collector.Plugins = append(collector.Plugins, plugin)
+	}
+
+	// lists every group, however few requests it has
+	if reportIncludeZero {
+		if maxReportGroups > 0 {
+			return nil, fmt.Errorf("--report-include-zero can't be combined with --max-groups-report")
+		}
+
+		collector.MinRequests = 0
+	}
+
+	collector.TrendFlatThreshold = trendFlatThreshold
+	collector.ReqIDCap = reqIDCap
+	collector.Warmup = warmup
+	collector.Talkers = talkers
+	collector.TalkersCapacity = talkersCapacity
+	collector.MaxMemory = maxMemoryMB * 1024 * 1024
+	collector.ApproximateCapacity = approximateCapacity
+
+	excludeStatusRanges, err := metric.ParseStatusRanges(excludeStatus)
+
+	if err != nil {
+		return nil, err
+	}
+
+	collector.ExcludeStatus = excludeStatusRanges
+
+	if collector.ErrorRateExcludeStatus, err = metric.ParseStatusRanges(errorRateExclude); err != nil {
+		return nil, err
+	}
+
+	collector.IncludeMethods = includeMethods
+	collector.IncludeStream = includeStream
+	collector.ExcludeMethods = excludeMethods
+
+	switch healthSort {
+	case "asc":
+		health.Ascending = true
+	case "desc":
+		health.Ascending = false
+	default:
+		return nil, fmt.Errorf("invalid --health-sort %s, must be asc or desc", healthSort)
+	}
+
+	healthWeights := []struct {
+		flag   string
+		weight float64
+	}{
+		{"--health-error-weight", health.ErrorWeight},
+		{"--health-timeout-weight", health.TimeoutWeight},
+		{"--health-latency-weight", health.LatencyWeight},
+	}
+
+	// negative or infinite weights would take the score out of the 0-100 range
+	for _, w := range healthWeights {
+		if w.weight < 0 || math.IsInf(w.weight, 0) || math.IsNaN(w.weight) {
+			return nil, fmt.Errorf("invalid %s %g, must be a non-negative number", w.flag, w.weight)
+		}
+	}
+
+	collector.HealthScores = showHealth
+	collector.Health = health
+
+	if validateLatency {
+		collector.SaneLatency = &saneLatency
+	}
+
+	for _, tier := range slaTiers {
+		collector.SLATiers = append(collector.SLATiers, tier.Seconds())
+	}
+
+	sort.Float64s(collector.SLATiers)
+
+	collector.ArrivalHistogram = arrivalHistogram
+	collector.Window = window
+	collector.LatencyByClass = latencyByClass
+	collector.BurstSize = burstSize
+	collector.TopErrors = topErrors
+
+	if reservoir < 0 {
+		return nil, fmt.Errorf("invalid --reservoir %d", reservoir)
+	}
+
+	collector.Reservoir = reservoir
+
+	if heatmapPath != "" {
+		if heatmapTimeBucket <= 0 || heatmapLatencyBucket <= 0 {
+			return nil, fmt.Errorf("invalid heatmap bucket sizes %s and %g, both must be positive", heatmapTimeBucket, heatmapLatencyBucket)
+		}
+
+		collector.HeatmapTimeBucket = heatmapTimeBucket
+		collector.HeatmapLatencyBucket = heatmapLatencyBucket
+	}
+
+	if splitAt != "" {
+		if collector.SplitAt, err = time.Parse(time.RFC3339, splitAt); err != nil {
+			return nil, fmt.Errorf("invalid --split-at: %w", err)
+		}
+	}
+
+	collector.BurstWindow = burstWindow
+	collector.WorstRequests = worstRequests
+
+	if collector.SortKey, err = metric.ParseSortKey(sortKey); err != nil {
+		return nil, err
+	}
+
+	switch sortOrder {
+	case "asc":
+		collector.SortDescending = false
+	case "desc":
+		collector.SortDescending = true
+	case "":
+		// names read best in ascending order, and metrics worst first
+		collector.SortDescending = collector.SortKey != metric.SortByName
+	default:
+		return nil, fmt.Errorf("invalid --sort-order %s, must be asc or desc", sortOrder)
+	}
+
+	if outputFormat != "text" && outputFormat != "csv" && outputFormat != "json" && outputFormat != "markdown" {
+		return nil, fmt.Errorf("invalid --output %s, must be text, csv, json or markdown", outputFormat)
+	}
+
+	if textToStderr && outputFormat == "text" {
+		return nil, fmt.Errorf("--text-to-stderr needs --output csv, json or markdown")
+	}
+
+	if err := metric.ValidateSummaryColumns(summaryColumns); err != nil {
+		return nil, err
+	}
+
+	if maxLineSample < 0 {
+		return nil, fmt.Errorf("invalid --max-line-sample %d", maxLineSample)
+	}
+
+	collector.MaxLineSample = maxLineSample
+
+	if slowClients {
+		collector.SlowClients = &slowClientConfig
+	}
+
+	return collector, nil
+}
+
+// newSinks attaches the configured sinks to the collector, and returns them to close
+func newSinks(collector *metric.MetricCollector) ([]io.Closer, error) {
+	sinks := make([]io.Closer, 0)
+
+	if statsdAddr != "" {
+		if statsdFlushInterval <= 0 {
+			return nil, fmt.Errorf("invalid --statsd-flush-interval %s", statsdFlushInterval)
+		}
+
+		statsd, err := sink.NewStatsdSink(statsdAddr, statsdPrefix, statsdFlushInterval)
+
+		if err != nil {
+			return nil, err
+		}
+
+		collector.Sinks = append(collector.Sinks, statsd)
+		sinks = append(sinks, statsd)
+	}
+
+	if alertConfig.URL != "" {
+		alertConfig.ErrorRateExcludeStatus = collector.ErrorRateExcludeStatus
+		alert := sink.NewAlertSink(alertConfig)
+
+		collector.Sinks = append(collector.Sinks, alert)
+		sinks = append(sinks, alert)
+	}
+
+	if graphiteAddr != "" {
+		if graphiteInterval <= 0 {
+			return nil, fmt.Errorf("invalid --graphite-flush-interval %s", graphiteInterval)
+		}
+
+		graphite, err := sink.NewGraphiteSink(graphiteAddr, graphitePrefix, os.Stdout, graphiteInterval)
+
+		if err != nil {
+			return nil, err
+		}
+
+		collector.Sinks = append(collector.Sinks, graphite)
+		sinks = append(sinks, graphite)
+	}
+
+	if prometheusAddr != "" {
+		prometheus, err := sink.NewPrometheusSink(prometheusAddr, prometheusPrefix)
+
+		if err != nil {
+			return nil, err
+		}
+
+		collector.Sinks = append(collector.Sinks, prometheus)
+		sinks = append(sinks, prometheus)
+	}
+
+	return sinks, nil
+}
+
+// writeReport prints the report, and writes any other configured outputs
+func writeReport(collector *metric.MetricCollector) error {
+	switch {
+	case !collector.SplitAt.IsZero():
+		if err := collector.WriteComparison(os.Stdout); err != nil {
+			return err
+		}
+	case classSummary:
+		if err := collector.WriteClassSummary(os.Stdout); err != nil {
+			return err
+		}
+	case outputFormat == "text":
+		if err := collector.WriteReport(os.Stdout); err != nil {
+			return err
+		}
+	case outputFormat == "csv":
+		if err := collector.WriteSummaryCSV(os.Stdout, summaryColumns); err != nil {
+			return err
+		}
+	case outputFormat == "json":
+		if err := collector.WriteJSON(os.Stdout); err != nil {
+			return err
+		}
+	case outputFormat == "markdown":
+		if err := collector.WriteMarkdown(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	// the text report goes to stderr for a human, while a pipeline reads stdout
+	if textToStderr {
+		if err := collector.WriteReport(os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	if csvPath != "" {
+		if err := collector.WriteToCSV(csvPath); err != nil {
+			return err
+		}
+	}
+
+	if heatmapPath != "" {
+		if err := collector.WriteHeatmap(heatmapPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var inferLines int
+
+var inferCmd = &cobra.Command{
+	Use:   "infer",
+	Short: "Print a best-guess log format inferred from sample lines on stdin",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lines := make([]string, 0, inferLines)
+		scanner := bufio.NewScanner(os.Stdin)
+
+		for len(lines) < inferLines && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		format, err := parser.InferFormat(lines)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(format)
+
+		return nil
+	},
+}
+
+var validateFormatCmd = &cobra.Command{
+	Use:   "validate-format <format>",
+	Short: "Check that an access log format compiles and only uses fields the parser knows",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := parser.ValidateFormat(args[0])
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+
+		if len(problems) > 0 {
+			return fmt.Errorf("format has %d problems", len(problems))
+		}
+
+		fmt.Println("format is valid")
+
+		return nil
+	},
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of the --output json report",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(metric.ReportSchema)
+
+		return err
+	},
+}
+
+// openInput opens the file at path or stdin, and a function saving how far it was read
+func openInput(path string) (io.ReadCloser, func() error, error) {
+	saveState := func() error { return nil }
+
+	if journald {
+		// the journal has no byte offset to resume from
+		if stateFile != "" {
+			return nil, nil, fmt.Errorf("--journald can't be combined with --since-last-run")
+		}
+
+		reader, err := input.OpenJournal(journaldUnit)
+
+		return reader, saveState, err
+	}
+
+	file := os.Stdin
+
+	if path != "" {
+		f, err := os.Open(path)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		file = f
+	}
+
+	var source io.Reader = file
+
+	if stateFile != "" {
+		// offsets can't be tracked on compressed bytes
+		if compressedInput(file, path) {
+			file.Close()
+			return nil, nil, fmt.Errorf("--since-last-run can't be used with compressed input")
+		}
+
+		state, err := input.LoadState(stateFile)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tracker, err := state.Resume(file)
+
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		source = tracker
+		saveState = func() error {
+			state.Offset = tracker.LineEnd()
+
+			return state.Save(stateFile)
+		}
+	}
+
+	zstd := zstdInput || strings.HasSuffix(path, ".zst") || strings.HasSuffix(path, ".zstd")
+	reader, err := input.NewReader(source, input.Options{Zstd: zstd, Gzip: strings.HasSuffix(path, ".gz")})
+
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	if file == os.Stdin {
+		return reader, saveState, nil
+	}
+
+	return &fileReader{reader, file}, saveState, nil
+}
+
+// compressedInput reports whether the input is compressed
+func compressedInput(file *os.File, path string) bool {
+	if zstdInput || strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".zst") || strings.HasSuffix(path, ".zstd") {
+		return true
+	}
+
+	// ReadAt doesn't move the offset the state resumes from
+	header := make([]byte, 4)
+	n, _ := file.ReadAt(header, 0)
+
+	return input.IsCompressed(header[:n])
+}
+
+// fileReader closes the input file after the reader decompressing it
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadCloser.Close()
+
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&pprofAddr, "pprof", "", "serve the pprof profiling endpoints on this address, e.g. localhost:6060")
+	rootCmd.Flags().StringVar(&cpuProfilePath, "cpuprofile", "", "write a CPU profile of the run to this file")
+	rootCmd.Flags().StringVar(&memProfilePath, "memprofile", "", "write a heap profile to this file at the end of the run")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "level of the operational logs written to stderr: debug, info, warn or error")
+
+	rootCmd.AddCommand(inferCmd)
+	inferCmd.Flags().IntVar(&inferLines, "lines", 100, "number of sample lines to read")
+
+	rootCmd.AddCommand(validateFormatCmd)
+	rootCmd.AddCommand(schemaCmd)
+
+	rootCmd.Flags().BoolVar(&zstdInput, "zstd", false, "decompress zstd input (detected automatically from the stream header)")
+	rootCmd.Flags().DurationVar(&maxRuntime, "max-runtime", 0, "stop reading input after this duration and print the report, e.g. for streamed input")
+	rootCmd.Flags().StringVar(&dumpUnparsedPath, "dump-unparsed", "", "write the lines dropped as unparseable to this file, or - for stdout")
+	rootCmd.Flags().BoolVar(&verboseErrors, "verbose-errors", false, "log the parse error of each dropped line, which are only counted by default")
+	rootCmd.Flags().IntVar(&verboseErrorsRate, "verbose-errors-rate", 10, "maximum number of parse errors logged per second by --verbose-errors")
+	rootCmd.Flags().IntVar(&dedupeLines, "dedupe-lines", 0, "skip lines identical to one of the last N lines of the same input, e.g. duplicates emitted by log shippers, 0 to disable")
+	rootCmd.Flags().IntVar(&dumpUnparsedMax, "dump-unparsed-max", 10000, "maximum number of lines written by --dump-unparsed, 0 for no limit")
+	rootCmd.Flags().StringVar(&csvPath, "csv", "", "write every tracked latency sample to this CSV file, as group, RFC3339 time and latency rows")
+	rootCmd.Flags().StringVar(&heatmapPath, "heatmap", "", "write a time x latency heatmap of request counts to this JSON file")
+	rootCmd.Flags().DurationVar(&heatmapTimeBucket, "heatmap-time-bucket", time.Minute, "size of the heatmap time buckets")
+	rootCmd.Flags().Float64Var(&heatmapLatencyBucket, "heatmap-latency-bucket", 0.1, "size of the heatmap latency buckets, in seconds")
+	rootCmd.Flags().StringVar(&displayTimezone, "tz", "", "display timestamps in this IANA timezone, e.g. America/New_York")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "report format: text, csv for one row of aggregates per group, json following the schema printed by the schema command, or markdown tables")
+	rootCmd.Flags().BoolVar(&textToStderr, "text-to-stderr", false, "also write the text report to stderr, next to the --output csv or json report on stdout")
+	rootCmd.Flags().BoolVar(&classSummary, "class-summary", false, "print only a single line counting responses by status class instead of the report")
+	rootCmd.Flags().StringVar(&sortKey, "sort", string(metric.SortByName), "what to sort report groups by: name, count, error_rate, timeout_rate or p95")
+	rootCmd.Flags().StringVar(&sortOrder, "sort-order", "", "asc or desc (default asc for name, and desc otherwise)")
+	rootCmd.Flags().StringSliceVar(&summaryColumns, "summary-columns", metric.SummaryColumns, "columns of the csv report, after the group")
+	rootCmd.Flags().StringVar(&templatePath, "template", "", "render the report with this Go text/template file instead of the default layout")
+	rootCmd.Flags().BoolVar(&showSparkline, "sparkline", false, "show a sparkline of the latency distribution for each path")
+	rootCmd.Flags().BoolVar(&showBreakdown, "breakdown", false, "show the mean connect, header, response and total latency of each group")
+	rootCmd.Flags().BoolVar(&showResponseSizes, "response-sizes", false, "show the mean upstream and client response sizes of each group")
+	rootCmd.Flags().StringVar(&formatPreset, "format-preset", "", "access log format preset: ingress (default) or ingress-xff")
+	rootCmd.Flags().StringVar(&formatDir, "format-dir", "", "directory of <name>.format access log format definitions, for --format")
+	rootCmd.Flags().StringVar(&formatName, "format", "", "name of the access log format to load from --format-dir")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", "", "custom access log format, in nginx log_format syntax")
+	rootCmd.Flags().StringVar(&errLogFormat, "err-log-format", "", "custom error log format, using the field names of the built-in error formats")
+	rootCmd.Flags().StringSliceVar(&errorFormats, "error-formats", nil, "error log format presets to try in order: ingress, ingress-referrer, ingress-no-upstream and ingress-no-upstream-referrer, or their -unquoted variants for request lines logged without quotes (default all of them)")
+	rootCmd.Flags().StringVar(&clientIPField, "client-ip-field", "", "log field to read the client IP from, e.g. http_x_forwarded_for (default remote_addr)")
+	rootCmd.Flags().StringVar(&latencyField, "latency-field", "request_time", "field latencies are read from: request_time, or upstream_response_time for the last upstream attempt")
+	rootCmd.Flags().BoolVar(&sanitizeUTF8, "sanitize-utf8", false, "replace invalid UTF-8 in log fields, e.g. binary user agents, with U+FFFD")
+	rootCmd.Flags().BoolVar(&decimalComma, "decimal-comma", false, "parse numeric fields logged with comma decimals, e.g. a request_time of 0,123")
+	rootCmd.Flags().BoolVar(&collapseTargets, "collapse-ports-in-request", false, "strip the scheme, host and port of absolute-form request targets, e.g. group GET http://host:443/a under /a")
+	rootCmd.Flags().BoolVar(&noUpstreamFallback, "no-upstream-fallback", false, "count lines without an upstream address as timeouts instead of defaulting the address to 0.0.0.0")
+	rootCmd.Flags().BoolVar(&reportOnEOF, "report-on-eof", true, "print the report when the input ends")
+	rootCmd.Flags().BoolVar(&reportOnSigint, "report-on-sigint", true, "print the report when interrupted")
+	rootCmd.Flags().StringVar(&statsdAddr, "statsd", "", "send request metrics to the StatsD server at this address")
+	rootCmd.Flags().StringVar(&statsdPrefix, "statsd-prefix", "nginx", "prefix of the StatsD metric names")
+	rootCmd.Flags().DurationVar(&statsdFlushInterval, "statsd-flush-interval", time.Second, "how often buffered StatsD metrics are sent")
+	rootCmd.Flags().StringVar(&graphiteAddr, "output-graphite", "", "write per group metrics as Graphite plaintext lines to the Carbon server at this address, or to stdout with -")
+	rootCmd.Flags().StringVar(&graphitePrefix, "graphite-prefix", "nginx", "prefix of the Graphite metric names")
+	rootCmd.Flags().DurationVar(&graphiteInterval, "graphite-flush-interval", 10*time.Second, "how often aggregated Graphite metrics are written")
+	rootCmd.Flags().StringVar(&prometheusAddr, "output-prometheus", "", "serve per group metrics on /metrics at this address, in the Prometheus text format or in the OpenMetrics format to scrapers accepting application/openmetrics-text")
+	rootCmd.Flags().StringVar(&prometheusPrefix, "prometheus-prefix", "nginx", "prefix of the Prometheus metric names")
+	rootCmd.Flags().StringVar(&alertConfig.URL, "alert-webhook", "", "post a JSON alert to this URL when a group's error or timeout rate over the recent window crosses a threshold, for streamed input")
+	rootCmd.Flags().Float64Var(&alertConfig.ErrorRate, "alert-error-rate", alertConfig.ErrorRate, "fraction of 5XX or timed out requests above which --alert-webhook alerts, 0 to disable")
+	rootCmd.Flags().Float64Var(&alertConfig.TimeoutRate, "alert-timeout-rate", alertConfig.TimeoutRate, "fraction of timed out requests above which --alert-webhook alerts, 0 to disable")
+	rootCmd.Flags().DurationVar(&alertConfig.Window, "alert-window", alertConfig.Window, "window the --alert-webhook rates are computed over")
+	rootCmd.Flags().IntVar(&alertConfig.MinRequests, "alert-min-requests", alertConfig.MinRequests, "requests a group needs in the window before --alert-webhook checks its rates")
+	rootCmd.Flags().DurationVar(&alertConfig.Cooldown, "alert-cooldown", alertConfig.Cooldown, "minimum time between two --alert-webhook alerts of the same group")
+	rootCmd.Flags().StringVar(&stateFile, "since-last-run", "", "only process what was appended to the input file since the last run, recording the offset in this state file. The input can't be compressed")
+	rootCmd.Flags().StringVar(&inputFile, "file", "", "read this log file instead of stdin, like the file argument, - for stdin. gzip and zstd files are decompressed")
+	rootCmd.Flags().StringVar(&inputDir, "dir", "", "read every file of this directory instead of stdin, parsing files concurrently")
+	rootCmd.Flags().StringVar(&saveAggregatePath, "save-aggregate", "", "write the aggregated metrics to this file in a compact binary format, to merge them elsewhere")
+	rootCmd.Flags().StringSliceVar(&mergeAggregatePaths, "merge-aggregate", nil, "merge the aggregates written with --save-aggregate to these files into the report")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", runtime.NumCPU(), "number of files parsed at once with --dir")
+	rootCmd.Flags().BoolVar(&journald, "journald", false, "read log lines from the systemd journal instead of stdin")
+	rootCmd.Flags().StringVar(&journaldUnit, "unit", "nginx.service", "systemd unit to read the journal of, with --journald")
+	rootCmd.Flags().StringVar(&groupTemplate, "group-template", "", "build group keys from a template like \"{host} {method} {path-depth-2}\", overriding --group-by. Tokens are host, method, path, path-depth-N, status, upstream, upstream_name, client_ip, referer_host and field:<name>")
+	rootCmd.Flags().StringVar(&groupBy, "group-by", string(metric.GroupKindPath), "what to group requests by: path, upstream_ip, client_ip, referer_host, upstream_service for the namespace/service of the upstream name, latency_bucket for the rounded request time, none for a single overall group, or field:<name> for a parsed log field")
+	rootCmd.Flags().BoolVar(&groupIPSubnet, "group-normalize-ip-subnet", false, "with --group-by upstream_ip or client_ip, group addresses by subnet rather than exact IP")
+	rootCmd.Flags().IntVar(&subnetPrefixIPv4, "subnet-prefix-ipv4", metric.DefaultSubnetPrefixIPv4, "prefix length IPv4 addresses are grouped by with --group-normalize-ip-subnet")
+	rootCmd.Flags().IntVar(&subnetPrefixIPv6, "subnet-prefix-ipv6", metric.DefaultSubnetPrefixIPv6, "prefix length IPv6 addresses are grouped by with --group-normalize-ip-subnet")
+	rootCmd.Flags().DurationVar(&latencyBucketSize, "latency-bucket-size", time.Duration(metric.DefaultLatencyBucketSize*float64(time.Second)), "request times are rounded to the nearest multiple of this with --group-by latency_bucket")
+	rootCmd.Flags().StringSliceVar(&upstreamNamespaces, "upstream-namespaces", nil, "namespaces with hyphens in their name, to split upstream names with --group-by upstream_service")
+	rootCmd.Flags().BoolVar(&caseInsensitivePaths, "group-case-insensitive", false, "lowercase request paths before grouping by them")
+	rootCmd.Flags().IntVar(&pathDepth, "path-depth", 0, "group by only the first N segments of request paths")
+	rootCmd.Flags().BoolVar(&includeQuery, "group-include-query", false, "include query parameters in path groups")
+	rootCmd.Flags().StringSliceVar(&queryParams, "group-query-params", nil, "only include these query parameters in path groups, with --group-include-query")
+	rootCmd.Flags().BoolVar(&redactQueryValues, "truncate-query-values", false, "redact the values of the query parameters included in path groups, keeping the parameter names")
+	rootCmd.Flags().StringSliceVar(&keepQueryValues, "keep-query-values", nil, "query parameters whose values aren't redacted by --truncate-query-values")
+	rootCmd.Flags().BoolVar(&clusterPaths, "cluster-paths", false, "group similar paths under inferred templates, e.g. /a/1/b and /a/2/b under /a/*/b")
+	rootCmd.Flags().IntVar(&clusterMaxDistinct, "cluster-max-distinct", metric.DefaultClusterMaxDistinct, "distinct values of a path segment above which it's clustered, with --cluster-paths")
+	rootCmd.Flags().IntVar(&maxReportGroups, "max-groups-report", 0, "only report the first N groups by --sort, or the N groups with the most requests when sorted by name")
+	rootCmd.Flags().IntVar(&minRequests, "min-requests", metric.DefaultMinRequests, "only list groups with more than N requests in the response code and time out sections")
+	rootCmd.Flags().StringVar(&percentileMethod, "latency-percentile-interpolation", string(metric.PercentileNearestRank), "how latency percentiles are computed: nearest-rank for observed latencies, or linear to interpolate between them like numpy and closer to Prometheus")
+	rootCmd.Flags().Float64SliceVar(&percentiles, "percentiles", metric.DefaultPercentiles, "latency percentiles reported for each group, e.g. 50,95,99,99.9")
+	rootCmd.Flags().BoolVar(&manifest, "manifest", false, "record the effective value of every option at the top of the text and json reports")
+	rootCmd.Flags().StringSliceVar(&plugins, "plugins", nil, "custom metrics computed for each group: unique_clients")
+	rootCmd.Flags().BoolVar(&reportIncludeZero, "report-include-zero", false, "list every group in the report, however few requests it has, ignoring --min-requests")
+	rootCmd.Flags().IntVar(&roundLatency, "round-latency", -1, "round latencies in the report to N decimal places")
+	rootCmd.Flags().IntVar(&reqIDCap, "req-id-cap", metric.DefaultReqIDCap, "maximum number of distinct request IDs tracked for duplicates, 0 to disable")
+	rootCmd.Flags().BoolVar(&includeStream, "include-stream", false, "add the sessions of L4 TCP and UDP services from mixed stream logs to the aggregates, grouped as stream:<protocol>")
+	rootCmd.Flags().StringSliceVar(&includeMethods, "include-method", nil, "only analyze requests with these methods, case-insensitive")
+	rootCmd.Flags().StringSliceVar(&excludeMethods, "exclude-method", nil, "drop requests with these methods, e.g. OPTIONS, case-insensitive")
+	rootCmd.Flags().StringSliceVar(&errorRateExclude, "error-rate-exclude-status", nil, "leave upstream statuses out of error rates, health scores and --alert-error-rate, e.g. 429 or 503 for rate limited requests")
+	rootCmd.Flags().StringSliceVar(&excludeStatus, "exclude-status", nil, "exclude upstream statuses from all metrics, as codes (304), ranges (300-399) or classes (3xx)")
+	rootCmd.Flags().DurationSliceVar(&slaTiers, "sla-tiers", nil, "report the percentage of each group's requests faster than these latencies, e.g. 100ms,300ms,1s")
+	rootCmd.Flags().StringVar(&splitAt, "split-at", "", "compare the error rate and p95 latency of each group before and after this RFC3339 time, e.g. a deploy, instead of printing the report")
+	rootCmd.Flags().IntVar(&reservoir, "reservoir", 0, "keep a uniform random sample of at most this many latencies per group to estimate percentiles in bounded memory, also bounding the state of --plugins, 0 to keep every latency")
+	rootCmd.Flags().IntVar(&topErrors, "top-errors", 0, "report the groups with the most 5XX responses, up to this many")
+	rootCmd.Flags().IntVar(&burstSize, "bursts", 0, "report bursts of at least this many consecutive 5XX or timed out requests of a group, which need input in time order")
+	rootCmd.Flags().DurationVar(&burstWindow, "burst-window", metric.DefaultBurstWindow, "time the failures of a burst must be logged within, with --bursts")
+	rootCmd.Flags().BoolVar(&latencyByClass, "latency-by-class", false, "report the mean and p95 latencies of each response status class across all groups")
+	rootCmd.Flags().DurationVar(&window, "window", 0, "report the p95 and p99 latencies of each group over this recent window, next to the all-time ones")
+	rootCmd.Flags().BoolVar(&arrivalHistogram, "report-interval-histogram", false, "report a histogram of the gaps between consecutive requests of each group")
+	rootCmd.Flags().BoolVar(&worstRequests, "worst-requests", false, "report the raw line of the slowest request of each group")
+	rootCmd.Flags().IntVar(&maxLineSample, "max-line-sample", metric.DefaultMaxLineSample, "total bytes of raw lines retained for the report, evicting the least recently retained ones first")
+	rootCmd.Flags().BoolVar(&slowClients, "slow-clients", false, "report the groups and clients of slow, slowloris style requests")
+	rootCmd.Flags().Float64Var(&slowClientConfig.MinRequestTime, "slow-client-min-time", metric.DefaultSlowClientConfig.MinRequestTime, "request time, in seconds, from which a request is considered slow for --slow-clients")
+	rootCmd.Flags().Int64Var(&slowClientConfig.MaxBytes, "slow-client-max-bytes", metric.DefaultSlowClientConfig.MaxBytes, "request and response size up to which slow requests are reported by --slow-clients")
+	rootCmd.Flags().IntVar(&talkers, "talkers", 0, "report the N clients with the most requests")
+	rootCmd.Flags().IntVar(&talkersCapacity, "talkers-capacity", 0, "approximate the talkers report by tracking at most this many clients, 0 for exact counts")
+	rootCmd.Flags().Uint64Var(&maxMemoryMB, "max-memory", 0, "switch to approximate aggregation when the heap approaches this many MiB, 0 for no limit")
+	rootCmd.Flags().IntVar(&approximateCapacity, "approximate-capacity", metric.DefaultApproximateCapacity, "latencies sampled per group for time based metrics and clients tracked for talkers in approximate aggregation, where percentiles are estimated with t-digests")
+	rootCmd.Flags().BoolVar(&showTrends, "trends", false, "show whether the latency of each group trends up, down or stays flat over time")
+	rootCmd.Flags().Float64Var(&trendFlatThreshold, "trend-flat-threshold", metric.DefaultTrendFlatThreshold, "latency slope in seconds per minute below which a latency trend is flat")
+	rootCmd.Flags().DurationVar(&warmup, "warmup", 0, "ignore requests within this duration of the earliest request, e.g. 30s, not supported with --dir")
+	rootCmd.Flags().BoolVar(&validateLatency, "validate-latency-sane", false, "drop latencies outside of --latency-min and --latency-max")
+	rootCmd.Flags().Float64Var(&saneLatency.Min, "latency-min", 0, "smallest sane latency in seconds, with --validate-latency-sane")
+	rootCmd.Flags().Float64Var(&saneLatency.Max, "latency-max", 3600, "largest sane latency in seconds, with --validate-latency-sane")
+	rootCmd.Flags().BoolVar(&showHealth, "health", false, "show a health score for each group, blending its error rate, timeout rate and p95 latency")
+	rootCmd.Flags().Float64Var(&health.ErrorWeight, "health-error-weight", health.ErrorWeight, "weight of the 5XX or timed out rate in the health score")
+	rootCmd.Flags().Float64Var(&health.TimeoutWeight, "health-timeout-weight", health.TimeoutWeight, "weight of the timeout rate in the health score")
+	rootCmd.Flags().Float64Var(&health.LatencyWeight, "health-latency-weight", health.LatencyWeight, "weight of the p95 latency in the health score")
+	rootCmd.Flags().Float64Var(&health.LatencyThreshold, "health-latency-threshold", health.LatencyThreshold, "p95 latency in seconds above which the health score drops")
+	rootCmd.Flags().StringVar(&healthSort, "health-sort", "asc", "order of the health scores in the report: asc (worst first) or desc")
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
 }